@@ -0,0 +1,225 @@
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/johto/pg_pb3_ld"
+	"sync"
+)
+
+// Compression corresponds to the compression option of the plugin.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionPglz Compression = "pglz"
+	CompressionLz4 Compression = "lz4"
+	CompressionZstd Compression = "zstd"
+)
+
+// The message buffer of the plugin can't grow past 1GB, so a larger
+// uncompressed length means that the header is corrupt.
+const maxUncompressedLength = 1 << 30
+
+// A Decompressor decompresses a wire message body compressed by the plugin.
+// uncompressedLength is the length of the body before it was compressed.
+type Decompressor func(data []byte, uncompressedLength int) ([]byte, error)
+
+var decompressorsLock sync.RWMutex
+var decompressors = map[pg_pb3_ld.CompressionMethod]Decompressor{
+	pg_pb3_ld.CompressionMethod_COMPRESSION_PGLZ: decompressPglz,
+	pg_pb3_ld.CompressionMethod_COMPRESSION_LZ4: decompressLz4,
+}
+
+// RegisterDecompressor sets the function used to decompress wire message
+// bodies compressed with method, replacing the previous one.  This package
+// only knows how to decompress pglz and lz4, so zstd compressed wire messages
+// can only be decoded after registering a decompressor for them, e.g. one
+// based on github.com/klauspost/compress/zstd:
+//
+//	zstdDecoder, _ := zstd.NewReader(nil)
+//	client.RegisterDecompressor(pg_pb3_ld.CompressionMethod_COMPRESSION_ZSTD,
+//		func(data []byte, uncompressedLength int) ([]byte, error) {
+//			return zstdDecoder.DecodeAll(data, make([]byte, 0, uncompressedLength))
+//		})
+func RegisterDecompressor(method pg_pb3_ld.CompressionMethod, fn Decompressor) {
+	decompressorsLock.Lock()
+	decompressors[method] = fn
+	decompressorsLock.Unlock()
+}
+
+// DecompressionError is returned when the body of a compressed wire message
+// could not be decompressed.
+type DecompressionError struct {
+	Method pg_pb3_ld.CompressionMethod
+	Err error
+}
+
+func (e *DecompressionError) Error() string {
+	return fmt.Sprintf("could not decompress %s compressed wire message body: %s", e.Method, e.Err)
+}
+
+func (e *DecompressionError) Unwrap() error {
+	return e.Err
+}
+
+// decompressBody returns the decompressed body of a wire message.
+func decompressBody(header *pg_pb3_ld.WireMessageHeader, body []byte) ([]byte, error) {
+	method := header.GetCompression()
+	if method == pg_pb3_ld.CompressionMethod_COMPRESSION_NONE {
+		return body, nil
+	}
+	uncompressedLength := int(header.GetUncompressedLength())
+	if uncompressedLength <= 0 || uncompressedLength > maxUncompressedLength {
+		return nil, &DecompressionError{
+			Method: method,
+			Err: fmt.Errorf("invalid uncompressed length %d", uncompressedLength),
+		}
+	}
+
+	decompressorsLock.RLock()
+	fn := decompressors[method]
+	decompressorsLock.RUnlock()
+	if fn == nil {
+		return nil, &DecompressionError{
+			Method: method,
+			Err: fmt.Errorf("no decompressor registered"),
+		}
+	}
+	data, err := fn(body, uncompressedLength)
+	if err != nil {
+		return nil, &DecompressionError{Method: method, Err: err}
+	}
+	if len(data) != uncompressedLength {
+		return nil, &DecompressionError{
+			Method: method,
+			Err: fmt.Errorf("decompressed %d bytes; expected %d", len(data), uncompressedLength),
+		}
+	}
+	return data, nil
+}
+
+// decompressPglz decompresses data in the format produced by pglz_compress().
+// Every control byte is followed by eight items, starting from its least
+// significant bit: a literal byte if the bit is clear, and a back reference
+// of two or three bytes if it's set.
+func decompressPglz(data []byte, uncompressedLength int) ([]byte, error) {
+	out := make([]byte, 0, uncompressedLength)
+	sp := 0
+	for sp < len(data) && len(out) < uncompressedLength {
+		control := data[sp]
+		sp++
+		for bit := 0; bit < 8 && sp < len(data) && len(out) < uncompressedLength; bit++ {
+			if control & (1 << bit) == 0 {
+				out = append(out, data[sp])
+				sp++
+				continue
+			}
+
+			if sp + 1 >= len(data) {
+				return nil, fmt.Errorf("truncated back reference at offset %d", sp)
+			}
+			length := int(data[sp] & 0x0F) + 3
+			offset := int(data[sp] & 0xF0) << 4 | int(data[sp + 1])
+			sp += 2
+			if length == 18 {
+				if sp >= len(data) {
+					return nil, fmt.Errorf("truncated back reference at offset %d", sp)
+				}
+				length += int(data[sp])
+				sp++
+			}
+			if offset == 0 || offset > len(out) {
+				return nil, fmt.Errorf("invalid back reference offset %d at output position %d", offset, len(out))
+			}
+			if len(out) + length > uncompressedLength {
+				return nil, fmt.Errorf("back reference of length %d overflows the output", length)
+			}
+			// the source and the destination may overlap
+			start := len(out) - offset
+			for i := 0; i < length; i++ {
+				out = append(out, out[start + i])
+			}
+		}
+	}
+	if len(out) != uncompressedLength {
+		return nil, fmt.Errorf("decompressed %d bytes; expected %d", len(out), uncompressedLength)
+	}
+	return out, nil
+}
+
+// decompressLz4 decompresses an LZ4 block, as produced by
+// LZ4_compress_default().
+func decompressLz4(data []byte, uncompressedLength int) ([]byte, error) {
+	out := make([]byte, 0, uncompressedLength)
+	readLength := func(sp int, length int) (int, int, error) {
+		if length != 15 {
+			return sp, length, nil
+		}
+		for {
+			if sp >= len(data) {
+				return 0, 0, fmt.Errorf("truncated length at offset %d", sp)
+			}
+			b := data[sp]
+			sp++
+			length += int(b)
+			if length > uncompressedLength {
+				return 0, 0, fmt.Errorf("length %d overflows the output", length)
+			}
+			if b != 255 {
+				return sp, length, nil
+			}
+		}
+	}
+
+	sp := 0
+	for {
+		if sp >= len(data) {
+			return nil, fmt.Errorf("truncated sequence at offset %d", sp)
+		}
+		token := data[sp]
+		sp++
+
+		var literals, length int
+		var err error
+		sp, literals, err = readLength(sp, int(token >> 4))
+		if err != nil {
+			return nil, err
+		}
+		if sp + literals > len(data) || len(out) + literals > uncompressedLength {
+			return nil, fmt.Errorf("literal run of length %d at offset %d overflows", literals, sp)
+		}
+		out = append(out, data[sp:sp + literals]...)
+		sp += literals
+
+		// the last sequence only has literals
+		if sp == len(data) {
+			break
+		}
+
+		if sp + 2 > len(data) {
+			return nil, fmt.Errorf("truncated match offset at offset %d", sp)
+		}
+		offset := int(binary.LittleEndian.Uint16(data[sp:]))
+		sp += 2
+		sp, length, err = readLength(sp, int(token & 0x0F))
+		if err != nil {
+			return nil, err
+		}
+		length += 4
+		if offset == 0 || offset > len(out) {
+			return nil, fmt.Errorf("invalid match offset %d at output position %d", offset, len(out))
+		}
+		if len(out) + length > uncompressedLength {
+			return nil, fmt.Errorf("match of length %d overflows the output", length)
+		}
+		start := len(out) - offset
+		for i := 0; i < length; i++ {
+			out = append(out, out[start + i])
+		}
+	}
+	if len(out) != uncompressedLength {
+		return nil, fmt.Errorf("decompressed %d bytes; expected %d", len(out), uncompressedLength)
+	}
+	return out, nil
+}
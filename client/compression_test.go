@@ -0,0 +1,204 @@
+package client
+
+import (
+	"errors"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+	"strings"
+	"testing"
+)
+
+func TestDecompressPglz(t *testing.T) {
+	tests := []struct{
+		input []byte
+		expected string
+	}{
+		// three literals, then a back reference of length 9 at offset 3
+		{[]byte{0x08, 'a', 'b', 'c', 0x06, 0x03}, strings.Repeat("abc", 4)},
+		// a back reference of length 18 + 12 at offset 1, then a literal
+		{[]byte{0x02, 'x', 0x0F, 0x01, 12, 'y'}, strings.Repeat("x", 31) + "y"},
+	}
+
+	// offsets larger than 255 use the high nibble of the first byte: 260
+	// literals, then a back reference of length 3 at offset 260
+	literals := make([]byte, 260)
+	for i := range literals {
+		literals[i] = byte(i * 7)
+	}
+	var input []byte
+	for i := 0; i < 256; i += 8 {
+		input = append(input, 0x00)
+		input = append(input, literals[i:i + 8]...)
+	}
+	input = append(input, 0x10)
+	input = append(input, literals[256:]...)
+	input = append(input, 0x10, 0x04)
+	tests = append(tests, struct{
+		input []byte
+		expected string
+	}{input, string(literals) + string(literals[:3])})
+
+	for i, test := range tests {
+		out, err := decompressPglz(test.input, len(test.expected))
+		if err != nil {
+			t.Errorf("test %d: %s", i, err)
+		} else if string(out) != test.expected {
+			t.Errorf("test %d: got %q; expected %q", i, out, test.expected)
+		}
+	}
+
+	errorTests := []struct{
+		input []byte
+		uncompressedLength int
+		expect_error string
+	}{
+		{[]byte{0x01, 0x06}, 9, "truncated back reference"},
+		{[]byte{0x01, 0x06, 0x03}, 9, "invalid back reference offset 3"},
+		{[]byte{0x02, 'a', 0x06, 0x01}, 5, "overflows the output"},
+		{[]byte{0x00, 'a'}, 2, "decompressed 1 bytes; expected 2"},
+	}
+	for i, test := range errorTests {
+		_, err := decompressPglz(test.input, test.uncompressedLength)
+		if err == nil {
+			t.Errorf("error test %d succeeded unexpectedly", i)
+		} else if strings.Index(err.Error(), test.expect_error) == -1 {
+			t.Errorf("error test %d failed with an unexpected error: %s (expected to contain %q)", i, err, test.expect_error)
+		}
+	}
+}
+
+func TestDecompressLz4(t *testing.T) {
+	tests := []struct{
+		input []byte
+		expected string
+	}{
+		// literals only
+		{[]byte{0x30, 'a', 'b', 'c'}, "abc"},
+		// three literals and a match of length 5 at offset 3, then a literal
+		{[]byte{0x31, 'a', 'b', 'c', 0x03, 0x00, 0x10, 'd'}, "abcabcab" + "d"},
+		// extended literal and match lengths
+		{append(append([]byte{0xFF, 2}, []byte(strings.Repeat("z", 17))...),
+			0x01, 0x00, 255, 3, 0x10, '!'), strings.Repeat("z", 17 + 15 + 4 + 255 + 3) + "!"},
+	}
+	for i, test := range tests {
+		out, err := decompressLz4(test.input, len(test.expected))
+		if err != nil {
+			t.Errorf("test %d: %s", i, err)
+		} else if string(out) != test.expected {
+			t.Errorf("test %d: got %q; expected %q", i, out, test.expected)
+		}
+	}
+
+	errorTests := []struct{
+		input []byte
+		uncompressedLength int
+		expect_error string
+	}{
+		{nil, 1, "truncated sequence"},
+		{[]byte{0x30, 'a'}, 3, "overflows"},
+		{[]byte{0x11, 'a', 0x02, 0x00, 0x00}, 6, "invalid match offset 2"},
+		{[]byte{0x11, 'a', 0x01}, 6, "truncated match offset"},
+		{[]byte{0x20, 'a', 'b'}, 3, "decompressed 2 bytes; expected 3"},
+	}
+	for i, test := range errorTests {
+		_, err := decompressLz4(test.input, test.uncompressedLength)
+		if err == nil {
+			t.Errorf("error test %d succeeded unexpectedly", i)
+		} else if strings.Index(err.Error(), test.expect_error) == -1 {
+			t.Errorf("error test %d failed with an unexpected error: %s (expected to contain %q)", i, err, test.expect_error)
+		}
+	}
+}
+
+// compressedFrame builds a wire message whose body is replaced with
+// compressedBody, the way the plugin does when compression is enabled.
+func compressedFrame(t *testing.T, types []pg_pb3_ld.WireMessageType, messages []proto.Message, method pg_pb3_ld.CompressionMethod, compressedBody []byte) ([]byte, []byte) {
+	var body []byte
+	header := &pg_pb3_ld.WireMessageHeader{}
+	for i, msg := range messages {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header.Types = append(header.Types, types[i])
+		header.Offsets = append(header.Offsets, int32(len(body)))
+		body = append(body, data...)
+	}
+	header.Compression = method
+	header.UncompressedLength = int32(len(body))
+	headerData, err := proto.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressedBody == nil {
+		compressedBody = body
+	}
+	return appendFrame(headerData, compressedBody), body
+}
+
+func TestDecodeCompressedWireMessage(t *testing.T) {
+	types := []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_BEGIN,
+		pg_pb3_ld.WireMessageType_WMSG_COMMIT,
+	}
+	messages := []proto.Message{
+		&pg_pb3_ld.BeginTransaction{Xid: 1234},
+		&pg_pb3_ld.CommitTransaction{Xid: 1234},
+	}
+
+	// the body stored as pglz literals
+	_, body := compressedFrame(t, types, messages, pg_pb3_ld.CompressionMethod_COMPRESSION_NONE, nil)
+	var pglzBody []byte
+	for i := 0; i < len(body); i += 8 {
+		end := i + 8
+		if end > len(body) {
+			end = len(body)
+		}
+		pglzBody = append(pglzBody, 0x00)
+		pglzBody = append(pglzBody, body[i:end]...)
+	}
+	data, _ := compressedFrame(t, types, messages, pg_pb3_ld.CompressionMethod_COMPRESSION_PGLZ, pglzBody)
+	decoded, err := DecodeWireMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !proto.Equal(decoded[0], &Begin{&pg_pb3_ld.BeginTransaction{Xid: 1234}}) {
+		t.Errorf("unexpected messages %+v", decoded)
+	}
+
+	// zstd needs a registered decompressor
+	data, _ = compressedFrame(t, types, messages, pg_pb3_ld.CompressionMethod_COMPRESSION_ZSTD, []byte("zstd"))
+	_, err = DecodeWireMessage(data)
+	var decompressionErr *DecompressionError
+	if !errors.As(err, &decompressionErr) || decompressionErr.Method != pg_pb3_ld.CompressionMethod_COMPRESSION_ZSTD {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	RegisterDecompressor(pg_pb3_ld.CompressionMethod_COMPRESSION_ZSTD, func(data []byte, uncompressedLength int) ([]byte, error) {
+		if string(data) != "zstd" {
+			t.Errorf("unexpected data %q", data)
+		}
+		return body, nil
+	})
+	defer RegisterDecompressor(pg_pb3_ld.CompressionMethod_COMPRESSION_ZSTD, nil)
+	decoded, err = DecodeWireMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !proto.Equal(decoded[1], &Commit{&pg_pb3_ld.CommitTransaction{Xid: 1234}}) {
+		t.Errorf("unexpected messages %+v", decoded)
+	}
+
+	// a corrupt uncompressed length
+	header := &pg_pb3_ld.WireMessageHeader{
+		Compression: pg_pb3_ld.CompressionMethod_COMPRESSION_PGLZ,
+	}
+	headerData, err := proto.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = DecodeWireMessage(appendFrame(headerData, []byte{0x00}))
+	if !errors.As(err, &decompressionErr) || strings.Index(err.Error(), "invalid uncompressed length 0") == -1 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ColumnChange classifies a column of an updated row; see Update.Diff.
+type ColumnChange int
+
+const (
+	// ColumnUnknown means that either the old or the new value of the column
+	// was not sent, so it's not known whether the value changed.  Old values
+	// are only sent for tables with REPLICA IDENTITY FULL when the
+	// enable_old_values option is set, and the values of unchanged TOASTed
+	// columns are never sent.
+	ColumnUnknown ColumnChange = iota
+	ColumnUnchanged
+	ColumnChanged
+)
+
+func (c ColumnChange) String() string {
+	switch c {
+		case ColumnUnknown:
+			return "unknown"
+		case ColumnUnchanged:
+			return "unchanged"
+		case ColumnChanged:
+			return "changed"
+		default:
+			return fmt.Sprintf("ColumnChange(%d)", int(c))
+	}
+}
+
+// ColumnDiff describes how a single column of an updated row changed.
+type ColumnDiff struct {
+	Name string
+	Change ColumnChange
+
+	// Old and New are nil if the corresponding value was not sent.  An
+	// unchanged TOASTed value is considered not sent.
+	Old *Column
+	New *Column
+}
+
+// Diff compares the old and the new version of the updated row column by
+// column.  The columns are in the order of the old row, followed by any
+// columns only present in the new row.  Two values are considered equal if
+// they were sent as identical data; since both versions of the row are
+// written using the same options, this is the same as comparing the output
+// of the type's output or send function.
+func (m *Update) Diff() ([]ColumnDiff, error) {
+	oldColumns, err := m.OldRow().Columns()
+	if err != nil {
+		return nil, fmt.Errorf("invalid old values: %w", err)
+	}
+	newColumns, err := m.NewRow().Columns()
+	if err != nil {
+		return nil, fmt.Errorf("invalid new values: %w", err)
+	}
+
+	newByName := make(map[string]*Column, len(newColumns))
+	for i := range newColumns {
+		if newColumns[i].UnchangedToast {
+			continue
+		}
+		newByName[newColumns[i].Name] = &newColumns[i]
+	}
+
+	seenOld := make(map[string]bool, len(oldColumns))
+	diff := make([]ColumnDiff, 0, len(oldColumns) + len(newColumns))
+	for i := range oldColumns {
+		oldCol := &oldColumns[i]
+		newCol := newByName[oldCol.Name]
+		delete(newByName, oldCol.Name)
+		seenOld[oldCol.Name] = true
+
+		d := ColumnDiff{
+			Name: oldCol.Name,
+			Change: ColumnUnknown,
+			Old: oldCol,
+			New: newCol,
+		}
+		if newCol != nil {
+			if oldCol.Null == newCol.Null && bytes.Equal(oldCol.Data, newCol.Data) {
+				d.Change = ColumnUnchanged
+			} else {
+				d.Change = ColumnChanged
+			}
+		}
+		diff = append(diff, d)
+	}
+	for i := range newColumns {
+		newCol := &newColumns[i]
+		if newCol.UnchangedToast {
+			if !seenOld[newCol.Name] {
+				diff = append(diff, ColumnDiff{
+					Name: newCol.Name,
+					Change: ColumnUnknown,
+				})
+			}
+			continue
+		}
+		if newByName[newCol.Name] == nil {
+			continue
+		}
+		diff = append(diff, ColumnDiff{
+			Name: newCol.Name,
+			Change: ColumnUnknown,
+			New: newCol,
+		})
+	}
+	return diff, nil
+}
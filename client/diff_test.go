@@ -0,0 +1,87 @@
+package client
+
+import (
+	"github.com/johto/pg_pb3_ld"
+	"testing"
+)
+
+func TestUpdateDiff(t *testing.T) {
+	update := &Update{&pg_pb3_ld.UpdateDescription{
+		// "doc" is an unchanged TOASTed value
+		NewValues: &pg_pb3_ld.FieldSetDescription{
+			Names: []string{"id", "a", "b", "c"},
+			Values: [][]byte{[]byte("1"), []byte("x"), []byte{}, []byte("z")},
+			Nulls: []byte{0, 0, 1, 0},
+		},
+		OldValues: &pg_pb3_ld.FieldSetDescription{
+			Names: []string{"id", "a", "b", "doc", "c"},
+			Values: [][]byte{[]byte("1"), []byte("y"), []byte{}, []byte("..."), []byte{}},
+			Nulls: []byte{0, 0, 1, 0, 1},
+		},
+	}}
+
+	diff, err := update.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct{
+		name string
+		change ColumnChange
+	}{
+		{"id", ColumnUnchanged},
+		{"a", ColumnChanged},
+		{"b", ColumnUnchanged},
+		{"doc", ColumnUnknown},
+		// NULL to a value
+		{"c", ColumnChanged},
+	}
+	if len(diff) != len(expected) {
+		t.Fatalf("got %d columns; expected %d", len(diff), len(expected))
+	}
+	for i, d := range diff {
+		if d.Name != expected[i].name || d.Change != expected[i].change {
+			t.Errorf("column %d is %s %s; expected %s %s", i, d.Name, d.Change, expected[i].name, expected[i].change)
+		}
+	}
+	if diff[3].Old == nil || diff[3].New != nil {
+		t.Errorf("unexpected values %+v for the omitted column", diff[3])
+	}
+
+	// same thing with the unchanged TOASTed column marked
+	update.NewValues = &pg_pb3_ld.FieldSetDescription{
+		Names: []string{"id", "a", "b", "doc", "c"},
+		Values: [][]byte{[]byte("1"), []byte("x"), []byte{}, []byte{}, []byte("z")},
+		Nulls: []byte{0, 0, 1, 0, 0},
+		UnchangedToast: []byte{0, 0, 0, 1, 0},
+	}
+	diff, err = update.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != len(expected) {
+		t.Fatalf("got %d columns; expected %d", len(diff), len(expected))
+	}
+	for i, d := range diff {
+		if d.Name != expected[i].name || d.Change != expected[i].change {
+			t.Errorf("column %d is %s %s; expected %s %s", i, d.Name, d.Change, expected[i].name, expected[i].change)
+		}
+	}
+	if diff[3].New != nil {
+		t.Errorf("unexpected new value %+v for the unchanged TOASTed column", diff[3].New)
+	}
+
+	// without old values nothing is known
+	update.OldValues = nil
+	diff, err = update.Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 5 {
+		t.Fatalf("got %d columns; expected 5", len(diff))
+	}
+	for _, d := range diff {
+		if d.Change != ColumnUnknown || d.Old != nil || (d.New == nil) != (d.Name == "doc") {
+			t.Errorf("unexpected diff %+v", d)
+		}
+	}
+}
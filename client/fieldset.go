@@ -0,0 +1,191 @@
+package client
+
+import (
+	"fmt"
+	"github.com/johto/pg_pb3_ld"
+)
+
+// FieldSet wraps a FieldSetDescription, providing access to its columns.
+type FieldSet struct {
+	*pg_pb3_ld.FieldSetDescription
+}
+
+// Column is a single column of a FieldSet.
+type Column struct {
+	Name string
+
+	// TypeOid is the oid of the column's type, or zero if the plugin did not
+	// send it; see the type_oids_mode option.
+	TypeOid uint32
+
+	Null bool
+
+	// UnchangedToast is true if the column is a TOASTed value which was not
+	// changed by an UPDATE, and so its value was not sent.  Only possible
+	// with the enable_unchanged_toast_bitmap option.
+	UnchangedToast bool
+
+	// Binary is true if Data is in the binary send format of the type, and
+	// false if it's in the text output format.
+	Binary bool
+
+	// Data is the value as it was received from the plugin.
+	Data []byte
+
+	// Value is the decoded value, or nil for a NULL or an unchanged TOASTed
+	// value.  See DecodeValue for the Go types used for each PostgreSQL type.
+	Value interface{}
+}
+
+// NewRow returns the new values of the inserted row.
+func (m *Insert) NewRow() FieldSet {
+	return FieldSet{m.GetNewValues()}
+}
+
+// NewRow returns the new values of the updated row.
+func (m *Update) NewRow() FieldSet {
+	return FieldSet{m.GetNewValues()}
+}
+
+// KeyRow returns the key of the updated row.  Depending on the replica
+// identity of the table, this might be empty.
+func (m *Update) KeyRow() FieldSet {
+	return FieldSet{m.GetKeyFields()}
+}
+
+// OldRow returns the old version of the updated row.  It's only available
+// for tables with REPLICA IDENTITY FULL with the enable_old_values option.
+func (m *Update) OldRow() FieldSet {
+	return FieldSet{m.GetOldValues()}
+}
+
+// KeyRow returns the key of the deleted row.
+func (m *Delete) KeyRow() FieldSet {
+	return FieldSet{m.GetKeyFields()}
+}
+
+// OldRow returns the deleted row.  It's only available for tables with
+// REPLICA IDENTITY FULL with the enable_old_values option.
+func (m *Delete) OldRow() FieldSet {
+	return FieldSet{m.GetOldValues()}
+}
+
+// Columns returns the columns of the field set in order, with values decoded
+// into Go types.  In the omit_nulls modes of type_oids_mode and formats_mode
+// the type_oids and formats arrays only contain entries for non-NULL columns;
+// Columns aligns them with the columns they describe.
+func (fs FieldSet) Columns() ([]Column, error) {
+	if fs.FieldSetDescription == nil {
+		return nil, nil
+	}
+
+	names := fs.GetNames()
+	values := fs.GetValues()
+	nulls := fs.GetNulls()
+	typeOids := fs.GetTypeOids()
+	formats := fs.GetFormats()
+	unchangedToast := fs.GetUnchangedToast()
+
+	numColumns := len(names)
+	if len(values) != numColumns {
+		return nil, fmt.Errorf("field set has %d names but %d values", numColumns, len(values))
+	}
+	if len(nulls) != numColumns {
+		return nil, fmt.Errorf("field set has %d names but a nulls bitmap of length %d", numColumns, len(nulls))
+	}
+	if len(unchangedToast) != 0 && len(unchangedToast) != numColumns {
+		return nil, fmt.Errorf("field set has %d names but an unchanged_toast bitmap of length %d", numColumns, len(unchangedToast))
+	}
+	numNonNulls := 0
+	for _, null := range nulls {
+		if null == 0 {
+			numNonNulls++
+		}
+	}
+
+	typeOidsOmitNulls, err := omitsNulls("type_oids", len(typeOids), numColumns, numNonNulls)
+	if err != nil {
+		return nil, err
+	}
+	formatsOmitNulls, err := omitsNulls("formats", len(formats), numColumns, numNonNulls)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]Column, numColumns)
+	typeOidIdx := 0
+	formatIdx := 0
+	for i := range columns {
+		col := &columns[i]
+		col.Name = names[i]
+		col.Null = nulls[i] != 0
+		col.Data = values[i]
+		col.UnchangedToast = len(unchangedToast) > 0 && unchangedToast[i] != 0
+
+		if len(typeOids) > 0 && !(col.Null && typeOidsOmitNulls) {
+			col.TypeOid = typeOids[typeOidIdx]
+			typeOidIdx++
+		}
+		if len(formats) > 0 && !(col.Null && formatsOmitNulls) {
+			col.Binary = formats[formatIdx] != 0
+			formatIdx++
+		}
+
+		if col.Null || col.UnchangedToast {
+			continue
+		}
+		col.Value, err = DecodeValue(col.TypeOid, col.Binary, col.Data)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode column %q: %w", col.Name, err)
+		}
+	}
+	return columns, nil
+}
+
+// Merge returns the columns of the field set with the unchanged TOASTed
+// columns replaced by the columns of the same name in previous, typically the
+// columns of an earlier version of the same row.  This requires the
+// enable_unchanged_toast_bitmap option; without it unchanged TOASTed columns
+// are omitted from the field set, and Merge can't tell them apart from
+// columns which don't exist.
+func (fs FieldSet) Merge(previous []Column) ([]Column, error) {
+	columns, err := fs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		if !columns[i].UnchangedToast {
+			continue
+		}
+		found := false
+		for _, prev := range previous {
+			if prev.Name == columns[i].Name {
+				if prev.UnchangedToast {
+					break
+				}
+				columns[i] = prev
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no previous value for unchanged TOASTed column %q", columns[i].Name)
+		}
+	}
+	return columns, nil
+}
+
+// omitsNulls figures out whether an array describing the columns of a field
+// set was written in omit_nulls mode based on its length.  If there are no
+// NULLs, both interpretations are equivalent.
+func omitsNulls(field string, length int, numColumns int, numNonNulls int) (bool, error) {
+	if length == 0 || length == numColumns {
+		return false, nil
+	} else if length == numNonNulls {
+		return true, nil
+	}
+	return false, fmt.Errorf(
+		"field set has %d columns, %d of them non-NULL, but %s has %d entries",
+		numColumns, numNonNulls, field, length,
+	)
+}
@@ -0,0 +1,391 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TypeOidsMode corresponds to the type_oids_mode option of the plugin.
+type TypeOidsMode string
+
+const (
+	TypeOidsDisabled TypeOidsMode = "disabled"
+	TypeOidsOmitNulls TypeOidsMode = "omit_nulls"
+	TypeOidsFull TypeOidsMode = "full"
+)
+
+// FormatsMode corresponds to the formats_mode option of the plugin.
+type FormatsMode string
+
+const (
+	FormatsDisabled FormatsMode = "disabled"
+	FormatsOmitNulls FormatsMode = "omit_nulls"
+	FormatsFull FormatsMode = "full"
+)
+
+// MissingReplicaIdentityMode corresponds to the missing_replica_identity
+// option of the plugin.
+type MissingReplicaIdentityMode string
+
+const (
+	MissingReplicaIdentityError MissingReplicaIdentityMode = "error"
+	MissingReplicaIdentitySkip MissingReplicaIdentityMode = "skip"
+	MissingReplicaIdentitySendWithoutKeys MissingReplicaIdentityMode = "send_without_keys"
+	MissingReplicaIdentitySendFullOldTupleIfAvailable MissingReplicaIdentityMode = "send_full_old_tuple_if_available"
+)
+
+// Operation is one of the values of the operations option of the plugin.
+type Operation string
+
+const (
+	OperationInsert Operation = "insert"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+	OperationTruncate Operation = "truncate"
+)
+
+// The bounds of the wire_message_target_size option.  Zero is also accepted,
+// and means that every change is sent in a wire message of its own.
+const (
+	MinWireMessageTargetSize = 1024
+	MaxWireMessageTargetSize = 256 * 1024 * 1024
+)
+
+// OidRange is a closed range of type oids.
+type OidRange struct {
+	Min uint32
+	Max uint32
+}
+
+func (r OidRange) String() string {
+	if r.Min == r.Max {
+		return strconv.FormatUint(uint64(r.Min), 10)
+	}
+	return strconv.FormatUint(uint64(r.Min), 10) + "-" + strconv.FormatUint(uint64(r.Max), 10)
+}
+
+// Options describes the options passed to the output plugin.  The zero value
+// of every field means that the option is not passed to the plugin, and the
+// plugin's default is used instead.  See the "Options" section of the README
+// for the meaning of each option.
+type Options struct {
+	EnableBeginMessages *bool
+	EnableCommitMessages *bool
+	EnableTransactionMetadata *bool
+	EnableTruncateMessages *bool
+	EnableLogicalMessages *bool
+	LogicalMessagePrefixes []string
+	TypeOidsMode TypeOidsMode
+	BinaryOidRanges []OidRange
+	FormatsMode FormatsMode
+	EnableTableOids *bool
+	EnableRelationMessages *bool
+	MissingReplicaIdentity MissingReplicaIdentityMode
+	EnableOldValues *bool
+	EnableUnchangedToastBitmap *bool
+	IncludeTables []TablePattern
+	ExcludeTables []TablePattern
+	Columns []ColumnProjection
+	Operations []Operation
+	RowFilters []RowFilter
+	// OriginAny, OriginNone or a list of origin names; see OriginList
+	Origin string
+	WireMessageTargetSize *int
+	// see TransactionAssembler for handling streamed transactions
+	Streaming *bool
+	// requires PostgreSQL 15; see TransactionAssembler for handling prepared
+	// transactions
+	TwoPhase *bool
+	EnableSequenceMessages *bool
+	// a protocol version or a range of them; see NegotiateProtoVersion
+	ProtoVersion string
+	// lz4 and zstd require a server built with them; see RegisterDecompressor
+	// for decompressing zstd
+	Compression Compression
+}
+
+// Validate checks that the options would be accepted by the plugin.
+func (o *Options) Validate() error {
+	switch o.TypeOidsMode {
+		case "", TypeOidsDisabled, TypeOidsOmitNulls, TypeOidsFull:
+		default:
+			return fmt.Errorf("\"%s\" is not a valid value for parameter \"type_oids_mode\"", o.TypeOidsMode)
+	}
+	err := validateOidRanges(o.BinaryOidRanges)
+	if err != nil {
+		return err
+	}
+	switch o.FormatsMode {
+		case "", FormatsDisabled, FormatsOmitNulls, FormatsFull:
+		default:
+			return fmt.Errorf("\"%s\" is not a valid value for parameter \"formats_mode\"", o.FormatsMode)
+	}
+	switch o.MissingReplicaIdentity {
+		case "", MissingReplicaIdentityError, MissingReplicaIdentitySkip,
+			MissingReplicaIdentitySendWithoutKeys, MissingReplicaIdentitySendFullOldTupleIfAvailable:
+		default:
+			return fmt.Errorf("\"%s\" is not a valid value for parameter \"missing_replica_identity\"", o.MissingReplicaIdentity)
+	}
+	for _, patterns := range [][]TablePattern{o.IncludeTables, o.ExcludeTables} {
+		for _, p := range patterns {
+			err = p.validate()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range o.Columns {
+		err = p.validate()
+		if err != nil {
+			return err
+		}
+	}
+	for _, f := range o.RowFilters {
+		err = f.validate()
+		if err != nil {
+			return err
+		}
+	}
+	for _, op := range o.Operations {
+		switch op {
+			case OperationInsert, OperationUpdate, OperationDelete, OperationTruncate:
+			default:
+				return fmt.Errorf("unrecognized operation \"%s\" in operations", op)
+		}
+	}
+	err = validateOrigin(o.Origin)
+	if err != nil {
+		return err
+	}
+	err = validateProtoVersion(o.ProtoVersion)
+	if err != nil {
+		return err
+	}
+	switch o.Compression {
+		case "", CompressionNone, CompressionPglz, CompressionLz4, CompressionZstd:
+		default:
+			return fmt.Errorf("\"%s\" is not a valid value for parameter \"compression\"", o.Compression)
+	}
+	if o.WireMessageTargetSize != nil {
+		size := *o.WireMessageTargetSize
+		if size != 0 && (size < MinWireMessageTargetSize || size > MaxWireMessageTargetSize) {
+			return fmt.Errorf(
+				"wire_message_target_size must be 0 or between %d and %d bytes",
+				MinWireMessageTargetSize, MaxWireMessageTargetSize,
+			)
+		}
+	}
+	for _, prefix := range o.LogicalMessagePrefixes {
+		// the plugin splits the list on commas and ignores surrounding
+		// whitespace, so such prefixes can't be represented
+		if prefix == "" || strings.Contains(prefix, ",") || strings.TrimSpace(prefix) != prefix {
+			return fmt.Errorf("invalid logical message prefix %q", prefix)
+		}
+	}
+	return nil
+}
+
+// ParseOidRanges parses a comma-separated list of oid ranges in the format
+// accepted by the binary_oid_ranges option.  The input is validated the same
+// way the plugin validates it, and the errors mirror the ones the plugin
+// would raise.
+func ParseOidRanges(input string) ([]OidRange, error) {
+	input = strings.TrimLeft(input, " \t\n\r\f\v")
+	if input == "" {
+		return nil, nil
+	}
+
+	values := strings.Split(input, ",")
+	for _, value := range values {
+		if strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("invalid input syntax for binary_oid_ranges")
+		}
+	}
+
+	ranges := make([]OidRange, len(values))
+	for i, value := range values {
+		var err error
+
+		ranges[i], err = parseOidRange(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s (while parsing binary_oid_ranges range \"%s\")", err, value)
+		}
+		err = validateOidRanges(ranges[:i + 1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ranges, nil
+}
+
+func parseOidRange(value string) (OidRange, error) {
+	var r OidRange
+	var err error
+
+	hyphen := strings.IndexByte(value, '-')
+	if hyphen == -1 {
+		r.Min, err = parseOidValue(value)
+		r.Max = r.Min
+		return r, err
+	}
+
+	r.Min, err = parseOidValue(value[:hyphen])
+	if err != nil {
+		return r, err
+	}
+	r.Max, err = parseOidValue(value[hyphen + 1:])
+	if err != nil {
+		return r, err
+	}
+	if r.Max < r.Min {
+		return r, fmt.Errorf("the upper bound of a range can't be lower than its lower bound in binary_oid_ranges")
+	}
+	return r, nil
+}
+
+func parseOidValue(value string) (uint32, error) {
+	bigint, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid input syntax for integer: \"%s\"", value)
+	}
+	if bigint < 0 {
+		return 0, fmt.Errorf("oids can't be negative")
+	} else if bigint == 0 {
+		return 0, fmt.Errorf("oid can't be InvalidOid (0)")
+	} else if bigint > math.MaxUint32 {
+		return 0, fmt.Errorf("oids can't be larger than OID_MAX (%d)", uint32(math.MaxUint32))
+	}
+	return uint32(bigint), nil
+}
+
+func validateOidRanges(ranges []OidRange) error {
+	for i, current := range ranges {
+		if current.Min == 0 {
+			return fmt.Errorf("oid can't be InvalidOid (0)")
+		}
+		if current.Max < current.Min {
+			return fmt.Errorf("the upper bound of a range can't be lower than its lower bound in binary_oid_ranges")
+		}
+		if i > 0 {
+			previous := ranges[i - 1]
+			if previous.Max >= current.Min {
+				return fmt.Errorf(
+					"binary_oid_ranges range %d - %d overlaps with or precedes range %d - %d",
+					previous.Min, previous.Max,
+					current.Min, current.Max,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// Bool returns a pointer to b, for use with the boolean fields of Options.
+func Bool(b bool) *bool {
+	return &b
+}
+
+// Int returns a pointer to i, for use with the integer fields of Options.
+func Int(i int) *int {
+	return &i
+}
+
+type pluginArg struct {
+	name string
+	value string
+}
+
+func (o *Options) args() []pluginArg {
+	var args []pluginArg
+
+	appendBool := func(name string, value *bool) {
+		if value != nil {
+			args = append(args, pluginArg{name, strconv.FormatBool(*value)})
+		}
+	}
+	appendString := func(name string, value string) {
+		if value != "" {
+			args = append(args, pluginArg{name, value})
+		}
+	}
+
+	appendBool("enable_begin_messages", o.EnableBeginMessages)
+	appendBool("enable_commit_messages", o.EnableCommitMessages)
+	appendBool("enable_transaction_metadata", o.EnableTransactionMetadata)
+	appendBool("enable_truncate_messages", o.EnableTruncateMessages)
+	appendBool("enable_logical_messages", o.EnableLogicalMessages)
+	if len(o.LogicalMessagePrefixes) > 0 {
+		args = append(args, pluginArg{"logical_message_prefixes", strings.Join(o.LogicalMessagePrefixes, ",")})
+	}
+	appendString("type_oids_mode", string(o.TypeOidsMode))
+	if len(o.BinaryOidRanges) > 0 {
+		ranges := make([]string, len(o.BinaryOidRanges))
+		for i, r := range o.BinaryOidRanges {
+			ranges[i] = r.String()
+		}
+		args = append(args, pluginArg{"binary_oid_ranges", strings.Join(ranges, ",")})
+	}
+	appendString("formats_mode", string(o.FormatsMode))
+	appendBool("enable_table_oids", o.EnableTableOids)
+	appendBool("enable_relation_messages", o.EnableRelationMessages)
+	appendString("missing_replica_identity", string(o.MissingReplicaIdentity))
+	appendBool("enable_old_values", o.EnableOldValues)
+	appendBool("enable_unchanged_toast_bitmap", o.EnableUnchangedToastBitmap)
+	if len(o.IncludeTables) > 0 {
+		args = append(args, pluginArg{"include_tables", renderTablePatterns(o.IncludeTables)})
+	}
+	if len(o.ExcludeTables) > 0 {
+		args = append(args, pluginArg{"exclude_tables", renderTablePatterns(o.ExcludeTables)})
+	}
+	if len(o.Columns) > 0 {
+		rendered := make([]string, len(o.Columns))
+		for i, p := range o.Columns {
+			rendered[i] = p.String()
+		}
+		args = append(args, pluginArg{"columns", strings.Join(rendered, ";")})
+	}
+	if len(o.Operations) > 0 {
+		rendered := make([]string, len(o.Operations))
+		for i, op := range o.Operations {
+			rendered[i] = string(op)
+		}
+		args = append(args, pluginArg{"operations", strings.Join(rendered, ",")})
+	}
+	// passed once per filter
+	for _, f := range o.RowFilters {
+		args = append(args, pluginArg{"row_filter", f.String()})
+	}
+	appendString("origin", o.Origin)
+	if o.WireMessageTargetSize != nil {
+		args = append(args, pluginArg{"wire_message_target_size", strconv.Itoa(*o.WireMessageTargetSize)})
+	}
+	appendBool("streaming", o.Streaming)
+	appendBool("two_phase", o.TwoPhase)
+	appendBool("enable_sequence_messages", o.EnableSequenceMessages)
+	appendString("proto_version", o.ProtoVersion)
+	appendString("compression", string(o.Compression))
+	return args
+}
+
+// SQLFunctionArgs renders the options as a flat list of names and values, in
+// the form expected by the VARIADIC options argument of the
+// pg_logical_slot_get_binary_changes() family of functions.
+func (o *Options) SQLFunctionArgs() []string {
+	rendered := []string{}
+	for _, arg := range o.args() {
+		rendered = append(rendered, arg.name, arg.value)
+	}
+	return rendered
+}
+
+// ReplicationPluginArgs renders the options in the form expected by the
+// START_REPLICATION command, e.g. pglogrepl.StartReplicationOptions.
+func (o *Options) ReplicationPluginArgs() []string {
+	var rendered []string
+	for _, arg := range o.args() {
+		rendered = append(rendered, fmt.Sprintf("%s '%s'", arg.name, strings.ReplaceAll(arg.value, "'", "''")))
+	}
+	return rendered
+}
@@ -0,0 +1,189 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Mirrors TestBinaryOidRangesInput in the integration tests.
+func TestParseOidRanges(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"foo", true, "invalid input syntax for integer"},
+		{"0", true, "oid can't be InvalidOid"},
+		{"0-0", true, "oid can't be InvalidOid"},
+		{"0-1", true, "oid can't be InvalidOid"},
+		{"-1", true, "invalid input syntax for integer"},
+		{"1", false, ""},
+		{"4294967295", false, ""},
+		{"4294967296", true, "oids can't be larger than OID_MAX"},
+		{"1,", true, "invalid input syntax for binary_oid_ranges"},
+		{"1-", true, "invalid input syntax for integer"},
+		{"1-,", true, "invalid input syntax for binary_oid_ranges"},
+		{"1,2", false, ""},
+		{"2-1", true, "the upper bound of a range can't be lower than its lower bound"},
+		{"1,1-2", true, "overlaps with or precedes range"},
+		{"1-3,2-4", true, "overlaps with or precedes range"},
+		{"3-4,1-2", true, "overlaps with or precedes range"},
+		{"1,2,3,4,5,6,7,8,9,10", false, ""},
+		{"1-2,3,4-5", false, ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseOidRanges(test.input)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+
+	ranges, err := ParseOidRanges(" 17,20-21,23")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OidRange{{17, 17}, {20, 21}, {23, 23}}
+	if !reflect.DeepEqual(ranges, expected) {
+		t.Fatalf("got %+v; expected %+v", ranges, expected)
+	}
+}
+
+func TestOptionsRendering(t *testing.T) {
+	options := Options{
+		EnableBeginMessages: Bool(true),
+		EnableCommitMessages: Bool(false),
+		EnableTransactionMetadata: Bool(true),
+		TypeOidsMode: TypeOidsOmitNulls,
+		BinaryOidRanges: []OidRange{{17, 17}, {20, 21}},
+		FormatsMode: FormatsFull,
+		LogicalMessagePrefixes: []string{"deploy", "outbox"},
+		IncludeTables: []TablePattern{{"public", "*"}, {"audit", "events_*"}},
+		Operations: []Operation{OperationInsert, OperationDelete},
+		RowFilters: []RowFilter{
+			{TablePattern{"public", "orders"}, "status <> 'draft'"},
+			{TablePattern{"public", "*"}, "tenant_id = 5"},
+		},
+		Origin: OriginList("replicator_a", "replicator_b"),
+		WireMessageTargetSize: Int(0),
+		Streaming: Bool(true),
+		TwoPhase: Bool(true),
+		EnableSequenceMessages: Bool(false),
+		ProtoVersion: NegotiateProtoVersion,
+		Compression: CompressionPglz,
+	}
+	err := options.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replicationArgs := options.ReplicationPluginArgs()
+	expectedReplicationArgs := []string{
+		"enable_begin_messages 'true'",
+		"enable_commit_messages 'false'",
+		"enable_transaction_metadata 'true'",
+		"logical_message_prefixes 'deploy,outbox'",
+		"type_oids_mode 'omit_nulls'",
+		"binary_oid_ranges '17,20-21'",
+		"formats_mode 'full'",
+		"include_tables 'public.*,audit.events_*'",
+		"operations 'insert,delete'",
+		"row_filter 'public.orders: status <> ''draft'''",
+		"row_filter 'public.*: tenant_id = 5'",
+		"origin 'replicator_a,replicator_b'",
+		"wire_message_target_size '0'",
+		"streaming 'true'",
+		"two_phase 'true'",
+		"enable_sequence_messages 'false'",
+		"proto_version '1-2'",
+		"compression 'pglz'",
+	}
+	if !reflect.DeepEqual(replicationArgs, expectedReplicationArgs) {
+		t.Errorf("got %q; expected %q", replicationArgs, expectedReplicationArgs)
+	}
+
+	sqlArgs := options.SQLFunctionArgs()
+	expectedSQLArgs := []string{
+		"enable_begin_messages", "true",
+		"enable_commit_messages", "false",
+		"enable_transaction_metadata", "true",
+		"logical_message_prefixes", "deploy,outbox",
+		"type_oids_mode", "omit_nulls",
+		"binary_oid_ranges", "17,20-21",
+		"formats_mode", "full",
+		"include_tables", "public.*,audit.events_*",
+		"operations", "insert,delete",
+		"row_filter", "public.orders: status <> 'draft'",
+		"row_filter", "public.*: tenant_id = 5",
+		"origin", "replicator_a,replicator_b",
+		"wire_message_target_size", "0",
+		"streaming", "true",
+		"two_phase", "true",
+		"enable_sequence_messages", "false",
+		"proto_version", "1-2",
+		"compression", "pglz",
+	}
+	if !reflect.DeepEqual(sqlArgs, expectedSQLArgs) {
+		t.Errorf("got %q; expected %q", sqlArgs, expectedSQLArgs)
+	}
+
+	if len((&Options{}).SQLFunctionArgs()) != 0 {
+		t.Errorf("expected no arguments for the zero value of Options")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct{
+		options Options
+		expect_error string
+	}{
+		{Options{TypeOidsMode: "omit_null"}, `"omit_null" is not a valid value for parameter "type_oids_mode"`},
+		{Options{FormatsMode: "binary"}, `"binary" is not a valid value for parameter "formats_mode"`},
+		{Options{MissingReplicaIdentity: "ignore"}, `"ignore" is not a valid value for parameter "missing_replica_identity"`},
+		{Options{BinaryOidRanges: []OidRange{{0, 1}}}, "oid can't be InvalidOid"},
+		{Options{BinaryOidRanges: []OidRange{{2, 1}}}, "the upper bound of a range can't be lower than its lower bound"},
+		{Options{BinaryOidRanges: []OidRange{{3, 4}, {1, 2}}}, "overlaps with or precedes range"},
+		{Options{IncludeTables: []TablePattern{{"public", ""}}}, "the schema and table parts of a table pattern can't be empty"},
+		{Options{ExcludeTables: []TablePattern{{"public", "a.b"}}}, `table pattern "public.a.b" can't be represented`},
+		{Options{Columns: []ColumnProjection{{TablePattern{"public", "foo"}, nil, false}}}, "column names in columns can't be empty"},
+		{Options{Columns: []ColumnProjection{{TablePattern{"public", "foo"}, []string{"a)"}, false}}}, `column name "a)" in columns can't be represented`},
+		{Options{Operations: []Operation{OperationInsert, "upsert"}}, `unrecognized operation "upsert" in operations`},
+		{Options{RowFilters: []RowFilter{{TablePattern{"public", "foo"}, " "}}}, "invalid input syntax for row_filter"},
+		{Options{RowFilters: []RowFilter{{TablePattern{"public", "a:b"}, "true"}}}, `table pattern "public.a:b" can't be represented`},
+		{Options{Origin: "a,,b"}, "invalid input syntax for origin"},
+		{Options{Origin: OriginList("a", " ")}, "invalid input syntax for origin"},
+		{Options{WireMessageTargetSize: Int(1023)}, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{Options{WireMessageTargetSize: Int(MaxWireMessageTargetSize + 1)}, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{Options{WireMessageTargetSize: Int(-1)}, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{Options{ProtoVersion: "two"}, "invalid input syntax for proto_version"},
+		{Options{Compression: "gzip"}, `"gzip" is not a valid value for parameter "compression"`},
+		{Options{ProtoVersion: "0"}, "invalid input syntax for proto_version"},
+		{Options{ProtoVersion: "1-"}, "invalid input syntax for proto_version"},
+		{Options{ProtoVersion: "2-1"}, "the upper bound of a range can't be lower than its lower bound in proto_version"},
+		{Options{ProtoVersion: ProtoVersionRange(MaxProtoVersion + 1, MaxProtoVersion + 2)}, "does not include any of the protocol versions"},
+		{Options{LogicalMessagePrefixes: []string{"a", ""}}, `invalid logical message prefix ""`},
+		{Options{LogicalMessagePrefixes: []string{"a,b"}}, `invalid logical message prefix "a,b"`},
+		{Options{LogicalMessagePrefixes: []string{" a"}}, `invalid logical message prefix " a"`},
+	}
+	for _, test := range tests {
+		err := test.options.Validate()
+		if err == nil {
+			t.Errorf("%+v succeeded unexpectedly", test.options)
+		} else if strings.Index(err.Error(), test.expect_error) == -1 {
+			t.Errorf("%+v failed with an unexpected error: %s (expected to contain %q)", test.options, err, test.expect_error)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"strings"
+)
+
+// Values of the origin option of the plugin other than lists of origin names.
+const (
+	OriginAny = "any"
+	OriginNone = "none"
+)
+
+// OriginList renders a list of replication origin names as a value of the
+// origin option.  Changes without an origin and changes from the listed
+// origins are decoded.
+func OriginList(names ...string) string {
+	return strings.Join(names, ",")
+}
+
+func validateOrigin(origin string) error {
+	if origin == "" || origin == OriginAny || origin == OriginNone {
+		return nil
+	}
+	for _, name := range strings.Split(origin, ",") {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid input syntax for origin")
+		}
+	}
+	return nil
+}
+
+// OriginName returns the name of the replication origin the transaction was
+// replayed from, or an empty string if it doesn't have one.  The origin is
+// only known if begin messages are enabled.
+func (t *Transaction) OriginName() string {
+	if t.Begin == nil {
+		return ""
+	}
+	return t.Begin.GetOriginName()
+}
+
+// SetupReplicationOrigin creates the replication origin name unless it
+// already exists, and sets it up for the session of conn.  Changes made by the
+// session are then marked with the origin, so that they can be filtered out
+// with the origin option, until the session ends or calls
+// pg_replication_origin_session_reset().
+func SetupReplicationOrigin(ctx context.Context, conn *pgconn.PgConn, name string) error {
+	params := [][]byte{[]byte(name)}
+	result := conn.ExecParams(ctx, `
+SELECT pg_replication_origin_create($1)
+WHERE pg_replication_origin_oid($1) IS NULL
+`, params, nil, nil, nil).Read()
+	if result.Err != nil {
+		return fmt.Errorf("could not create replication origin %q: %w", name, result.Err)
+	}
+	result = conn.ExecParams(ctx, `SELECT pg_replication_origin_session_setup($1)`, params, nil, nil, nil).Read()
+	if result.Err != nil {
+		return fmt.Errorf("could not set up replication origin %q: %w", name, result.Err)
+	}
+	return nil
+}
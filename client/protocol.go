@@ -0,0 +1,79 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The versions of the wire protocol this package can decode.  Version 1 is
+// the protocol spoken when the proto_version option isn't given; version 2
+// adds the SessionStart message.
+const (
+	MinProtoVersion = 1
+	MaxProtoVersion = 2
+)
+
+// NegotiateProtoVersion is the value of the proto_version option which makes
+// the plugin pick the highest protocol version supported by both it and this
+// package.  The chosen version is reported in the SessionStart message; see
+// Decoder.ProtoVersion.
+var NegotiateProtoVersion = ProtoVersionRange(MinProtoVersion, MaxProtoVersion)
+
+// ProtoVersionRange renders a range of protocol versions as a value of the
+// proto_version option.  The plugin picks the highest version in the range it
+// supports, and refuses to start if there is none.
+func ProtoVersionRange(min int, max int) string {
+	if min == max {
+		return strconv.Itoa(min)
+	}
+	return strconv.Itoa(min) + "-" + strconv.Itoa(max)
+}
+
+func parseProtoVersion(value string) (int, error) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid input syntax for proto_version")
+	}
+	return version, nil
+}
+
+// validateProtoVersion checks the syntax of a value of the proto_version
+// option, and that the range includes a version this package can decode.
+func validateProtoVersion(value string) error {
+	if value == "" {
+		return nil
+	}
+	minValue, maxValue := value, value
+	if hyphen := strings.IndexByte(value, '-'); hyphen != -1 {
+		minValue, maxValue = value[:hyphen], value[hyphen+1:]
+	}
+	min, err := parseProtoVersion(minValue)
+	if err != nil {
+		return err
+	}
+	max, err := parseProtoVersion(maxValue)
+	if err != nil {
+		return err
+	}
+	if max < min {
+		return fmt.Errorf("the upper bound of a range can't be lower than its lower bound in proto_version")
+	}
+	if min > MaxProtoVersion || max < MinProtoVersion {
+		return fmt.Errorf(
+			"proto_version %q does not include any of the protocol versions %d to %d supported by this client",
+			value, MinProtoVersion, MaxProtoVersion,
+		)
+	}
+	return nil
+}
+
+// UnsupportedProtoVersionError is returned by a strict Decoder when the
+// plugin reports a protocol version this package can't decode.
+type UnsupportedProtoVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedProtoVersionError) Error() string {
+	return fmt.Sprintf("protocol version %d is not supported; supported versions are %d to %d", e.Version, MinProtoVersion, MaxProtoVersion)
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"fmt"
+	"github.com/johto/pg_pb3_ld"
+)
+
+// Relation describes the columns of a relation.  The plugin only sends
+// relation descriptions if relation messages are enabled, in which case the
+// changes themselves only refer to the relation by its oid, and carry no
+// column names.  See Decoder.
+//
+// If some of the columns of the table are excluded by the columns option, the
+// relation only describes the columns which are sent.
+type Relation struct {
+	*pg_pb3_ld.RelationDescription
+}
+
+func (*Relation) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_RELATION
+}
+
+// KeyColumnNames returns the names of the replica identity columns of the
+// relation in the order the key fields of changes list them.
+func (r *Relation) KeyColumnNames() ([]string, error) {
+	columnNames := r.GetColumnNames()
+	names := make([]string, len(r.GetKeyColumns()))
+	for i, col := range r.GetKeyColumns() {
+		if col >= uint32(len(columnNames)) {
+			return nil, fmt.Errorf("key column %d of relation %d is out of range (%d columns)", col, r.GetRelationId(), len(columnNames))
+		}
+		names[i] = columnNames[col]
+	}
+	return names, nil
+}
+
+// UnknownRelationError is returned by Decoder when a change refers to a
+// relation no RelationDescription has been received for.
+type UnknownRelationError struct {
+	RelationID uint32
+}
+
+func (e *UnknownRelationError) Error() string {
+	return fmt.Sprintf("change refers to relation %d which has not been described", e.RelationID)
+}
+
+// Decoder decodes wire messages the same way DecodeWireMessage does, but also
+// keeps track of the relation descriptions it has seen.  Changes which only
+// refer to their relation by its oid are expanded to look exactly like they
+// would have without relation messages: the schema and table names of their
+// TableDescriptions and the names of their field sets are filled in based on
+// the most recent description of the relation.  The Relation messages
+// themselves are returned as well.
+//
+// The plugin describes every relation again after the connection has been
+// re-established, so a Decoder should be used for a single session only.
+//
+// Unless Strict is set, messages of types unknown to this package are
+// skipped, so that a client can keep up with a newer plugin.  A strict
+// Decoder fails on them instead, as well as on a SessionStart reporting a
+// protocol version this package doesn't support.
+type Decoder struct {
+	Strict bool
+
+	relations map[uint32]*Relation
+	protoVersion int
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{
+		relations: make(map[uint32]*Relation),
+	}
+}
+
+// Relation returns the most recent description of the relation, or nil if
+// none has been received.
+func (d *Decoder) Relation(relationID uint32) *Relation {
+	return d.relations[relationID]
+}
+
+// ProtoVersion returns the protocol version reported by the SessionStart
+// message, or 0 if none has been received.  The plugin only sends one if
+// protocol version 2 or later was negotiated, before the first message of the
+// session.
+func (d *Decoder) ProtoVersion() int {
+	return d.protoVersion
+}
+
+// Decode decodes a single wire message.
+func (d *Decoder) Decode(data []byte) ([]Message, error) {
+	messages, err := decodeWireMessage(data, d.Strict)
+	if err != nil {
+		return nil, err
+	}
+	for i, msg := range messages {
+		err = d.expand(msg)
+		if err != nil {
+			return nil, fmt.Errorf("could not expand message %d: %w", i, err)
+		}
+	}
+	return messages, nil
+}
+
+func (d *Decoder) expand(msg Message) error {
+	switch m := msg.(type) {
+		case *SessionStart:
+			version := int(m.GetProtocolVersion())
+			if d.Strict && (version < MinProtoVersion || version > MaxProtoVersion) {
+				return &UnsupportedProtoVersionError{Version: version}
+			}
+			d.protoVersion = version
+			return nil
+		case *Relation:
+			d.relations[m.GetRelationId()] = m
+			return nil
+		case *Insert:
+			rel, err := d.expandTable(m.GetTable())
+			if err != nil || rel == nil {
+				return err
+			}
+			return expandFieldSet(m.GetNewValues(), rel, rel.GetColumnNames())
+		case *Update:
+			rel, err := d.expandTable(m.GetTable())
+			if err != nil || rel == nil {
+				return err
+			}
+			err = expandFieldSet(m.GetNewValues(), rel, rel.GetColumnNames())
+			if err != nil {
+				return err
+			}
+			err = expandFieldSet(m.GetOldValues(), rel, rel.GetColumnNames())
+			if err != nil {
+				return err
+			}
+			return expandKeyFields(m.GetKeyFields(), rel)
+		case *Delete:
+			rel, err := d.expandTable(m.GetTable())
+			if err != nil || rel == nil {
+				return err
+			}
+			err = expandFieldSet(m.GetOldValues(), rel, rel.GetColumnNames())
+			if err != nil {
+				return err
+			}
+			return expandKeyFields(m.GetKeyFields(), rel)
+		case *Truncate:
+			for _, table := range m.GetTables() {
+				_, err := d.expandTable(table)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			return nil
+	}
+}
+
+// expandTable fills in the names of a TableDescription which only refers to
+// its relation by oid.  Returns nil if the TableDescription was already
+// complete.
+func (d *Decoder) expandTable(table *pg_pb3_ld.TableDescription) (*Relation, error) {
+	if table == nil || table.TableName != "" {
+		return nil, nil
+	}
+	rel := d.relations[table.TableOid]
+	if rel == nil {
+		return nil, &UnknownRelationError{RelationID: table.TableOid}
+	}
+	table.SchemaName = rel.GetSchemaName()
+	table.TableName = rel.GetTableName()
+	return rel, nil
+}
+
+func expandKeyFields(fs *pg_pb3_ld.FieldSetDescription, rel *Relation) error {
+	// no key was sent; see the missing_replica_identity option
+	if fs == nil || len(fs.Values) == 0 {
+		return nil
+	}
+	names, err := rel.KeyColumnNames()
+	if err != nil {
+		return err
+	}
+	return expandFieldSet(fs, rel, names)
+}
+
+// expandFieldSet fills in the names of the field set from names, which are
+// the names of the columns the field set's column numbers refer to.  Column
+// numbers are only present if some of the columns were omitted; otherwise the
+// field set contains all of the columns in order.
+func expandFieldSet(fs *pg_pb3_ld.FieldSetDescription, rel *Relation, names []string) error {
+	if fs == nil || len(fs.Names) > 0 {
+		return nil
+	}
+
+	numValues := len(fs.Values)
+	if len(fs.ColumnNumbers) == 0 {
+		if numValues != len(names) {
+			return fmt.Errorf("field set has %d values but relation %d has %d columns", numValues, rel.GetRelationId(), len(names))
+		}
+		fs.Names = append([]string(nil), names...)
+		return nil
+	}
+
+	if len(fs.ColumnNumbers) != numValues {
+		return fmt.Errorf("field set has %d values but %d column numbers", numValues, len(fs.ColumnNumbers))
+	}
+	fs.Names = make([]string, numValues)
+	for i, col := range fs.ColumnNumbers {
+		if col >= uint32(len(names)) {
+			return fmt.Errorf("column number %d is out of range for relation %d (%d columns)", col, rel.GetRelationId(), len(names))
+		}
+		fs.Names[i] = names[col]
+	}
+	fs.ColumnNumbers = nil
+	return nil
+}
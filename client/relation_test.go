@@ -0,0 +1,247 @@
+package client
+
+import (
+	"errors"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	rel := &pg_pb3_ld.RelationDescription{
+		RelationId: 16384,
+		SchemaName: "public",
+		TableName: "tbl",
+		ColumnNames: []string{"id", "a", "b"},
+		TypeOids: []uint32{23, 25, 25},
+		TypeModifiers: []int32{-1, -1, -1},
+		KeyColumns: []uint32{0},
+	}
+	table := &pg_pb3_ld.TableDescription{TableOid: 16384}
+	ins := &pg_pb3_ld.InsertDescription{
+		Table: table,
+		NewValues: &pg_pb3_ld.FieldSetDescription{
+			Values: [][]byte{[]byte("1"), []byte("a"), []byte{}},
+			Nulls: []byte{0, 0, 1},
+		},
+	}
+	// column "a" was omitted from the new values
+	upd := &pg_pb3_ld.UpdateDescription{
+		Table: table,
+		NewValues: &pg_pb3_ld.FieldSetDescription{
+			Values: [][]byte{[]byte("1"), []byte("b")},
+			Nulls: []byte{0, 0},
+			ColumnNumbers: []uint32{0, 2},
+		},
+		KeyFields: &pg_pb3_ld.FieldSetDescription{
+			Values: [][]byte{[]byte("1")},
+			Nulls: []byte{0},
+		},
+	}
+	// sent without a key
+	del := &pg_pb3_ld.DeleteDescription{
+		Table: table,
+		KeyFields: &pg_pb3_ld.FieldSetDescription{},
+	}
+	trunc := &pg_pb3_ld.TruncateDescription{
+		Tables: []*pg_pb3_ld.TableDescription{table},
+	}
+	data := buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_RELATION,
+		pg_pb3_ld.WireMessageType_WMSG_INSERT,
+		pg_pb3_ld.WireMessageType_WMSG_UPDATE,
+		pg_pb3_ld.WireMessageType_WMSG_DELETE,
+		pg_pb3_ld.WireMessageType_WMSG_TRUNCATE,
+	}, []proto.Message{rel, ins, upd, del, trunc})
+
+	expectedTable := &pg_pb3_ld.TableDescription{
+		SchemaName: "public",
+		TableName: "tbl",
+		TableOid: 16384,
+	}
+	expected := []proto.Message{
+		rel,
+		&pg_pb3_ld.InsertDescription{
+			Table: expectedTable,
+			NewValues: &pg_pb3_ld.FieldSetDescription{
+				Names: []string{"id", "a", "b"},
+				Values: [][]byte{[]byte("1"), []byte("a"), []byte{}},
+				Nulls: []byte{0, 0, 1},
+			},
+		},
+		&pg_pb3_ld.UpdateDescription{
+			Table: expectedTable,
+			NewValues: &pg_pb3_ld.FieldSetDescription{
+				Names: []string{"id", "b"},
+				Values: [][]byte{[]byte("1"), []byte("b")},
+				Nulls: []byte{0, 0},
+			},
+			KeyFields: &pg_pb3_ld.FieldSetDescription{
+				Names: []string{"id"},
+				Values: [][]byte{[]byte("1")},
+				Nulls: []byte{0},
+			},
+		},
+		&pg_pb3_ld.DeleteDescription{
+			Table: expectedTable,
+			KeyFields: &pg_pb3_ld.FieldSetDescription{},
+		},
+		&pg_pb3_ld.TruncateDescription{
+			Tables: []*pg_pb3_ld.TableDescription{expectedTable},
+		},
+	}
+
+	decoder := NewDecoder()
+	messages, err := decoder.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("got %d messages; expected %d", len(messages), len(expected))
+	}
+	for i, msg := range messages {
+		if !proto.Equal(msg, expected[i]) {
+			t.Errorf("message %d %s does not match %s", i, proto.MarshalTextString(msg), proto.MarshalTextString(expected[i]))
+		}
+	}
+	if decoder.Relation(16384) == nil {
+		t.Errorf("relation 16384 is not known to the decoder")
+	}
+
+	// the relation is remembered across wire messages
+	data = buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_INSERT,
+	}, []proto.Message{ins})
+	messages, err = decoder.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(messages[0], expected[1]) {
+		t.Errorf("message %s does not match %s", proto.MarshalTextString(messages[0]), proto.MarshalTextString(expected[1]))
+	}
+
+	// but not by a different decoder
+	_, err = NewDecoder().Decode(data)
+	var unknownRelationErr *UnknownRelationError
+	if !errors.As(err, &unknownRelationErr) || unknownRelationErr.RelationID != 16384 {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	rel := &pg_pb3_ld.RelationDescription{
+		RelationId: 16384,
+		SchemaName: "public",
+		TableName: "tbl",
+		ColumnNames: []string{"id", "a"},
+		TypeOids: []uint32{23, 25},
+		TypeModifiers: []int32{-1, -1},
+		KeyColumns: []uint32{0},
+	}
+	table := &pg_pb3_ld.TableDescription{TableOid: 16384}
+
+	tests := []struct{
+		name string
+		msg proto.Message
+		typ pg_pb3_ld.WireMessageType
+	}{
+		{
+			"too few values",
+			&pg_pb3_ld.InsertDescription{
+				Table: table,
+				NewValues: &pg_pb3_ld.FieldSetDescription{
+					Values: [][]byte{[]byte("1")},
+					Nulls: []byte{0},
+				},
+			},
+			pg_pb3_ld.WireMessageType_WMSG_INSERT,
+		},
+		{
+			"column number out of range",
+			&pg_pb3_ld.InsertDescription{
+				Table: table,
+				NewValues: &pg_pb3_ld.FieldSetDescription{
+					Values: [][]byte{[]byte("1")},
+					Nulls: []byte{0},
+					ColumnNumbers: []uint32{2},
+				},
+			},
+			pg_pb3_ld.WireMessageType_WMSG_INSERT,
+		},
+		{
+			"too many key values",
+			&pg_pb3_ld.DeleteDescription{
+				Table: table,
+				KeyFields: &pg_pb3_ld.FieldSetDescription{
+					Values: [][]byte{[]byte("1"), []byte("a")},
+					Nulls: []byte{0, 0},
+				},
+			},
+			pg_pb3_ld.WireMessageType_WMSG_DELETE,
+		},
+	}
+	for _, test := range tests {
+		data := buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+			pg_pb3_ld.WireMessageType_WMSG_RELATION,
+			test.typ,
+		}, []proto.Message{rel, test.msg})
+		_, err := NewDecoder().Decode(data)
+		if err == nil {
+			t.Errorf("%s: unexpected success", test.name)
+		}
+	}
+}
+
+func TestDecoderStrict(t *testing.T) {
+	data := buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_SESSION_START,
+		pg_pb3_ld.WireMessageType(1000),
+		pg_pb3_ld.WireMessageType_WMSG_BEGIN,
+	}, []proto.Message{
+		&pg_pb3_ld.SessionStart{ProtocolVersion: 2},
+		&pg_pb3_ld.BeginTransaction{Xid: 1},
+		&pg_pb3_ld.BeginTransaction{Xid: 2},
+	})
+
+	decoder := NewDecoder()
+	if decoder.ProtoVersion() != 0 {
+		t.Fatalf("unexpected protocol version %d before SessionStart", decoder.ProtoVersion())
+	}
+	messages, err := decoder.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages; expected the message of the unknown type to be skipped", len(messages))
+	}
+	if begin, ok := messages[1].(*Begin); !ok || begin.Xid != 2 {
+		t.Errorf("unexpected message %+v", messages[1])
+	}
+	if decoder.ProtoVersion() != 2 {
+		t.Errorf("got protocol version %d; expected 2", decoder.ProtoVersion())
+	}
+
+	decoder = NewDecoder()
+	decoder.Strict = true
+	_, err = decoder.Decode(data)
+	var unknownTypeErr *UnknownMessageTypeError
+	if !errors.As(err, &unknownTypeErr) || unknownTypeErr.Index != 1 {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	data = buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_SESSION_START,
+	}, []proto.Message{
+		&pg_pb3_ld.SessionStart{ProtocolVersion: MaxProtoVersion + 1},
+	})
+	_, err = decoder.Decode(data)
+	var versionErr *UnsupportedProtoVersionError
+	if !errors.As(err, &versionErr) || versionErr.Version != MaxProtoVersion + 1 {
+		t.Errorf("unexpected error %v", err)
+	}
+	decoder = NewDecoder()
+	_, err = decoder.Decode(data)
+	if err != nil || decoder.ProtoVersion() != MaxProtoVersion + 1 {
+		t.Errorf("unexpected error %v or protocol version %d", err, decoder.ProtoVersion())
+	}
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgproto3/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultStatusInterval = 10 * time.Second
+
+// StreamConfig describes how a Stream connects to the server.
+type StreamConfig struct {
+	// ConnString is a libpq style connection string.  "replication=database"
+	// is added to it automatically.
+	ConnString string
+
+	// SlotName is the name of an existing logical replication slot using the
+	// pg_pb3_ld output plugin.
+	SlotName string
+
+	// StartLSN is the position to start streaming from.  If zero, streaming
+	// starts from the position the slot was last confirmed to.
+	StartLSN pglogrepl.LSN
+
+	Options Options
+
+	// StatusInterval is how often standby status updates are sent to the
+	// server.  Defaults to ten seconds.
+	StatusInterval time.Duration
+
+	// Strict makes Run fail on messages it doesn't know how to decode instead
+	// of skipping them; see Decoder.
+	Strict bool
+}
+
+// StreamMessage is a Message received over a Stream.
+type StreamMessage struct {
+	// LSN is the WAL position of the XLogData message the Message was
+	// received in.  For a Commit, this is the end of the transaction's commit
+	// record, and acknowledging it via Stream.Flush means that the
+	// transaction will not be sent again.
+	LSN pglogrepl.LSN
+
+	Message Message
+}
+
+// Stream is a logical replication connection streaming changes from a
+// pg_pb3_ld replication slot.  The slot only advances past the positions the
+// caller has acknowledged by calling Flush.
+type Stream struct {
+	conn *pgconn.PgConn
+	statusInterval time.Duration
+	decoder *Decoder
+
+	// Protected by lock, since Flush may be called from any goroutine.
+	lock sync.Mutex
+	receivedLSN pglogrepl.LSN
+	flushedLSN pglogrepl.LSN
+
+	replyRequested bool
+	nextStatusUpdate time.Time
+}
+
+// OpenStream opens a replication connection and starts streaming changes
+// from the slot.  No messages are received until Run is called.
+func OpenStream(ctx context.Context, config StreamConfig) (*Stream, error) {
+	if config.SlotName == "" {
+		return nil, fmt.Errorf("SlotName is required")
+	}
+	err := config.Options.Validate()
+	if err != nil {
+		return nil, err
+	}
+	statusInterval := config.StatusInterval
+	if statusInterval <= 0 {
+		statusInterval = defaultStatusInterval
+	}
+
+	connString := strings.TrimSpace(config.ConnString + " replication=database")
+	conn, err := pgconn.Connect(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+	err = pglogrepl.StartReplication(
+		ctx,
+		conn,
+		config.SlotName,
+		config.StartLSN,
+		pglogrepl.StartReplicationOptions{
+			PluginArgs: config.Options.ReplicationPluginArgs(),
+		},
+	)
+	if err != nil {
+		_ = conn.Close(context.Background())
+		return nil, err
+	}
+
+	decoder := NewDecoder()
+	decoder.Strict = config.Strict
+	return &Stream{
+		conn: conn,
+		statusInterval: statusInterval,
+		decoder: decoder,
+
+		receivedLSN: config.StartLSN,
+		flushedLSN: config.StartLSN,
+
+		replyRequested: false,
+		nextStatusUpdate: time.Now().Add(statusInterval),
+	}, nil
+}
+
+// Flush acknowledges that everything up to and including lsn has been
+// durably processed by the caller, and the server can release the WAL
+// required to decode it.  The position is reported to the server with the
+// next standby status update.  Flushing a position lower than a previously
+// flushed position has no effect.
+func (s *Stream) Flush(lsn pglogrepl.LSN) {
+	s.lock.Lock()
+	if lsn > s.flushedLSN {
+		s.flushedLSN = lsn
+	}
+	s.lock.Unlock()
+}
+
+// ProtoVersion returns the protocol version negotiated with the plugin, once
+// it has been reported by Run.  See Decoder.ProtoVersion.
+func (s *Stream) ProtoVersion() int {
+	return s.decoder.ProtoVersion()
+}
+
+// FlushedLSN returns the highest position passed to Flush.
+func (s *Stream) FlushedLSN() pglogrepl.LSN {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.flushedLSN
+}
+
+// Run receives messages from the server and calls handler for each of them,
+// in order.  Run returns when ctx is canceled, when the handler returns an
+// error or when the connection fails.  Calling Run again after it has
+// returned because ctx was canceled or the handler failed continues from
+// where the previous call left off.  Changes are expanded using the relation
+// descriptions received so far; see Decoder.
+func (s *Stream) Run(ctx context.Context, handler func(msg *StreamMessage) error) error {
+	return s.RunWireMessages(ctx, func(lsn pglogrepl.LSN, data []byte) error {
+		messages, err := s.decoder.Decode(data)
+		if err != nil {
+			return fmt.Errorf("could not decode wire message at %s: %w", lsn, err)
+		}
+		for _, msg := range messages {
+			err = handler(&StreamMessage{
+				LSN: lsn,
+				Message: msg,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RunWireMessages is like Run, except that the handler is called with the
+// undecoded wire messages and the WAL position they were received at.  The
+// handler must not retain data after it returns.
+func (s *Stream) RunWireMessages(ctx context.Context, handler func(lsn pglogrepl.LSN, data []byte) error) error {
+	for {
+		if s.replyRequested || !time.Now().Before(s.nextStatusUpdate) {
+			err := s.sendStatusUpdate(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		recvCtx, cancel := context.WithDeadline(ctx, s.nextStatusUpdate)
+		msg, err := s.conn.ReceiveMessage(recvCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) {
+				// time for a status update
+				continue
+			}
+			return err
+		}
+
+		var copyData *pgproto3.CopyData
+		switch msg := msg.(type) {
+			case *pgproto3.CopyData:
+				copyData = msg
+			case *pgproto3.ErrorResponse:
+				return pgconn.ErrorResponseToPgError(msg)
+			case *pgproto3.NoticeResponse, *pgproto3.ParameterStatus:
+				continue
+			default:
+				return fmt.Errorf("unexpected message %T from the server", msg)
+		}
+		if len(copyData.Data) == 0 {
+			return fmt.Errorf("unexpected empty CopyData message")
+		}
+
+		switch copyData.Data[0] {
+			case pglogrepl.PrimaryKeepaliveMessageByteID:
+				pkm, err := pglogrepl.ParsePrimaryKeepaliveMessage(copyData.Data[1:])
+				if err != nil {
+					return err
+				}
+				if pkm.ReplyRequested {
+					s.replyRequested = true
+				}
+			case pglogrepl.XLogDataByteID:
+				xld, err := pglogrepl.ParseXLogData(copyData.Data[1:])
+				if err != nil {
+					return err
+				}
+
+				s.lock.Lock()
+				if xld.WALStart > s.receivedLSN {
+					s.receivedLSN = xld.WALStart
+				}
+				s.lock.Unlock()
+
+				err = handler(xld.WALStart, xld.WALData)
+				if err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected CopyData message type %q", copyData.Data[0])
+		}
+	}
+}
+
+// Close sends a final standby status update containing the latest flushed
+// position and closes the replication connection.
+func (s *Stream) Close(ctx context.Context) error {
+	err := s.sendStatusUpdate(ctx)
+	closeErr := s.conn.Close(ctx)
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// sendStatusUpdate sends a standby status update to the server.  We can't use
+// pglogrepl.SendStandbyStatusUpdate here, since it replaces a zero flush
+// position with the write position, which would confirm changes the caller
+// hasn't acknowledged yet.  A zero flush position is ignored by the server.
+func (s *Stream) sendStatusUpdate(ctx context.Context) error {
+	s.lock.Lock()
+	receivedLSN := s.receivedLSN
+	flushedLSN := s.flushedLSN
+	s.lock.Unlock()
+
+	data := encodeStandbyStatusUpdate(receivedLSN, flushedLSN, time.Now())
+	err := s.conn.SendBytes(ctx, (&pgproto3.CopyData{Data: data}).Encode(nil))
+	if err != nil {
+		return err
+	}
+
+	s.replyRequested = false
+	s.nextStatusUpdate = time.Now().Add(s.statusInterval)
+	return nil
+}
+
+// encodeStandbyStatusUpdate builds the body of a Standby status update
+// message.  The flushed position is also reported as the applied position.
+func encodeStandbyStatusUpdate(receivedLSN pglogrepl.LSN, flushedLSN pglogrepl.LSN, now time.Time) []byte {
+	// microseconds since 2000-01-01
+	const postgresEpochMicros = 946684800 * 1000000
+
+	data := make([]byte, 34)
+	data[0] = pglogrepl.StandbyStatusUpdateByteID
+	binary.BigEndian.PutUint64(data[1:], uint64(receivedLSN))
+	binary.BigEndian.PutUint64(data[9:], uint64(flushedLSN))
+	binary.BigEndian.PutUint64(data[17:], uint64(flushedLSN))
+	binary.BigEndian.PutUint64(data[25:], uint64(now.UnixNano() / 1000 - postgresEpochMicros))
+	data[33] = 0
+	return data
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"encoding/binary"
+	"github.com/jackc/pglogrepl"
+	"testing"
+	"time"
+)
+
+func TestEncodeStandbyStatusUpdate(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 1, 0, time.UTC)
+	data := encodeStandbyStatusUpdate(pglogrepl.LSN(0x200), pglogrepl.LSN(0), now)
+	if len(data) != 34 || data[0] != 'r' {
+		t.Fatalf("unexpected status update %x", data)
+	}
+	if binary.BigEndian.Uint64(data[1:]) != 0x200 {
+		t.Errorf("unexpected write position %x", data[1:9])
+	}
+	// an unacknowledged stream must never report a non-zero flush position
+	if binary.BigEndian.Uint64(data[9:]) != 0 || binary.BigEndian.Uint64(data[17:]) != 0 {
+		t.Errorf("unexpected flush/apply position %x", data[9:25])
+	}
+	if binary.BigEndian.Uint64(data[25:]) != 1000000 {
+		t.Errorf("unexpected client time %x", data[25:33])
+	}
+}
+
+func TestStreamFlushIsMonotonic(t *testing.T) {
+	s := &Stream{}
+	s.Flush(pglogrepl.LSN(100))
+	s.Flush(pglogrepl.LSN(50))
+	if s.FlushedLSN() != 100 {
+		t.Fatalf("FlushedLSN %s; expected %s", s.FlushedLSN(), pglogrepl.LSN(100))
+	}
+}
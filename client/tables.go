@@ -0,0 +1,314 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TablePattern is a single pattern of the include_tables and exclude_tables
+// options.  In both Schema and Table, '*' matches any sequence of characters
+// and every other character matches itself.
+type TablePattern struct {
+	Schema string
+	Table string
+}
+
+func (p TablePattern) String() string {
+	return p.Schema + "." + p.Table
+}
+
+// Matches returns true if the pattern matches the table.
+func (p TablePattern) Matches(schemaName string, tableName string) bool {
+	return wildcardMatch(p.Schema, schemaName) && wildcardMatch(p.Table, tableName)
+}
+
+func (p TablePattern) validate() error {
+	if p.Schema == "" || p.Table == "" {
+		return fmt.Errorf("the schema and table parts of a table pattern can't be empty")
+	}
+	// the plugin splits the list on commas, ignores surrounding whitespace
+	// and splits the pattern on the dot
+	s := p.String()
+	if strings.Contains(s, ",") || strings.TrimSpace(s) != s || strings.Count(s, ".") != 1 {
+		return fmt.Errorf("table pattern %q can't be represented", s)
+	}
+	return nil
+}
+
+// ParseTablePatterns parses a comma-separated list of table patterns in the
+// format accepted by the include_tables and exclude_tables options.  The
+// errors mirror the ones the plugin would raise.
+func ParseTablePatterns(input string, optionName string) ([]TablePattern, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	values := strings.Split(input, ",")
+	patterns := make([]TablePattern, len(values))
+	for i, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("invalid input syntax for %s", optionName)
+		}
+		var err error
+		patterns[i], err = parseTablePattern(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s (while parsing %s pattern \"%s\")", err, optionName, value)
+		}
+	}
+	return patterns, nil
+}
+
+func parseTablePattern(value string) (TablePattern, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) == 1 {
+		return TablePattern{}, fmt.Errorf("table patterns must be schema-qualified")
+	} else if len(parts) > 2 {
+		return TablePattern{}, fmt.Errorf("table patterns must consist of exactly two parts")
+	}
+	p := TablePattern{Schema: parts[0], Table: parts[1]}
+	if p.Schema == "" || p.Table == "" {
+		return TablePattern{}, fmt.Errorf("the schema and table parts of a table pattern can't be empty")
+	}
+	return p, nil
+}
+
+// IncludesTable returns true if changes to the table would be decoded with
+// the include_tables and exclude_tables options.
+func (o *Options) IncludesTable(schemaName string, tableName string) bool {
+	if len(o.IncludeTables) > 0 && !matchesAnyTablePattern(o.IncludeTables, schemaName, tableName) {
+		return false
+	}
+	return !matchesAnyTablePattern(o.ExcludeTables, schemaName, tableName)
+}
+
+func matchesAnyTablePattern(patterns []TablePattern, schemaName string, tableName string) bool {
+	for _, p := range patterns {
+		if p.Matches(schemaName, tableName) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch is the same algorithm as pb3ld_wildcard_match in the plugin.
+func wildcardMatch(pattern string, str string) bool {
+	p, s := 0, 0
+	star, backtrack := -1, -1
+	for s < len(str) {
+		if p < len(pattern) && pattern[p] == '*' {
+			star = p
+			p++
+			backtrack = s
+		} else if p < len(pattern) && pattern[p] == str[s] {
+			p++
+			s++
+		} else if star != -1 {
+			// let the last star eat one more byte
+			p = star + 1
+			backtrack++
+			s = backtrack
+		} else {
+			return false
+		}
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+func renderTablePatterns(patterns []TablePattern) string {
+	rendered := make([]string, len(patterns))
+	for i, p := range patterns {
+		rendered[i] = p.String()
+	}
+	return strings.Join(rendered, ",")
+}
+
+// ColumnProjection is a single entry of the columns option.  If Exclude is
+// false, only the listed columns of the matching tables are sent; otherwise
+// the listed columns are not sent.  The columns of the replica identity index
+// are sent regardless.
+type ColumnProjection struct {
+	Table TablePattern
+	Columns []string
+	Exclude bool
+}
+
+func (p ColumnProjection) String() string {
+	columns := make([]string, len(p.Columns))
+	for i, col := range p.Columns {
+		if p.Exclude {
+			columns[i] = "-" + col
+		} else {
+			columns[i] = col
+		}
+	}
+	return p.Table.String() + "(" + strings.Join(columns, ",") + ")"
+}
+
+// IncludesColumn returns true if the projection doesn't exclude the column.
+// The columns of the replica identity index are sent even if this returns
+// false.
+func (p ColumnProjection) IncludesColumn(column string) bool {
+	for _, col := range p.Columns {
+		if col == column {
+			return !p.Exclude
+		}
+	}
+	return p.Exclude
+}
+
+func (p ColumnProjection) validate() error {
+	err := p.Table.validate()
+	if err != nil {
+		return err
+	}
+	if len(p.Columns) == 0 {
+		return fmt.Errorf("column names in columns can't be empty")
+	}
+	for _, col := range p.Columns {
+		// the plugin can't tell these apart from the syntax
+		if col == "" || strings.ContainsAny(col, ",;()") || strings.TrimSpace(col) != col ||
+			(!p.Exclude && strings.HasPrefix(col, "-")) {
+			return fmt.Errorf("column name %q in columns can't be represented", col)
+		}
+	}
+	if strings.Contains(p.Table.String(), ";") || strings.Contains(p.Table.String(), "(") {
+		return fmt.Errorf("table pattern %q can't be represented", p.Table.String())
+	}
+	return nil
+}
+
+// ParseColumnProjections parses the value of the columns option.  The errors
+// mirror the ones the plugin would raise.
+func ParseColumnProjections(input string) ([]ColumnProjection, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	var projections []ColumnProjection
+	for _, entry := range strings.Split(input, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			return nil, fmt.Errorf("invalid input syntax for columns")
+		}
+		p, err := parseColumnProjection(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s (while parsing columns entry \"%s\")", err, entry)
+		}
+		projections = append(projections, p)
+	}
+	return projections, nil
+}
+
+func parseColumnProjection(entry string) (ColumnProjection, error) {
+	var p ColumnProjection
+
+	openParen := strings.IndexByte(entry, '(')
+	if openParen == -1 || !strings.HasSuffix(entry, ")") {
+		return p, fmt.Errorf("invalid input syntax for columns")
+	}
+	pattern := strings.TrimSpace(entry[:openParen])
+	var err error
+	p.Table, err = parseTablePattern(pattern)
+	if err != nil {
+		return p, fmt.Errorf("%s (while parsing columns pattern \"%s\")", err, pattern)
+	}
+
+	haveIncluded := false
+	haveExcluded := false
+	for _, col := range strings.Split(entry[openParen + 1:len(entry) - 1], ",") {
+		col = strings.TrimSpace(col)
+		if strings.HasPrefix(col, "-") {
+			haveExcluded = true
+			col = col[1:]
+		} else {
+			haveIncluded = true
+		}
+		if col == "" {
+			return p, fmt.Errorf("column names in columns can't be empty")
+		}
+		if strings.ContainsAny(col, "()") {
+			return p, fmt.Errorf("invalid input syntax for columns")
+		}
+		p.Columns = append(p.Columns, col)
+	}
+	if haveIncluded && haveExcluded {
+		return p, fmt.Errorf("a columns entry can't both include and exclude columns")
+	}
+	p.Exclude = haveExcluded
+	return p, nil
+}
+
+// ColumnProjection returns the entry of the columns option which applies to
+// the table, or nil if all of its columns are sent.
+func (o *Options) ColumnProjection(schemaName string, tableName string) *ColumnProjection {
+	for i := range o.Columns {
+		if o.Columns[i].Table.Matches(schemaName, tableName) {
+			return &o.Columns[i]
+		}
+	}
+	return nil
+}
+
+// RowFilter is a single row_filter option.  Expression is a boolean SQL
+// expression which the plugin evaluates against the rows of the tables
+// matching Table; the client doesn't look into it.
+type RowFilter struct {
+	Table TablePattern
+	Expression string
+}
+
+func (f RowFilter) String() string {
+	return f.Table.String() + ": " + f.Expression
+}
+
+func (f RowFilter) validate() error {
+	err := f.Table.validate()
+	if err != nil {
+		return err
+	}
+	if strings.Contains(f.Table.String(), ":") {
+		return fmt.Errorf("table pattern %q can't be represented", f.Table.String())
+	}
+	if strings.TrimSpace(f.Expression) == "" {
+		return fmt.Errorf("invalid input syntax for row_filter")
+	}
+	return nil
+}
+
+// ParseRowFilter parses the value of a row_filter option.  Only the table
+// pattern is validated; the errors mirror the ones the plugin would raise.
+// Whether the expression is valid SQL is only known to the server.
+func ParseRowFilter(input string) (RowFilter, error) {
+	var f RowFilter
+
+	colon := strings.IndexByte(input, ':')
+	if colon == -1 {
+		return f, fmt.Errorf("invalid input syntax for row_filter")
+	}
+	pattern := strings.TrimSpace(input[:colon])
+	var err error
+	f.Table, err = parseTablePattern(pattern)
+	if err != nil {
+		return f, fmt.Errorf("%s (while parsing row_filter pattern \"%s\")", err, pattern)
+	}
+	f.Expression = strings.TrimSpace(input[colon + 1:])
+	if f.Expression == "" {
+		return f, fmt.Errorf("invalid input syntax for row_filter")
+	}
+	return f, nil
+}
+
+// RowFilter returns the row filter which applies to the table, or nil if
+// none does.
+func (o *Options) RowFilter(schemaName string, tableName string) *RowFilter {
+	for i := range o.RowFilters {
+		if o.RowFilters[i].Table.Matches(schemaName, tableName) {
+			return &o.RowFilters[i]
+		}
+	}
+	return nil
+}
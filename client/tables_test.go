@@ -0,0 +1,302 @@
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Mirrors TestTablePatternsInput in the integration tests.
+func TestParseTablePatterns(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", false, ""},
+		{"  ", false, ""},
+		{"foo", true, "table patterns must be schema-qualified"},
+		{"public.foo.bar", true, "table patterns must consist of exactly two parts"},
+		{".foo", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.foo,", true, "invalid input syntax for include_tables"},
+		{",public.foo", true, "invalid input syntax for include_tables"},
+		{"public.foo, ,public.bar", true, "invalid input syntax for include_tables"},
+		{"public.foo,bar", true, `table patterns must be schema-qualified (while parsing include_tables pattern "bar")`},
+		{"public.foo", false, ""},
+		{"*.*", false, ""},
+		{" public.* , audit.events_* ", false, ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseTablePatterns(test.input, "include_tables")
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+
+	patterns, err := ParseTablePatterns(" public.* , audit.events_* ", "include_tables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TablePattern{{"public", "*"}, {"audit", "events_*"}}
+	if !reflect.DeepEqual(patterns, expected) {
+		t.Fatalf("got %+v; expected %+v", patterns, expected)
+	}
+}
+
+func TestTablePatternMatches(t *testing.T) {
+	tests := []struct{
+		pattern TablePattern
+		schema string
+		table string
+		expected bool
+	}{
+		{TablePattern{"public", "foo"}, "public", "foo", true},
+		{TablePattern{"public", "foo"}, "public", "foobar", false},
+		{TablePattern{"public", "foo"}, "Public", "foo", false},
+		{TablePattern{"public", "*"}, "public", "foo", true},
+		{TablePattern{"public", "*"}, "audit", "foo", false},
+		{TablePattern{"*", "foo"}, "audit", "foo", true},
+		{TablePattern{"audit", "events_*"}, "audit", "events_2024", true},
+		{TablePattern{"audit", "events_*"}, "audit", "events_", true},
+		{TablePattern{"audit", "events_*"}, "audit", "events", false},
+		{TablePattern{"audit", "*_log"}, "audit", "login_log", true},
+		{TablePattern{"audit", "*_log"}, "audit", "log", false},
+		{TablePattern{"audit", "a*b*c"}, "audit", "abc", true},
+		{TablePattern{"audit", "a*b*c"}, "audit", "axxbxxbxc", true},
+		{TablePattern{"audit", "a*b*c"}, "audit", "axxbxxcx", false},
+		{TablePattern{"audit", "**"}, "audit", "", true},
+		{TablePattern{"p*", "t"}, "pg_catalog", "t", true},
+	}
+
+	for _, test := range tests {
+		matches := test.pattern.Matches(test.schema, test.table)
+		if matches != test.expected {
+			t.Errorf("pattern %q matching %s.%s returned %v; expected %v", test.pattern, test.schema, test.table, matches, test.expected)
+		}
+	}
+}
+
+func TestOptionsIncludesTable(t *testing.T) {
+	tests := []struct{
+		include string
+		exclude string
+		schema string
+		table string
+		expected bool
+	}{
+		{"", "", "public", "foo", true},
+		{"public.*", "", "public", "foo", true},
+		{"public.*", "", "audit", "foo", false},
+		{"public.*,audit.events_*", "", "audit", "events_1", true},
+		{"", "audit.*", "audit", "events_1", false},
+		{"", "audit.*", "public", "foo", true},
+		// exclude wins
+		{"public.*", "public.secret_*", "public", "secret_keys", false},
+		{"public.*", "public.secret_*", "public", "keys", true},
+		{"public.foo", "public.foo", "public", "foo", false},
+	}
+
+	for _, test := range tests {
+		var o Options
+		var err error
+		o.IncludeTables, err = ParseTablePatterns(test.include, "include_tables")
+		if err != nil {
+			t.Fatal(err)
+		}
+		o.ExcludeTables, err = ParseTablePatterns(test.exclude, "exclude_tables")
+		if err != nil {
+			t.Fatal(err)
+		}
+		included := o.IncludesTable(test.schema, test.table)
+		if included != test.expected {
+			t.Errorf("include %q, exclude %q: IncludesTable(%q, %q) returned %v; expected %v", test.include, test.exclude, test.schema, test.table, included, test.expected)
+		}
+	}
+}
+
+// Mirrors TestColumnProjectionsInput in the integration tests.
+func TestParseColumnProjections(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", false, ""},
+		{"public.foo", true, "invalid input syntax for columns"},
+		{"public.foo(a", true, "invalid input syntax for columns"},
+		{"public.foo(a);", true, "invalid input syntax for columns"},
+		{"public.foo()", true, "column names in columns can't be empty"},
+		{"public.foo(a,)", true, "column names in columns can't be empty"},
+		{"public.foo(-)", true, "column names in columns can't be empty"},
+		{"public.foo(a,(b))", true, "invalid input syntax for columns"},
+		{"public.foo(a, -b)", true, "a columns entry can't both include and exclude columns"},
+		{"foo(a)", true, "table patterns must be schema-qualified"},
+		{"public.foo(a)", false, ""},
+		{"public.foo(-a, -b)", false, ""},
+		{" public.foo ( a , b ) ; audit.*(-payload) ", false, ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseColumnProjections(test.input)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+
+	projections, err := ParseColumnProjections(" public.foo ( a , b ) ; audit.*(-payload) ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ColumnProjection{
+		{TablePattern{"public", "foo"}, []string{"a", "b"}, false},
+		{TablePattern{"audit", "*"}, []string{"payload"}, true},
+	}
+	if !reflect.DeepEqual(projections, expected) {
+		t.Fatalf("got %+v; expected %+v", projections, expected)
+	}
+
+	o := Options{Columns: projections}
+	if o.SQLFunctionArgs()[1] != "public.foo(a,b);audit.*(-payload)" {
+		t.Errorf("unexpected rendering %q", o.SQLFunctionArgs()[1])
+	}
+}
+
+func TestOptionsColumnProjection(t *testing.T) {
+	projections, err := ParseColumnProjections("public.foo(a, b); public.*(-payload)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Options{Columns: projections}
+
+	tests := []struct{
+		schema string
+		table string
+		column string
+		expected bool
+	}{
+		{"public", "foo", "a", true},
+		{"public", "foo", "c", false},
+		// only the first matching entry applies
+		{"public", "foo", "payload", false},
+		{"public", "bar", "payload", false},
+		{"public", "bar", "a", true},
+		{"audit", "bar", "payload", true},
+	}
+	for _, test := range tests {
+		included := true
+		p := o.ColumnProjection(test.schema, test.table)
+		if p != nil {
+			included = p.IncludesColumn(test.column)
+		}
+		if included != test.expected {
+			t.Errorf("column %q of %s.%s included is %v; expected %v", test.column, test.schema, test.table, included, test.expected)
+		}
+	}
+}
+
+// Mirrors TestRowFilterInput in the integration tests, except for the errors
+// only the server can detect.
+func TestParseRowFilter(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, "invalid input syntax for row_filter"},
+		{"public.foo", true, "invalid input syntax for row_filter"},
+		{"public.foo:", true, "invalid input syntax for row_filter"},
+		{"public.foo:   ", true, "invalid input syntax for row_filter"},
+		{"foo: true", true, `table patterns must be schema-qualified (while parsing row_filter pattern "foo")`},
+		{": true", true, "table patterns must be schema-qualified"},
+		{"public.: true", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.foo: true", false, ""},
+		{" public.* : a::int > 0 ", false, ""},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRowFilter(test.input)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+
+	// only the first colon separates the pattern from the expression
+	f, err := ParseRowFilter(" public.* : a::int > 0 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := RowFilter{TablePattern{"public", "*"}, "a::int > 0"}
+	if !reflect.DeepEqual(f, expected) {
+		t.Fatalf("got %+v; expected %+v", f, expected)
+	}
+	if f.String() != "public.*: a::int > 0" {
+		t.Errorf("unexpected rendering %q", f.String())
+	}
+}
+
+func TestOptionsRowFilter(t *testing.T) {
+	o := Options{
+		RowFilters: []RowFilter{
+			{TablePattern{"public", "orders"}, "status <> 'draft'"},
+			{TablePattern{"public", "*"}, "tenant_id = 5"},
+		},
+	}
+
+	tests := []struct{
+		schema string
+		table string
+		expected string
+	}{
+		{"public", "orders", "status <> 'draft'"},
+		{"public", "invoices", "tenant_id = 5"},
+		{"audit", "orders", ""},
+	}
+	for _, test := range tests {
+		expression := ""
+		f := o.RowFilter(test.schema, test.table)
+		if f != nil {
+			expression = f.Expression
+		}
+		if expression != test.expected {
+			t.Errorf("row filter of %s.%s is %q; expected %q", test.schema, test.table, expression, test.expected)
+		}
+	}
+}
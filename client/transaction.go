@@ -0,0 +1,522 @@
+package client
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"github.com/jackc/pglogrepl"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+	"io"
+	"os"
+)
+
+const defaultMaxTransactionMemory = 64 * 1024 * 1024
+
+// Change is a single change within a Transaction.
+type Change struct {
+	// LSN is the position of the wire message the change was received in.
+	LSN pglogrepl.LSN
+
+	// Message is an *Insert, *Update, *Delete, *Truncate or *LogicalMessage.
+	// Relations and sequences aren't changes; see Decoder.Relation and
+	// Transaction.Sequences.
+	Message Message
+}
+
+// Transaction is a complete transaction assembled by a TransactionAssembler.
+type Transaction struct {
+	// Begin is nil unless begin messages are enabled.
+	Begin *Begin
+	// Commit is nil for a non-transactional LogicalMessage, which is returned
+	// in a Transaction of its own, and for a prepared transaction.
+	Commit *Commit
+
+	// Prepare is set instead of Commit if the transaction was prepared for
+	// two-phase commit.  Whether it commits or rolls back is returned later
+	// in a Transaction of its own with no changes and either CommitPrepared
+	// or RollbackPrepared set.
+	Prepare *Prepare
+	CommitPrepared *CommitPrepared
+	RollbackPrepared *RollbackPrepared
+
+	// CommitLSN is the position the message which completed the transaction
+	// was received at.  Acknowledging it via Stream.Flush means that the
+	// transaction will not be sent again.
+	CommitLSN pglogrepl.LSN
+
+	// Changes contains the changes of the transaction in order, unless the
+	// transaction was too large to keep in memory and was spilled to disk,
+	// in which case Changes is nil.  ForEachChange works in both cases.
+	Changes []Change
+
+	// Sequences contains the values of the sequences owned by the tables the
+	// transaction changed, if sequence messages are enabled.  They're never
+	// spilled, and aren't counted by NumChanges.
+	Sequences []*Sequence
+
+	numChanges int
+	spillFile *os.File
+	// the number of changes in the spill file, including the changes of
+	// abortedSubxacts
+	numSpilledChanges int
+	// subtransactions of a streamed transaction which rolled back after
+	// their changes had been spilled
+	abortedSubxacts map[uint32]bool
+}
+
+// NumChanges returns the number of changes in the transaction.
+func (t *Transaction) NumChanges() int {
+	return t.numChanges
+}
+
+// Spilled returns true if the changes of the transaction were spilled to disk.
+func (t *Transaction) Spilled() bool {
+	return t.spillFile != nil
+}
+
+// ForEachChange calls fn for each change of the transaction in order.  If the
+// transaction was spilled to disk, the changes are read back from the spill
+// file one at a time.
+func (t *Transaction) ForEachChange(fn func(change Change) error) error {
+	if t.spillFile == nil {
+		for _, change := range t.Changes {
+			err := fn(change)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_, err := t.spillFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(t.spillFile)
+	for i := 0; i < t.numSpilledChanges; i++ {
+		change, err := readSpilledChange(reader)
+		if err != nil {
+			return fmt.Errorf("could not read change %d from spill file %s: %w", i, t.spillFile.Name(), err)
+		}
+		if t.abortedSubxacts[streamXid(change.Message)] {
+			continue
+		}
+		err = fn(change)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the spill file of the transaction, if any.
+func (t *Transaction) Close() error {
+	if t.spillFile == nil {
+		return nil
+	}
+	name := t.spillFile.Name()
+	err := t.spillFile.Close()
+	removeErr := os.Remove(name)
+	t.spillFile = nil
+	if err != nil {
+		return err
+	}
+	return removeErr
+}
+
+// TransactionAssemblerConfig configures a TransactionAssembler.
+type TransactionAssemblerConfig struct {
+	// MaxMemory is the approximate number of bytes of changes a single
+	// transaction is allowed to hold in memory before its changes are
+	// spilled to a temporary file.  Defaults to 64MB.
+	MaxMemory int
+
+	// TempDir is the directory spill files are created in.  Defaults to
+	// os.TempDir().
+	TempDir string
+}
+
+// TransactionAssembler groups the flat sequence of messages received from the
+// plugin into complete transactions, regardless of how many wire messages a
+// transaction was split into.  Commit messages must be enabled.
+//
+// If the streaming option is enabled, the changes of a large transaction are
+// received in stream blocks while the transaction is still in progress, and
+// blocks of different transactions can be interleaved with each other and
+// with other transactions.  The assembler holds on to the changes of each
+// streamed transaction until its StreamCommit has been added, at which point
+// it returns the transaction like any other, with a Commit built from the
+// StreamCommit and no Begin.  The changes are discarded if the transaction
+// rolls back, and the changes of a subtransaction are discarded if only the
+// subtransaction rolls back.  A transactional LogicalMessage emitted in a
+// subtransaction is only discarded if the whole transaction rolls back, since
+// the plugin doesn't know which subtransaction it came from.
+//
+// If the two_phase option is enabled, a transaction which is prepared for
+// two-phase commit is returned once its Prepare or StreamPrepare has been
+// added, with Prepare set instead of Commit.  Its CommitPrepared or
+// RollbackPrepared is returned separately when it's added, possibly after the
+// stream has been restarted.  The assembler doesn't keep track of prepared
+// transactions, so matching the two up by Gid is up to the caller.
+type TransactionAssembler struct {
+	maxMemory int
+	tempDir string
+
+	current *pendingTransaction
+	// streamed transactions by xid, and the one whose stream block is being
+	// added, if any
+	streamed map[uint32]*pendingTransaction
+	currentStream *pendingTransaction
+}
+
+// pendingTransaction is a transaction whose Commit or Prepare hasn't been
+// added yet.
+type pendingTransaction struct {
+	txn *Transaction
+	memory int
+	spillWriter *bufio.Writer
+	// the number of changes of each subtransaction of a streamed transaction
+	subxactChanges map[uint32]int
+}
+
+func NewTransactionAssembler(config TransactionAssemblerConfig) *TransactionAssembler {
+	maxMemory := config.MaxMemory
+	if maxMemory <= 0 {
+		maxMemory = defaultMaxTransactionMemory
+	}
+	return &TransactionAssembler{
+		maxMemory: maxMemory,
+		tempDir: config.TempDir,
+		streamed: make(map[uint32]*pendingTransaction),
+	}
+}
+
+// Add adds the next message received from the plugin.  Once the Commit of a
+// transaction has been added, Add returns the complete transaction; otherwise
+// it returns nil.  Non-transactional logical messages are not part of any
+// transaction, and are returned immediately in a Transaction with no Begin or
+// Commit.  The caller is responsible for calling Close on the returned
+// transaction.
+func (a *TransactionAssembler) Add(msg *StreamMessage) (*Transaction, error) {
+	if m, ok := msg.Message.(*LogicalMessage); ok && !m.Transactional {
+		return &Transaction{
+			CommitLSN: msg.LSN,
+			Changes: []Change{{LSN: msg.LSN, Message: m}},
+			numChanges: 1,
+		}, nil
+	}
+
+	switch m := msg.Message.(type) {
+		case *SessionStart:
+			// not part of any transaction
+			return nil, nil
+		case *Relation:
+			// only needed by the Decoder, which has already seen it
+			return nil, nil
+		case *Sequence:
+			p := a.pending()
+			p.txn.Sequences = append(p.txn.Sequences, m)
+			return nil, nil
+		case *CommitPrepared:
+			if a.current != nil || a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected CommitPrepared at %s while a transaction is in progress", msg.LSN)
+			}
+			return &Transaction{CommitPrepared: m, CommitLSN: msg.LSN}, nil
+		case *RollbackPrepared:
+			if a.current != nil || a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected RollbackPrepared at %s while a transaction is in progress", msg.LSN)
+			}
+			return &Transaction{RollbackPrepared: m, CommitLSN: msg.LSN}, nil
+		case *Begin:
+			if a.current != nil {
+				return nil, fmt.Errorf("unexpected BeginTransaction at %s while a transaction is in progress", msg.LSN)
+			}
+			if a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected BeginTransaction at %s in a stream block", msg.LSN)
+			}
+			a.current = &pendingTransaction{txn: &Transaction{Begin: m}}
+			return nil, nil
+		case *Commit:
+			if a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected CommitTransaction at %s in a stream block", msg.LSN)
+			}
+			if a.current == nil {
+				// a transaction without changes
+				a.current = &pendingTransaction{txn: &Transaction{}}
+			}
+			p := a.current
+			a.current = nil
+			p.txn.Commit = m
+			return p.finish(msg.LSN)
+		case *Prepare:
+			if a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected PrepareTransaction at %s in a stream block", msg.LSN)
+			}
+			if a.current == nil {
+				// sent even if the transaction had no changes
+				a.current = &pendingTransaction{txn: &Transaction{}}
+			}
+			p := a.current
+			a.current = nil
+			p.txn.Prepare = m
+			return p.finish(msg.LSN)
+		case *StreamStart:
+			if a.current != nil {
+				return nil, fmt.Errorf("unexpected StreamStart at %s while a transaction is in progress", msg.LSN)
+			}
+			if a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected StreamStart at %s in a stream block", msg.LSN)
+			}
+			p := a.streamed[m.Xid]
+			if p == nil {
+				p = &pendingTransaction{txn: &Transaction{}}
+				a.streamed[m.Xid] = p
+			}
+			a.currentStream = p
+			return nil, nil
+		case *StreamStop:
+			if a.currentStream == nil {
+				return nil, fmt.Errorf("unexpected StreamStop at %s outside of a stream block", msg.LSN)
+			}
+			a.currentStream = nil
+			return nil, nil
+		case *StreamCommit:
+			if a.current != nil || a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected StreamCommit at %s while a transaction is in progress", msg.LSN)
+			}
+			p := a.streamed[m.Xid]
+			if p == nil {
+				// none of the changes of the transaction were streamed
+				p = &pendingTransaction{txn: &Transaction{}}
+			}
+			delete(a.streamed, m.Xid)
+			p.txn.Commit = &Commit{&pg_pb3_ld.CommitTransaction{
+				Xid: m.Xid,
+				CommitLsn: m.CommitLsn,
+				EndLsn: m.EndLsn,
+				CommitTime: m.CommitTime,
+			}}
+			return p.finish(msg.LSN)
+		case *StreamPrepare:
+			if a.current != nil || a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected StreamPrepare at %s while a transaction is in progress", msg.LSN)
+			}
+			p := a.streamed[m.Xid]
+			if p == nil {
+				p = &pendingTransaction{txn: &Transaction{}}
+			}
+			delete(a.streamed, m.Xid)
+			p.txn.Prepare = &Prepare{&pg_pb3_ld.PrepareTransaction{
+				Xid: m.Xid,
+				Gid: m.Gid,
+				PrepareLsn: m.PrepareLsn,
+				EndLsn: m.EndLsn,
+				PrepareTime: m.PrepareTime,
+			}}
+			return p.finish(msg.LSN)
+		case *StreamAbort:
+			if a.current != nil || a.currentStream != nil {
+				return nil, fmt.Errorf("unexpected StreamAbort at %s while a transaction is in progress", msg.LSN)
+			}
+			p := a.streamed[m.Xid]
+			if p == nil {
+				return nil, nil
+			}
+			if m.Subxid != 0 && m.Subxid != m.Xid {
+				p.abortSubtransaction(m.Subxid)
+				return nil, nil
+			}
+			delete(a.streamed, m.Xid)
+			return nil, p.txn.Close()
+		default:
+			return nil, a.addChange(a.pending(), Change{LSN: msg.LSN, Message: msg.Message})
+	}
+}
+
+// pending returns the transaction whose stream block is being added, or
+// otherwise the transaction in progress, which is started if there isn't one
+// since begin messages might be disabled.
+func (a *TransactionAssembler) pending() *pendingTransaction {
+	if a.currentStream != nil {
+		return a.currentStream
+	}
+	if a.current == nil {
+		a.current = &pendingTransaction{txn: &Transaction{}}
+	}
+	return a.current
+}
+
+func (a *TransactionAssembler) addChange(p *pendingTransaction, change Change) error {
+	txn := p.txn
+	txn.numChanges++
+	if xid := streamXid(change.Message); xid != 0 {
+		if p.subxactChanges == nil {
+			p.subxactChanges = make(map[uint32]int)
+		}
+		p.subxactChanges[xid]++
+	}
+
+	if txn.spillFile == nil {
+		txn.Changes = append(txn.Changes, change)
+		p.memory += proto.Size(change.Message)
+		if p.memory <= a.maxMemory {
+			return nil
+		}
+
+		f, err := os.CreateTemp(a.tempDir, "pg_pb3_ld-txn-*.spill")
+		if err != nil {
+			return err
+		}
+		txn.spillFile = f
+		p.spillWriter = bufio.NewWriter(f)
+		for _, change := range txn.Changes {
+			err = writeSpilledChange(p.spillWriter, change)
+			if err != nil {
+				return err
+			}
+		}
+		txn.numSpilledChanges = len(txn.Changes)
+		txn.Changes = nil
+		p.memory = 0
+		return nil
+	}
+
+	txn.numSpilledChanges++
+	return writeSpilledChange(p.spillWriter, change)
+}
+
+func (p *pendingTransaction) finish(lsn pglogrepl.LSN) (*Transaction, error) {
+	txn := p.txn
+	txn.CommitLSN = lsn
+	if p.spillWriter != nil {
+		err := p.spillWriter.Flush()
+		if err != nil {
+			_ = txn.Close()
+			return nil, err
+		}
+	}
+	return txn, nil
+}
+
+// abortSubtransaction discards the changes of a subtransaction of a streamed
+// transaction.  Changes which have already been spilled are skipped when
+// they're read back instead.
+func (p *pendingTransaction) abortSubtransaction(subxid uint32) {
+	n := p.subxactChanges[subxid]
+	if n == 0 {
+		return
+	}
+	delete(p.subxactChanges, subxid)
+
+	txn := p.txn
+	txn.numChanges -= n
+	if txn.spillFile != nil {
+		if txn.abortedSubxacts == nil {
+			txn.abortedSubxacts = make(map[uint32]bool)
+		}
+		txn.abortedSubxacts[subxid] = true
+		return
+	}
+
+	changes := txn.Changes[:0]
+	for _, change := range txn.Changes {
+		if streamXid(change.Message) == subxid {
+			p.memory -= proto.Size(change.Message)
+			continue
+		}
+		changes = append(changes, change)
+	}
+	// don't keep the discarded messages alive
+	for i := len(changes); i < len(txn.Changes); i++ {
+		txn.Changes[i] = Change{}
+	}
+	txn.Changes = changes
+}
+
+// Close discards the transactions in progress, if any.  This should be called
+// when the stream the messages were received from is closed, since the
+// transactions will be sent again in their entirety.
+func (a *TransactionAssembler) Close() error {
+	var err error
+	if a.current != nil {
+		err = a.current.txn.Close()
+		a.current = nil
+	}
+	for xid, p := range a.streamed {
+		closeErr := p.txn.Close()
+		if err == nil {
+			err = closeErr
+		}
+		delete(a.streamed, xid)
+	}
+	a.currentStream = nil
+	return err
+}
+
+// streamXid returns the xid of the (sub)transaction a change received in a
+// stream block belongs to, or 0 if the change wasn't streamed.
+func streamXid(msg Message) uint32 {
+	switch m := msg.(type) {
+		case *Insert:
+			return m.GetStreamXid()
+		case *Update:
+			return m.GetStreamXid()
+		case *Delete:
+			return m.GetStreamXid()
+		case *Truncate:
+			return m.GetStreamXid()
+		case *LogicalMessage:
+			return m.GetStreamXid()
+		default:
+			return 0
+	}
+}
+
+// Spilled changes are stored as the LSN, the message type and the length of
+// the marshaled message as uvarints, followed by the marshaled message.
+func writeSpilledChange(w *bufio.Writer, change Change) error {
+	data, err := proto.Marshal(change.Message)
+	if err != nil {
+		return err
+	}
+	var buf [3 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(change.LSN))
+	n += binary.PutUvarint(buf[n:], uint64(change.Message.Type()))
+	n += binary.PutUvarint(buf[n:], uint64(len(data)))
+	_, err = w.Write(buf[:n])
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func readSpilledChange(r *bufio.Reader) (Change, error) {
+	lsn, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Change{}, err
+	}
+	typ, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Change{}, err
+	}
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return Change{}, err
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	if err != nil {
+		return Change{}, err
+	}
+	msg := newMessage(pg_pb3_ld.WireMessageType(typ))
+	if msg == nil {
+		return Change{}, fmt.Errorf("unknown message type %d", typ)
+	}
+	err = proto.Unmarshal(data, msg)
+	if err != nil {
+		return Change{}, err
+	}
+	return Change{LSN: pglogrepl.LSN(lsn), Message: msg}, nil
+}
@@ -0,0 +1,395 @@
+package client
+
+import (
+	"fmt"
+	"github.com/jackc/pglogrepl"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+	"strings"
+	"testing"
+)
+
+func testInsert(value string) *Insert {
+	return &Insert{&pg_pb3_ld.InsertDescription{
+		Table: &pg_pb3_ld.TableDescription{
+			SchemaName: "public",
+			TableName: "tbl_identity_full",
+		},
+		NewValues: &pg_pb3_ld.FieldSetDescription{
+			Names: []string{"f1", "f2"},
+			Values: [][]byte{[]byte("1"), []byte(value)},
+			Nulls: []byte{0, 0},
+		},
+	}}
+}
+
+func TestTransactionAssembler(t *testing.T) {
+	for _, maxMemory := range []int{0, 1024} {
+		t.Run(fmt.Sprintf("MaxMemory=%d", maxMemory), func(t *testing.T) {
+			a := NewTransactionAssembler(TransactionAssemblerConfig{
+				MaxMemory: maxMemory,
+				TempDir: t.TempDir(),
+			})
+			defer a.Close()
+
+			var expected []proto.Message
+			begin := &Begin{&pg_pb3_ld.BeginTransaction{OriginName: "replicator"}}
+			messages := []*StreamMessage{{LSN: 1, Message: begin}}
+			for i := 0; i < 100; i++ {
+				ins := testInsert(strings.Repeat("j", i))
+				expected = append(expected, ins)
+				// spread the changes over several wire messages
+				messages = append(messages, &StreamMessage{LSN: pglogrepl.LSN(10 + i / 10), Message: ins})
+			}
+			messages = append(messages, &StreamMessage{LSN: 100, Message: &Commit{&pg_pb3_ld.CommitTransaction{}}})
+
+			var txn *Transaction
+			for i, msg := range messages {
+				var err error
+
+				txn, err = a.Add(msg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if txn != nil && i != len(messages) - 1 {
+					t.Fatalf("unexpected transaction after message %d", i)
+				}
+			}
+			if txn == nil {
+				t.Fatal("no transaction after Commit")
+			}
+			defer txn.Close()
+
+			if txn.Begin == nil || txn.Commit == nil || txn.CommitLSN != 100 {
+				t.Fatalf("unexpected transaction %+v", txn)
+			}
+			if txn.OriginName() != "replicator" {
+				t.Fatalf("OriginName() %q; expected %q", txn.OriginName(), "replicator")
+			}
+			if txn.Spilled() != (maxMemory > 0) {
+				t.Fatalf("Spilled() %v with MaxMemory %d", txn.Spilled(), maxMemory)
+			}
+			if txn.NumChanges() != len(expected) {
+				t.Fatalf("NumChanges() %d; expected %d", txn.NumChanges(), len(expected))
+			}
+			i := 0
+			err := txn.ForEachChange(func(change Change) error {
+				if _, ok := change.Message.(*Insert); !ok {
+					return fmt.Errorf("unexpected type %T for change %d", change.Message, i)
+				}
+				if change.LSN != pglogrepl.LSN(10 + i / 10) {
+					return fmt.Errorf("unexpected LSN %s for change %d", change.LSN, i)
+				}
+				if !proto.Equal(change.Message, expected[i]) {
+					return fmt.Errorf("change %d does not match", i)
+				}
+				i++
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if i != len(expected) {
+				t.Fatalf("ForEachChange returned %d changes; expected %d", i, len(expected))
+			}
+		})
+	}
+}
+
+func TestTransactionAssemblerWithoutBegin(t *testing.T) {
+	a := NewTransactionAssembler(TransactionAssemblerConfig{})
+
+	txn, err := a.Add(&StreamMessage{LSN: 1, Message: testInsert("a")})
+	if err != nil || txn != nil {
+		t.Fatalf("unexpected result %+v, %v", txn, err)
+	}
+	txn, err = a.Add(&StreamMessage{LSN: 2, Message: &Commit{&pg_pb3_ld.CommitTransaction{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn == nil || txn.Begin != nil || len(txn.Changes) != 1 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	if txn.OriginName() != "" {
+		t.Fatalf("OriginName() %q without a Begin", txn.OriginName())
+	}
+
+	// a non-transactional message is returned on its own without disturbing
+	// the transaction in progress
+	_, err = a.Add(&StreamMessage{LSN: 3, Message: testInsert("b")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &LogicalMessage{&pg_pb3_ld.LogicalMessage{Prefix: "test", Content: []byte("foo")}}
+	txn, err = a.Add(&StreamMessage{LSN: 4, Message: msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn == nil || txn.Commit != nil || txn.CommitLSN != 4 || len(txn.Changes) != 1 || txn.Changes[0].Message != msg {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	txn, err = a.Add(&StreamMessage{LSN: 5, Message: &Commit{&pg_pb3_ld.CommitTransaction{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn == nil || len(txn.Changes) != 1 || txn.CommitLSN != 5 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+
+	_, err = a.Add(&StreamMessage{LSN: 6, Message: &Begin{&pg_pb3_ld.BeginTransaction{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.Add(&StreamMessage{LSN: 7, Message: &Begin{&pg_pb3_ld.BeginTransaction{}}})
+	if err == nil {
+		t.Fatal("unexpected success for a nested BeginTransaction")
+	}
+}
+
+func testStreamedInsert(value string, xid uint32) *Insert {
+	ins := testInsert(value)
+	ins.StreamXid = xid
+	return ins
+}
+
+// Relations are left to the Decoder, and sequences aren't changes.
+func TestTransactionAssemblerRelationsAndSequences(t *testing.T) {
+	a := NewTransactionAssembler(TransactionAssemblerConfig{})
+
+	rel := &Relation{&pg_pb3_ld.RelationDescription{RelationId: 1234}}
+	seq := &Sequence{&pg_pb3_ld.SequenceDescription{SequenceName: "tbl_serial_id_seq", LastValue: 1}}
+	ins := testInsert("a")
+	for _, msg := range []Message{rel, ins, seq} {
+		txn, err := a.Add(&StreamMessage{LSN: 1, Message: msg})
+		if err != nil || txn != nil {
+			t.Fatalf("unexpected result %+v, %v", txn, err)
+		}
+	}
+	txn, err := a.Add(&StreamMessage{LSN: 2, Message: &Commit{&pg_pb3_ld.CommitTransaction{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn == nil || txn.NumChanges() != 1 || txn.Changes[0].Message != ins {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	if len(txn.Sequences) != 1 || txn.Sequences[0] != seq {
+		t.Fatalf("unexpected sequences %+v", txn.Sequences)
+	}
+
+	// a Relation by itself doesn't start a transaction
+	txn, err = a.Add(&StreamMessage{LSN: 3, Message: rel})
+	if err != nil || txn != nil {
+		t.Fatalf("unexpected result %+v, %v", txn, err)
+	}
+	_, err = a.Add(&StreamMessage{LSN: 4, Message: &StreamStart{&pg_pb3_ld.StreamStart{Xid: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransactionAssemblerStreaming(t *testing.T) {
+	for _, maxMemory := range []int{0, 1} {
+		t.Run(fmt.Sprintf("MaxMemory=%d", maxMemory), func(t *testing.T) {
+			a := NewTransactionAssembler(TransactionAssemblerConfig{
+				MaxMemory: maxMemory,
+				TempDir: t.TempDir(),
+			})
+			defer a.Close()
+
+			// 100 is the top-level transaction and 101 and 102 are its
+			// subtransactions; 101 rolls back and 200 rolls back entirely
+			messages := []Message{
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100, FirstSegment: true}},
+				testStreamedInsert("a1", 100),
+				testStreamedInsert("s1", 101),
+				&StreamStop{&pg_pb3_ld.StreamStop{Xid: 100}},
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 200, FirstSegment: true}},
+				testStreamedInsert("b1", 200),
+				&StreamStop{&pg_pb3_ld.StreamStop{Xid: 200}},
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+				testStreamedInsert("s2", 101),
+				testStreamedInsert("s3", 102),
+				testStreamedInsert("a2", 100),
+				&StreamStop{&pg_pb3_ld.StreamStop{Xid: 100}},
+				&StreamAbort{&pg_pb3_ld.StreamAbort{Xid: 100, Subxid: 101}},
+				&StreamAbort{&pg_pb3_ld.StreamAbort{Xid: 200, Subxid: 200}},
+			}
+			for i, msg := range messages {
+				txn, err := a.Add(&StreamMessage{LSN: pglogrepl.LSN(i + 1), Message: msg})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if txn != nil {
+					t.Fatalf("unexpected transaction after message %d", i)
+				}
+			}
+
+			// an ordinary transaction in between streamed ones
+			_, err := a.Add(&StreamMessage{LSN: 20, Message: testInsert("c")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			txn, err := a.Add(&StreamMessage{LSN: 21, Message: &Commit{&pg_pb3_ld.CommitTransaction{}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txn == nil || txn.NumChanges() != 1 {
+				t.Fatalf("unexpected transaction %+v", txn)
+			}
+			txn.Close()
+
+			txn, err = a.Add(&StreamMessage{LSN: 30, Message: &StreamCommit{&pg_pb3_ld.StreamCommit{Xid: 100, CommitLsn: 29}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txn == nil {
+				t.Fatal("no transaction after StreamCommit")
+			}
+			defer txn.Close()
+
+			if txn.Begin != nil || txn.Commit.GetXid() != 100 || txn.Commit.GetCommitLsn() != 29 || txn.CommitLSN != 30 {
+				t.Fatalf("unexpected transaction %+v", txn)
+			}
+			if txn.Spilled() != (maxMemory > 0) {
+				t.Fatalf("Spilled() %v with MaxMemory %d", txn.Spilled(), maxMemory)
+			}
+			expected := []string{"a1", "s3", "a2"}
+			if txn.NumChanges() != len(expected) {
+				t.Fatalf("NumChanges() %d; expected %d", txn.NumChanges(), len(expected))
+			}
+			var values []string
+			err = txn.ForEachChange(func(change Change) error {
+				values = append(values, string(change.Message.(*Insert).GetNewValues().Values[1]))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(values, ",") != strings.Join(expected, ",") {
+				t.Fatalf("got changes %q; expected %q", values, expected)
+			}
+		})
+	}
+}
+
+func TestTransactionAssemblerTwoPhase(t *testing.T) {
+	a := NewTransactionAssembler(TransactionAssemblerConfig{})
+	defer a.Close()
+
+	add := func(lsn pglogrepl.LSN, msg Message) *Transaction {
+		txn, err := a.Add(&StreamMessage{LSN: lsn, Message: msg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txn
+	}
+
+	add(1, &Begin{&pg_pb3_ld.BeginTransaction{}})
+	add(2, testInsert("p"))
+	txn := add(3, &Prepare{&pg_pb3_ld.PrepareTransaction{Xid: 300, Gid: "g1"}})
+	if txn == nil || txn.Begin == nil || txn.Commit != nil || txn.Prepare.GetGid() != "g1" || txn.CommitLSN != 3 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	if txn.NumChanges() != 1 {
+		t.Fatalf("NumChanges() %d; expected 1", txn.NumChanges())
+	}
+
+	// a prepared transaction without changes, and the outcome of both
+	txn = add(4, &Prepare{&pg_pb3_ld.PrepareTransaction{Xid: 301, Gid: "g2"}})
+	if txn == nil || txn.Prepare.GetGid() != "g2" || txn.NumChanges() != 0 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	txn = add(5, &RollbackPrepared{&pg_pb3_ld.RollbackPrepared{Xid: 301, Gid: "g2"}})
+	if txn == nil || txn.RollbackPrepared.GetGid() != "g2" || txn.Prepare != nil || txn.NumChanges() != 0 || txn.CommitLSN != 5 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	txn = add(6, &CommitPrepared{&pg_pb3_ld.CommitPrepared{Xid: 300, Gid: "g1"}})
+	if txn == nil || txn.CommitPrepared.GetGid() != "g1" || txn.Commit != nil || txn.NumChanges() != 0 || txn.CommitLSN != 6 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+
+	// a streamed transaction which is prepared
+	add(7, &StreamStart{&pg_pb3_ld.StreamStart{Xid: 400, FirstSegment: true}})
+	add(8, testStreamedInsert("s", 400))
+	add(9, &StreamStop{&pg_pb3_ld.StreamStop{Xid: 400}})
+	txn = add(10, &StreamPrepare{&pg_pb3_ld.StreamPrepare{Xid: 400, Gid: "g3", PrepareLsn: 9}})
+	if txn == nil || txn.Prepare.GetXid() != 400 || txn.Prepare.GetGid() != "g3" || txn.Prepare.GetPrepareLsn() != 9 {
+		t.Fatalf("unexpected transaction %+v", txn)
+	}
+	if txn.NumChanges() != 1 {
+		t.Fatalf("NumChanges() %d; expected 1", txn.NumChanges())
+	}
+}
+
+func TestTransactionAssemblerStreamErrors(t *testing.T) {
+	tests := []struct{
+		messages []Message
+		expect_error string
+	}{
+		{
+			[]Message{
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+				&Commit{&pg_pb3_ld.CommitTransaction{}},
+			},
+			"unexpected CommitTransaction at 0/2 in a stream block",
+		},
+		{
+			[]Message{
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 200}},
+			},
+			"unexpected StreamStart at 0/2 in a stream block",
+		},
+		{
+			[]Message{
+				&StreamStop{&pg_pb3_ld.StreamStop{Xid: 100}},
+			},
+			"unexpected StreamStop at 0/1 outside of a stream block",
+		},
+		{
+			[]Message{
+				testInsert("a"),
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+			},
+			"unexpected StreamStart at 0/2 while a transaction is in progress",
+		},
+		{
+			[]Message{
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+				&StreamCommit{&pg_pb3_ld.StreamCommit{Xid: 100}},
+			},
+			"unexpected StreamCommit at 0/2 while a transaction is in progress",
+		},
+		{
+			[]Message{
+				&StreamStart{&pg_pb3_ld.StreamStart{Xid: 100}},
+				&Prepare{&pg_pb3_ld.PrepareTransaction{Gid: "g1"}},
+			},
+			"unexpected PrepareTransaction at 0/2 in a stream block",
+		},
+		{
+			[]Message{
+				testInsert("a"),
+				&CommitPrepared{&pg_pb3_ld.CommitPrepared{Gid: "g1"}},
+			},
+			"unexpected CommitPrepared at 0/2 while a transaction is in progress",
+		},
+	}
+
+	for i, test := range tests {
+		a := NewTransactionAssembler(TransactionAssemblerConfig{})
+		var err error
+		for j, msg := range test.messages {
+			_, err = a.Add(&StreamMessage{LSN: pglogrepl.LSN(j + 1), Message: msg})
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			t.Errorf("test %d succeeded unexpectedly", i)
+		} else if strings.Index(err.Error(), test.expect_error) == -1 {
+			t.Errorf("test %d failed with an unexpected error: %s (expected to contain %q)", i, err, test.expect_error)
+		}
+		a.Close()
+	}
+}
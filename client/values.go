@@ -0,0 +1,626 @@
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type oids of the built-in types DecodeValue knows about.
+const (
+	BoolOid = 16
+	ByteaOid = 17
+	CharOid = 18
+	NameOid = 19
+	Int8Oid = 20
+	Int2Oid = 21
+	Int4Oid = 23
+	TextOid = 25
+	OidOid = 26
+	JSONOid = 114
+	Float4Oid = 700
+	Float8Oid = 701
+	BPCharOid = 1042
+	VarcharOid = 1043
+	DateOid = 1082
+	TimestampOid = 1114
+	TimestamptzOid = 1184
+	NumericOid = 1700
+	UUIDOid = 2950
+	JSONBOid = 3802
+
+	JSONArrayOid = 199
+	BoolArrayOid = 1000
+	ByteaArrayOid = 1001
+	CharArrayOid = 1002
+	NameArrayOid = 1003
+	Int2ArrayOid = 1005
+	Int4ArrayOid = 1007
+	TextArrayOid = 1009
+	BPCharArrayOid = 1014
+	VarcharArrayOid = 1015
+	Int8ArrayOid = 1016
+	Float4ArrayOid = 1021
+	Float8ArrayOid = 1022
+	OidArrayOid = 1028
+	TimestampArrayOid = 1115
+	DateArrayOid = 1182
+	TimestamptzArrayOid = 1185
+	NumericArrayOid = 1231
+	UUIDArrayOid = 2951
+	JSONBArrayOid = 3807
+)
+
+// arrayElementTypes maps the oids of the supported array types to the oids of
+// their element types.
+var arrayElementTypes = map[uint32]uint32{
+	JSONArrayOid: JSONOid,
+	BoolArrayOid: BoolOid,
+	ByteaArrayOid: ByteaOid,
+	CharArrayOid: CharOid,
+	NameArrayOid: NameOid,
+	Int2ArrayOid: Int2Oid,
+	Int4ArrayOid: Int4Oid,
+	TextArrayOid: TextOid,
+	BPCharArrayOid: BPCharOid,
+	VarcharArrayOid: VarcharOid,
+	Int8ArrayOid: Int8Oid,
+	Float4ArrayOid: Float4Oid,
+	Float8ArrayOid: Float8Oid,
+	OidArrayOid: OidOid,
+	TimestampArrayOid: TimestampOid,
+	DateArrayOid: DateOid,
+	TimestamptzArrayOid: TimestamptzOid,
+	NumericArrayOid: NumericOid,
+	UUIDArrayOid: UUIDOid,
+	JSONBArrayOid: JSONBOid,
+}
+
+// Numeric is the exact decimal representation of a numeric value, e.g.
+// "-12.340", "NaN" or "Infinity".
+type Numeric string
+
+// UUID is a uuid value.
+type UUID [16]byte
+
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// InfiniteTime is used for the special values "infinity" and "-infinity" of
+// the date and timestamp types, which can't be represented by time.Time.
+type InfiniteTime int
+
+const (
+	NegativeInfinity InfiniteTime = -1
+	PositiveInfinity InfiniteTime = 1
+)
+
+func (i InfiniteTime) String() string {
+	if i < 0 {
+		return "-infinity"
+	}
+	return "infinity"
+}
+
+// microseconds and days between 1970-01-01 and 2000-01-01
+const postgresEpochMicros = 946684800 * 1000000
+const postgresEpochDays = 10957
+
+// DecodeValue decodes a single non-NULL value of the type identified by
+// typeOid.  If binary is true, data is expected to be in the binary send
+// format of the type, and in the text output format otherwise.  The Go types
+// used are:
+//
+//   bool                          bool
+//   int2, int4, int8              int16, int32, int64
+//   oid                           uint32
+//   float4, float8                float32, float64
+//   text, varchar, bpchar, name   string
+//   "char"                        string
+//   bytea                         []byte
+//   json, jsonb                   json.RawMessage
+//   numeric                       Numeric
+//   date, timestamp, timestamptz  time.Time, or InfiniteTime
+//   uuid                          UUID
+//   arrays of the above           []interface{}, nested for multiple dimensions
+//
+// Values of other types, including all types when typeOid is zero, are
+// returned as a string if they're in text format, or as []byte if they're in
+// binary.  Text format dates and timestamps are expected to use the ISO
+// DateStyle.
+func DecodeValue(typeOid uint32, binary bool, data []byte) (interface{}, error) {
+	if elemOid, ok := arrayElementTypes[typeOid]; ok {
+		if binary {
+			return decodeBinaryArray(elemOid, data)
+		}
+		return decodeTextArray(elemOid, string(data))
+	}
+
+	if binary {
+		return decodeBinaryValue(typeOid, data)
+	}
+	return decodeTextValue(typeOid, string(data))
+}
+
+func decodeTextValue(typeOid uint32, s string) (interface{}, error) {
+	switch typeOid {
+		case BoolOid:
+			switch s {
+				case "t":
+					return true, nil
+				case "f":
+					return false, nil
+				default:
+					return nil, fmt.Errorf("invalid boolean %q", s)
+			}
+		case Int2Oid:
+			v, err := strconv.ParseInt(s, 10, 16)
+			return int16(v), err
+		case Int4Oid:
+			v, err := strconv.ParseInt(s, 10, 32)
+			return int32(v), err
+		case Int8Oid:
+			return strconv.ParseInt(s, 10, 64)
+		case OidOid:
+			v, err := strconv.ParseUint(s, 10, 32)
+			return uint32(v), err
+		case Float4Oid:
+			v, err := strconv.ParseFloat(s, 32)
+			return float32(v), err
+		case Float8Oid:
+			return strconv.ParseFloat(s, 64)
+		case TextOid, VarcharOid, BPCharOid, NameOid, CharOid:
+			return s, nil
+		case ByteaOid:
+			return decodeTextBytea(s)
+		case JSONOid, JSONBOid:
+			return json.RawMessage(s), nil
+		case NumericOid:
+			return Numeric(s), nil
+		case DateOid:
+			return decodeTextTime(s, "2006-01-02")
+		case TimestampOid:
+			return decodeTextTime(s, "2006-01-02 15:04:05.999999")
+		case TimestamptzOid:
+			return decodeTextTime(s, "2006-01-02 15:04:05.999999-07")
+		case UUIDOid:
+			var u UUID
+			h := strings.ReplaceAll(s, "-", "")
+			if len(h) != 32 {
+				return nil, fmt.Errorf("invalid uuid %q", s)
+			}
+			_, err := hex.Decode(u[:], []byte(h))
+			if err != nil {
+				return nil, err
+			}
+			return u, nil
+		default:
+			return s, nil
+	}
+}
+
+func decodeTextBytea(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "\\x") {
+		return hex.DecodeString(s[2:])
+	}
+
+	// bytea_output = 'escape'
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			buf = append(buf, s[i])
+		} else if i + 1 < len(s) && s[i + 1] == '\\' {
+			buf = append(buf, '\\')
+			i++
+		} else if i + 3 < len(s) {
+			v, err := strconv.ParseUint(s[i + 1:i + 4], 8, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape sequence in bytea %q", s)
+			}
+			buf = append(buf, byte(v))
+			i += 3
+		} else {
+			return nil, fmt.Errorf("invalid escape sequence in bytea %q", s)
+		}
+	}
+	return buf, nil
+}
+
+func decodeTextTime(s string, layout string) (interface{}, error) {
+	switch s {
+		case "infinity":
+			return PositiveInfinity, nil
+		case "-infinity":
+			return NegativeInfinity, nil
+	}
+
+	bc := strings.HasSuffix(s, " BC")
+	if bc {
+		s = strings.TrimSuffix(s, " BC")
+	}
+	// The output function omits the minutes of the offset when they're zero,
+	// but includes them (and possibly seconds) otherwise.
+	if strings.HasSuffix(layout, "-07") && len(s) > 6 {
+		if s[len(s) - 6] == '+' || s[len(s) - 6] == '-' {
+			layout += ":00"
+		} else if len(s) > 9 && (s[len(s) - 9] == '+' || s[len(s) - 9] == '-') {
+			layout += ":00:00"
+		}
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return nil, err
+	}
+	if bc {
+		// year 1 BC is year 0 in ISO 8601
+		t = t.AddDate(1 - 2 * t.Year(), 0, 0)
+	}
+	return t, nil
+}
+
+func decodeBinaryValue(typeOid uint32, data []byte) (interface{}, error) {
+	checkLen := func(expected int) error {
+		if len(data) != expected {
+			return fmt.Errorf("invalid binary value of length %d for type %d; expected %d", len(data), typeOid, expected)
+		}
+		return nil
+	}
+
+	switch typeOid {
+		case BoolOid:
+			if err := checkLen(1); err != nil {
+				return nil, err
+			}
+			return data[0] != 0, nil
+		case Int2Oid:
+			if err := checkLen(2); err != nil {
+				return nil, err
+			}
+			return int16(binary.BigEndian.Uint16(data)), nil
+		case Int4Oid:
+			if err := checkLen(4); err != nil {
+				return nil, err
+			}
+			return int32(binary.BigEndian.Uint32(data)), nil
+		case Int8Oid:
+			if err := checkLen(8); err != nil {
+				return nil, err
+			}
+			return int64(binary.BigEndian.Uint64(data)), nil
+		case OidOid:
+			if err := checkLen(4); err != nil {
+				return nil, err
+			}
+			return binary.BigEndian.Uint32(data), nil
+		case Float4Oid:
+			if err := checkLen(4); err != nil {
+				return nil, err
+			}
+			return math.Float32frombits(binary.BigEndian.Uint32(data)), nil
+		case Float8Oid:
+			if err := checkLen(8); err != nil {
+				return nil, err
+			}
+			return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+		case TextOid, VarcharOid, BPCharOid, NameOid, CharOid:
+			return string(data), nil
+		case ByteaOid:
+			return data, nil
+		case JSONOid:
+			return json.RawMessage(data), nil
+		case JSONBOid:
+			if len(data) == 0 || data[0] != 1 {
+				return nil, fmt.Errorf("unsupported jsonb binary format version")
+			}
+			return json.RawMessage(data[1:]), nil
+		case NumericOid:
+			return decodeBinaryNumeric(data)
+		case DateOid:
+			if err := checkLen(4); err != nil {
+				return nil, err
+			}
+			days := int32(binary.BigEndian.Uint32(data))
+			switch days {
+				case math.MaxInt32:
+					return PositiveInfinity, nil
+				case math.MinInt32:
+					return NegativeInfinity, nil
+			}
+			return time.Unix((int64(days) + postgresEpochDays) * 86400, 0).UTC(), nil
+		case TimestampOid, TimestamptzOid:
+			if err := checkLen(8); err != nil {
+				return nil, err
+			}
+			micros := int64(binary.BigEndian.Uint64(data))
+			switch micros {
+				case math.MaxInt64:
+					return PositiveInfinity, nil
+				case math.MinInt64:
+					return NegativeInfinity, nil
+			}
+			micros += postgresEpochMicros
+			return time.Unix(micros / 1000000, (micros % 1000000) * 1000).UTC(), nil
+		case UUIDOid:
+			var u UUID
+			if err := checkLen(16); err != nil {
+				return nil, err
+			}
+			copy(u[:], data)
+			return u, nil
+		default:
+			return data, nil
+	}
+}
+
+func decodeBinaryNumeric(data []byte) (interface{}, error) {
+	const (
+		numericPos = 0x0000
+		numericNeg = 0x4000
+		numericNaN = 0xC000
+		numericPInf = 0xD000
+		numericNInf = 0xF000
+	)
+
+	if len(data) < 8 {
+		return nil, fmt.Errorf("invalid binary numeric of length %d", len(data))
+	}
+	ndigits := int(binary.BigEndian.Uint16(data[0:]))
+	weight := int(int16(binary.BigEndian.Uint16(data[2:])))
+	sign := binary.BigEndian.Uint16(data[4:])
+	dscale := int(binary.BigEndian.Uint16(data[6:]))
+	if len(data) != 8 + 2 * ndigits {
+		return nil, fmt.Errorf("invalid binary numeric of length %d with %d digits", len(data), ndigits)
+	}
+
+	switch sign {
+		case numericNaN:
+			return Numeric("NaN"), nil
+		case numericPInf:
+			return Numeric("Infinity"), nil
+		case numericNInf:
+			return Numeric("-Infinity"), nil
+		case numericPos, numericNeg:
+		default:
+			return nil, fmt.Errorf("invalid numeric sign 0x%04X", sign)
+	}
+
+	digits := make([]int, ndigits)
+	for i := range digits {
+		digits[i] = int(binary.BigEndian.Uint16(data[8 + 2 * i:]))
+		if digits[i] >= 10000 {
+			return nil, fmt.Errorf("invalid numeric digit %d", digits[i])
+		}
+	}
+	digit := func(i int) int {
+		if i >= 0 && i < len(digits) {
+			return digits[i]
+		}
+		return 0
+	}
+
+	// Same as get_str_from_var(): digit i has the weight (weight - i) in base
+	// 10000.
+	var sb strings.Builder
+	if sign == numericNeg {
+		sb.WriteByte('-')
+	}
+	if weight < 0 {
+		sb.WriteByte('0')
+	} else {
+		for i := 0; i <= weight; i++ {
+			if i == 0 {
+				sb.WriteString(strconv.Itoa(digit(i)))
+			} else {
+				fmt.Fprintf(&sb, "%04d", digit(i))
+			}
+		}
+	}
+	if dscale > 0 {
+		var frac bytes.Buffer
+		for i := weight + 1; frac.Len() < dscale; i++ {
+			fmt.Fprintf(&frac, "%04d", digit(i))
+		}
+		sb.WriteByte('.')
+		sb.Write(frac.Bytes()[:dscale])
+	}
+	return Numeric(sb.String()), nil
+}
+
+func decodeBinaryArray(elemOid uint32, data []byte) (interface{}, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("invalid binary array of length %d", len(data))
+	}
+	ndim := int(int32(binary.BigEndian.Uint32(data[0:])))
+	// data[4:8] is the "has nulls" flag, which we don't need
+	dataElemOid := binary.BigEndian.Uint32(data[8:])
+	data = data[12:]
+	if ndim == 0 {
+		return []interface{}{}, nil
+	}
+	if ndim < 0 || ndim > 6 {
+		return nil, fmt.Errorf("invalid number of array dimensions %d", ndim)
+	}
+	if dataElemOid != elemOid {
+		return nil, fmt.Errorf("unexpected array element type %d; expected %d", dataElemOid, elemOid)
+	}
+	if len(data) < 8 * ndim {
+		return nil, fmt.Errorf("truncated binary array")
+	}
+	// Every element takes at least four bytes for its length, so a corrupt
+	// dimension is caught here instead of by trying to allocate it.  Since
+	// the product never exceeds maxElems, it can't overflow.
+	maxElems := (len(data) - 8 * ndim) / 4
+	nelems := 1
+	dims := make([]int, ndim)
+	for i := range dims {
+		dims[i] = int(int32(binary.BigEndian.Uint32(data[8 * i:])))
+		if dims[i] < 0 || dims[i] > maxElems {
+			return nil, fmt.Errorf("invalid array dimension %d", dims[i])
+		}
+		nelems *= dims[i]
+		if nelems > maxElems {
+			return nil, fmt.Errorf("truncated binary array")
+		}
+	}
+	data = data[8 * ndim:]
+
+	var decodeDimension func(dim int) ([]interface{}, error)
+	decodeDimension = func(dim int) ([]interface{}, error) {
+		elems := make([]interface{}, dims[dim])
+		for i := range elems {
+			var err error
+
+			if dim + 1 < ndim {
+				elems[i], err = decodeDimension(dim + 1)
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if len(data) < 4 {
+				return nil, fmt.Errorf("truncated binary array")
+			}
+			elemLen := int(int32(binary.BigEndian.Uint32(data)))
+			data = data[4:]
+			if elemLen == -1 {
+				elems[i] = nil
+				continue
+			}
+			if elemLen < 0 || elemLen > len(data) {
+				return nil, fmt.Errorf("truncated binary array")
+			}
+			elems[i], err = decodeBinaryValue(elemOid, data[:elemLen])
+			if err != nil {
+				return nil, err
+			}
+			data = data[elemLen:]
+		}
+		return elems, nil
+	}
+	elems, err := decodeDimension(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		return nil, fmt.Errorf("trailing data after binary array")
+	}
+	return elems, nil
+}
+
+func decodeTextArray(elemOid uint32, s string) (interface{}, error) {
+	// skip the dimension decoration, e.g. "[0:1]={1,2}"
+	if strings.HasPrefix(s, "[") {
+		eq := strings.Index(s, "=")
+		if eq == -1 {
+			return nil, fmt.Errorf("invalid array %q", s)
+		}
+		s = s[eq + 1:]
+	}
+
+	p := &textArrayParser{input: s, elemOid: elemOid}
+	elems, err := p.parseDimension()
+	if err != nil {
+		return nil, fmt.Errorf("invalid array %q: %w", s, err)
+	}
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("invalid array %q: trailing data", s)
+	}
+	return elems, nil
+}
+
+type textArrayParser struct {
+	input string
+	pos int
+	elemOid uint32
+}
+
+func (p *textArrayParser) parseDimension() ([]interface{}, error) {
+	if p.pos >= len(p.input) || p.input[p.pos] != '{' {
+		return nil, fmt.Errorf("expected '{' at position %d", p.pos)
+	}
+	p.pos++
+
+	elems := []interface{}{}
+	if p.pos < len(p.input) && p.input[p.pos] == '}' {
+		p.pos++
+		return elems, nil
+	}
+
+	for {
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unexpected end of input")
+		}
+
+		var elem interface{}
+		var err error
+		if p.input[p.pos] == '{' {
+			elem, err = p.parseDimension()
+		} else {
+			elem, err = p.parseElement()
+		}
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unexpected end of input")
+		}
+		switch p.input[p.pos] {
+			case ',':
+				p.pos++
+			case '}':
+				p.pos++
+				return elems, nil
+			default:
+				return nil, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+		}
+	}
+}
+
+func (p *textArrayParser) parseElement() (interface{}, error) {
+	var sb strings.Builder
+
+	quoted := p.input[p.pos] == '"'
+	if quoted {
+		p.pos++
+		for {
+			if p.pos >= len(p.input) {
+				return nil, fmt.Errorf("unterminated quoted element")
+			}
+			c := p.input[p.pos]
+			p.pos++
+			if c == '"' {
+				break
+			} else if c == '\\' {
+				if p.pos >= len(p.input) {
+					return nil, fmt.Errorf("unterminated quoted element")
+				}
+				c = p.input[p.pos]
+				p.pos++
+			}
+			sb.WriteByte(c)
+		}
+	} else {
+		for p.pos < len(p.input) && p.input[p.pos] != ',' && p.input[p.pos] != '}' {
+			sb.WriteByte(p.input[p.pos])
+			p.pos++
+		}
+		if sb.Len() == 0 {
+			return nil, fmt.Errorf("empty element at position %d", p.pos)
+		}
+		if sb.String() == "NULL" {
+			return nil, nil
+		}
+	}
+	return decodeTextValue(p.elemOid, sb.String())
+}
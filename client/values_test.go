@@ -0,0 +1,206 @@
+package client
+
+import (
+	"encoding/json"
+	"github.com/johto/pg_pb3_ld"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeValue(t *testing.T) {
+	ts := time.Date(2021, 7, 4, 12, 30, 45, 123456000, time.UTC)
+	tz := time.FixedZone("", 5 * 3600 + 30 * 60)
+
+	tests := []struct{
+		typeOid uint32
+		binary bool
+		data string
+		expected interface{}
+	}{
+		{BoolOid, false, "t", true},
+		{BoolOid, true, "\x00", false},
+		{Int2Oid, false, "-2", int16(-2)},
+		{Int2Oid, true, "\xff\xfe", int16(-2)},
+		{Int4Oid, false, "1", int32(1)},
+		{Int4Oid, true, "\x00\x00\x00\x01", int32(1)},
+		{Int8Oid, false, "-9223372036854775808", int64(-9223372036854775808)},
+		{Int8Oid, true, "\x00\x00\x00\x00\x00\x00\x01\x00", int64(256)},
+		{Float4Oid, false, "1.5", float32(1.5)},
+		{Float4Oid, true, "\x3f\xc0\x00\x00", float32(1.5)},
+		{Float8Oid, false, "-Infinity", math.Inf(-1)},
+		{Float8Oid, true, "\x3f\xf8\x00\x00\x00\x00\x00\x00", float64(1.5)},
+		{TextOid, false, "foo", "foo"},
+		{VarcharOid, true, "foo", "foo"},
+		{ByteaOid, false, "\\xdead", []byte{0xde, 0xad}},
+		{ByteaOid, false, "a\\\\b\\001", []byte{'a', '\\', 'b', 1}},
+		{ByteaOid, true, "\xde\xad", []byte{0xde, 0xad}},
+		{JSONOid, false, `{"a": 1}`, json.RawMessage(`{"a": 1}`)},
+		{JSONBOid, true, "\x01{\"a\": 1}", json.RawMessage(`{"a": 1}`)},
+		{NumericOid, false, "-12.340", Numeric("-12.340")},
+		// -12.340: ndigits 2, weight 0, sign NEG, dscale 3, digits 12, 3400
+		{NumericOid, true, "\x00\x02\x00\x00\x40\x00\x00\x03\x00\x0c\x0d\x48", Numeric("-12.340")},
+		// 0.0012: ndigits 1, weight -1, dscale 4, digits 12
+		{NumericOid, true, "\x00\x01\xff\xff\x00\x00\x00\x04\x00\x0c", Numeric("0.0012")},
+		// 100000000: ndigits 1, weight 2, dscale 0, digits 1
+		{NumericOid, true, "\x00\x01\x00\x02\x00\x00\x00\x00\x00\x01", Numeric("100000000")},
+		{NumericOid, true, "\x00\x00\x00\x00\xc0\x00\x00\x00", Numeric("NaN")},
+		{DateOid, false, "2021-07-04", time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{DateOid, true, "\x00\x00\x1e\xaf", time.Date(2021, 7, 4, 0, 0, 0, 0, time.UTC)},
+		{DateOid, false, "infinity", PositiveInfinity},
+		{DateOid, false, "0044-03-15 BC", time.Date(-43, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{TimestampOid, false, "2021-07-04 12:30:45.123456", ts},
+		{TimestampOid, true, "\x00\x02\x69\x4a\x20\x60\xa9\x80", ts},
+		{TimestampOid, true, "\x80\x00\x00\x00\x00\x00\x00\x00", NegativeInfinity},
+		{TimestamptzOid, false, "2021-07-04 12:30:45.123456+00", ts},
+		{TimestamptzOid, false, "2021-07-04 18:00:45.123456+05:30", ts.In(tz)},
+		{UUIDOid, false, "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11", UUID{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11}},
+		{0, false, "1", "1"},
+		{0, true, "\x01", []byte{1}},
+		{Int4ArrayOid, false, "{1,NULL,3}", []interface{}{int32(1), nil, int32(3)}},
+		{TextArrayOid, false, `{"a b","c\"d",NULL,"NULL"}`, []interface{}{"a b", `c"d`, nil, "NULL"}},
+		{Int4ArrayOid, false, "[0:1]={{1,2},{3,4}}", []interface{}{[]interface{}{int32(1), int32(2)}, []interface{}{int32(3), int32(4)}}},
+		{Int4ArrayOid, false, "{}", []interface{}{}},
+		// one dimension of two elements: 7 and NULL
+		{Int4ArrayOid, true,
+			"\x00\x00\x00\x01\x00\x00\x00\x01\x00\x00\x00\x17" +
+			"\x00\x00\x00\x02\x00\x00\x00\x01" +
+			"\x00\x00\x00\x04\x00\x00\x00\x07" +
+			"\xff\xff\xff\xff",
+			[]interface{}{int32(7), nil}},
+	}
+
+	for _, test := range tests {
+		value, err := DecodeValue(test.typeOid, test.binary, []byte(test.data))
+		if err != nil {
+			t.Errorf("decoding %q (type %d, binary %v) failed: %s", test.data, test.typeOid, test.binary, err)
+			continue
+		}
+		equal := reflect.DeepEqual(value, test.expected)
+		if tm, ok := test.expected.(time.Time); ok {
+			vtm, ok := value.(time.Time)
+			equal = ok && tm.Equal(vtm)
+		}
+		if !equal {
+			t.Errorf("decoding %q (type %d, binary %v) returned %#v; expected %#v", test.data, test.typeOid, test.binary, value, test.expected)
+		}
+	}
+}
+
+func TestDecodeValueErrors(t *testing.T) {
+	tests := []struct{
+		typeOid uint32
+		data string
+		expect_error string
+	}{
+		// a dimension of 2^31-1 elements with no data for them
+		{Int4ArrayOid,
+			"\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x17" +
+			"\x7f\xff\xff\xff\x00\x00\x00\x01",
+			"invalid array dimension 2147483647"},
+		// two dimensions whose product is too large for the data
+		{Int4ArrayOid,
+			"\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x17" +
+			"\x00\x00\x00\x02\x00\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x01" +
+			"\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff",
+			"truncated binary array"},
+	}
+
+	for _, test := range tests {
+		_, err := DecodeValue(test.typeOid, true, []byte(test.data))
+		if err == nil {
+			t.Errorf("decoding %q (type %d) succeeded unexpectedly", test.data, test.typeOid)
+		} else if err.Error() != test.expect_error {
+			t.Errorf("decoding %q (type %d) failed with %q; expected %q", test.data, test.typeOid, err, test.expect_error)
+		}
+	}
+}
+
+func TestFieldSetColumns(t *testing.T) {
+	// type_oids_mode omit_nulls, formats_mode full
+	fs := FieldSet{&pg_pb3_ld.FieldSetDescription{
+		Names: []string{"f1", "f2", "f3"},
+		Values: [][]byte{[]byte("\x00\x00\x00\x01"), []byte{}, []byte("foo")},
+		TypeOids: []uint32{Int4Oid, TextOid},
+		Nulls: []byte{0, 1, 0},
+		Formats: []byte{1, 0, 0},
+	}}
+	columns, err := fs.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Column{
+		{Name: "f1", TypeOid: Int4Oid, Binary: true, Data: []byte("\x00\x00\x00\x01"), Value: int32(1)},
+		{Name: "f2", Null: true, Data: []byte{}},
+		{Name: "f3", TypeOid: TextOid, Data: []byte("foo"), Value: "foo"},
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Fatalf("got %+v; expected %+v", columns, expected)
+	}
+
+	// type_oids_mode full, formats_mode omit_nulls
+	fs.TypeOids = []uint32{Int4Oid, TextOid, TextOid}
+	fs.Formats = []byte{1, 0}
+	columns, err = fs.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected[1].TypeOid = TextOid
+	if !reflect.DeepEqual(columns, expected) {
+		t.Fatalf("got %+v; expected %+v", columns, expected)
+	}
+
+	fs.Formats = []byte{1}
+	_, err = fs.Columns()
+	if err == nil {
+		t.Fatalf("unexpected success with a misaligned formats array")
+	}
+}
+
+func TestFieldSetMerge(t *testing.T) {
+	// "f2" is an unchanged TOASTed value
+	fs := FieldSet{&pg_pb3_ld.FieldSetDescription{
+		Names: []string{"f1", "f2", "f3"},
+		Values: [][]byte{[]byte("2"), []byte{}, []byte{}},
+		TypeOids: []uint32{Int4Oid, TextOid, TextOid},
+		Nulls: []byte{0, 0, 1},
+		UnchangedToast: []byte{0, 1, 0},
+	}}
+	columns, err := fs.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !columns[1].UnchangedToast || columns[1].Null || columns[1].Value != nil {
+		t.Fatalf("unexpected column %+v", columns[1])
+	}
+
+	previous := []Column{
+		{Name: "f1", TypeOid: Int4Oid, Data: []byte("1"), Value: int32(1)},
+		{Name: "f2", TypeOid: TextOid, Data: []byte("long"), Value: "long"},
+		{Name: "f3", TypeOid: TextOid, Data: []byte("foo"), Value: "foo"},
+	}
+	merged, err := fs.Merge(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Column{
+		{Name: "f1", TypeOid: Int4Oid, Data: []byte("2"), Value: int32(2)},
+		{Name: "f2", TypeOid: TextOid, Data: []byte("long"), Value: "long"},
+		{Name: "f3", TypeOid: TextOid, Null: true, Data: []byte{}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("got %+v; expected %+v", merged, expected)
+	}
+
+	_, err = fs.Merge(previous[:1])
+	if err == nil {
+		t.Fatalf("unexpected success without a previous value")
+	}
+
+	fs.UnchangedToast = []byte{0, 1}
+	_, err = fs.Columns()
+	if err == nil {
+		t.Fatalf("unexpected success with a misaligned unchanged_toast bitmap")
+	}
+}
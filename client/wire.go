@@ -0,0 +1,401 @@
+// Package client decodes the wire messages produced by the pg_pb3_ld logical
+// decoding output plugin.
+package client
+
+import (
+	"encoding/binary"
+	"fmt"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+)
+
+// Message is a single message decoded from a wire message.  The concrete type
+// is one of *Begin, *Commit, *Insert, *Update, *Delete, *Truncate,
+// *LogicalMessage, *Relation, *StreamStart, *StreamStop, *StreamCommit,
+// *StreamAbort, *Prepare, *CommitPrepared, *RollbackPrepared, *StreamPrepare,
+// *Sequence or *SessionStart.  Since all of them embed the generated protobuf type, they
+// can be passed to proto.Equal, proto.MarshalTextString etc. directly.
+type Message interface {
+	proto.Message
+	Type() pg_pb3_ld.WireMessageType
+}
+
+// SessionStart is the first message of a session if protocol version 2 or
+// later was negotiated via the proto_version option.
+type SessionStart struct {
+	*pg_pb3_ld.SessionStart
+}
+
+func (*SessionStart) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_SESSION_START
+}
+
+type Begin struct {
+	*pg_pb3_ld.BeginTransaction
+}
+
+func (*Begin) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_BEGIN
+}
+
+type Commit struct {
+	*pg_pb3_ld.CommitTransaction
+}
+
+func (*Commit) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_COMMIT
+}
+
+type Insert struct {
+	*pg_pb3_ld.InsertDescription
+}
+
+func (*Insert) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_INSERT
+}
+
+type Update struct {
+	*pg_pb3_ld.UpdateDescription
+}
+
+func (*Update) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_UPDATE
+}
+
+type Delete struct {
+	*pg_pb3_ld.DeleteDescription
+}
+
+func (*Delete) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_DELETE
+}
+
+type Truncate struct {
+	*pg_pb3_ld.TruncateDescription
+}
+
+func (*Truncate) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_TRUNCATE
+}
+
+type LogicalMessage struct {
+	*pg_pb3_ld.LogicalMessage
+}
+
+func (*LogicalMessage) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_MESSAGE
+}
+
+// StreamStart and StreamStop delimit a block of changes of a transaction
+// which is still in progress.  They are only sent if the streaming option is
+// enabled; see TransactionAssembler.
+type StreamStart struct {
+	*pg_pb3_ld.StreamStart
+}
+
+func (*StreamStart) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_STREAM_START
+}
+
+type StreamStop struct {
+	*pg_pb3_ld.StreamStop
+}
+
+func (*StreamStop) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_STREAM_STOP
+}
+
+// StreamCommit is sent in place of a Commit for a transaction whose changes
+// were streamed.
+type StreamCommit struct {
+	*pg_pb3_ld.StreamCommit
+}
+
+func (*StreamCommit) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_STREAM_COMMIT
+}
+
+// StreamAbort is sent when a streamed transaction rolls back.  If Subxid is
+// different from Xid, only the subtransaction Subxid rolled back.
+type StreamAbort struct {
+	*pg_pb3_ld.StreamAbort
+}
+
+func (*StreamAbort) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_STREAM_ABORT
+}
+
+// Prepare is sent in place of a Commit for a transaction which was prepared
+// for two-phase commit.  It's only sent if the two_phase option is enabled;
+// see TransactionAssembler.
+type Prepare struct {
+	*pg_pb3_ld.PrepareTransaction
+}
+
+func (*Prepare) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_PREPARE
+}
+
+// CommitPrepared and RollbackPrepared are sent when a transaction which was
+// sent at the time of its Prepare commits or rolls back.  The transaction is
+// identified by Gid.
+type CommitPrepared struct {
+	*pg_pb3_ld.CommitPrepared
+}
+
+func (*CommitPrepared) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_COMMIT_PREPARED
+}
+
+type RollbackPrepared struct {
+	*pg_pb3_ld.RollbackPrepared
+}
+
+func (*RollbackPrepared) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_ROLLBACK_PREPARED
+}
+
+// StreamPrepare is sent in place of a StreamCommit for a streamed transaction
+// which was prepared for two-phase commit.
+type StreamPrepare struct {
+	*pg_pb3_ld.StreamPrepare
+}
+
+func (*StreamPrepare) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_STREAM_PREPARE
+}
+
+// Sequence carries the current value of a sequence owned by a table changed
+// in the transaction.  It's only sent if the enable_sequence_messages option
+// is enabled, and is treated as a change of the transaction.
+type Sequence struct {
+	*pg_pb3_ld.SequenceDescription
+}
+
+func (*Sequence) Type() pg_pb3_ld.WireMessageType {
+	return pg_pb3_ld.WireMessageType_WMSG_SEQUENCE
+}
+
+// TruncatedHeaderError is returned when a wire message is too short to
+// contain the WireMessageHeader it claims to have.
+type TruncatedHeaderError struct {
+	HeaderLen uint64
+	DataLen int
+}
+
+func (e *TruncatedHeaderError) Error() string {
+	if e.HeaderLen == 0 {
+		return fmt.Sprintf("could not parse wire message header length from %d bytes of data", e.DataLen)
+	}
+	return fmt.Sprintf("wire message header length %d exceeds the length of the data %d", e.HeaderLen, e.DataLen)
+}
+
+// MalformedHeaderError is returned when the WireMessageHeader could not be
+// unmarshaled, or when its types and offsets don't match up.
+type MalformedHeaderError struct {
+	Err error
+}
+
+func (e *MalformedHeaderError) Error() string {
+	return fmt.Sprintf("malformed wire message header: %s", e.Err)
+}
+
+func (e *MalformedHeaderError) Unwrap() error {
+	return e.Err
+}
+
+// OffsetOverflowError is returned when the offset of a message points outside
+// of the wire message body, or precedes the offset of the previous message.
+type OffsetOverflowError struct {
+	Index int
+	Offset int32
+	PreviousOffset int32
+	BodyLen int
+}
+
+func (e *OffsetOverflowError) Error() string {
+	if e.Offset < e.PreviousOffset {
+		return fmt.Sprintf("offset %d of message %d precedes the offset %d of the previous message", e.Offset, e.Index, e.PreviousOffset)
+	}
+	return fmt.Sprintf("offset %d of message %d exceeds the length of the wire message body %d", e.Offset, e.Index, e.BodyLen)
+}
+
+// UnknownMessageTypeError is returned when the WireMessageHeader contains a
+// message type this package doesn't know how to decode.
+type UnknownMessageTypeError struct {
+	Index int
+	Type pg_pb3_ld.WireMessageType
+}
+
+func (e *UnknownMessageTypeError) Error() string {
+	return fmt.Sprintf("unknown wire message type %d for message %d", int32(e.Type), e.Index)
+}
+
+// UnmarshalError is returned when the body of a single message could not be
+// unmarshaled.
+type UnmarshalError struct {
+	Index int
+	Type pg_pb3_ld.WireMessageType
+	Err error
+}
+
+func (e *UnmarshalError) Error() string {
+	return fmt.Sprintf("could not unmarshal message %d of type %s: %s", e.Index, e.Type, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeWireMessage decodes a single wire message, i.e. the contents of a
+// single XLogData message or a single row returned by
+// pg_logical_slot_get_binary_changes(), into the messages contained in it.
+// Messages of unknown types are an error.
+func DecodeWireMessage(data []byte) ([]Message, error) {
+	return decodeWireMessage(data, true)
+}
+
+// decodeWireMessage decodes a single wire message.  Unless strict is set,
+// messages of unknown types are skipped.
+func decodeWireMessage(data []byte, strict bool) ([]Message, error) {
+	header, body, err := decodeWireMessageHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]Message, 0, len(header.Types))
+	for i, typ := range header.Types {
+		msgData, err := wireMessageBody(header, body, i)
+		if err != nil {
+			return nil, err
+		}
+
+		msg := newMessage(typ)
+		if msg == nil && !strict {
+			continue
+		} else if msg == nil {
+			return nil, &UnknownMessageTypeError{
+				Index: i,
+				Type: typ,
+			}
+		}
+		err = proto.Unmarshal(msgData, msg)
+		if err != nil {
+			return nil, &UnmarshalError{
+				Index: i,
+				Type: typ,
+				Err: err,
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// newMessage returns an empty Message of the given type, or nil if the type is
+// not known.
+func newMessage(typ pg_pb3_ld.WireMessageType) Message {
+	switch typ {
+		case pg_pb3_ld.WireMessageType_WMSG_SESSION_START:
+			return &SessionStart{&pg_pb3_ld.SessionStart{}}
+		case pg_pb3_ld.WireMessageType_WMSG_BEGIN:
+			return &Begin{&pg_pb3_ld.BeginTransaction{}}
+		case pg_pb3_ld.WireMessageType_WMSG_COMMIT:
+			return &Commit{&pg_pb3_ld.CommitTransaction{}}
+		case pg_pb3_ld.WireMessageType_WMSG_INSERT:
+			return &Insert{&pg_pb3_ld.InsertDescription{}}
+		case pg_pb3_ld.WireMessageType_WMSG_UPDATE:
+			return &Update{&pg_pb3_ld.UpdateDescription{}}
+		case pg_pb3_ld.WireMessageType_WMSG_DELETE:
+			return &Delete{&pg_pb3_ld.DeleteDescription{}}
+		case pg_pb3_ld.WireMessageType_WMSG_TRUNCATE:
+			return &Truncate{&pg_pb3_ld.TruncateDescription{}}
+		case pg_pb3_ld.WireMessageType_WMSG_MESSAGE:
+			return &LogicalMessage{&pg_pb3_ld.LogicalMessage{}}
+		case pg_pb3_ld.WireMessageType_WMSG_RELATION:
+			return &Relation{&pg_pb3_ld.RelationDescription{}}
+		case pg_pb3_ld.WireMessageType_WMSG_STREAM_START:
+			return &StreamStart{&pg_pb3_ld.StreamStart{}}
+		case pg_pb3_ld.WireMessageType_WMSG_STREAM_STOP:
+			return &StreamStop{&pg_pb3_ld.StreamStop{}}
+		case pg_pb3_ld.WireMessageType_WMSG_STREAM_COMMIT:
+			return &StreamCommit{&pg_pb3_ld.StreamCommit{}}
+		case pg_pb3_ld.WireMessageType_WMSG_STREAM_ABORT:
+			return &StreamAbort{&pg_pb3_ld.StreamAbort{}}
+		case pg_pb3_ld.WireMessageType_WMSG_PREPARE:
+			return &Prepare{&pg_pb3_ld.PrepareTransaction{}}
+		case pg_pb3_ld.WireMessageType_WMSG_COMMIT_PREPARED:
+			return &CommitPrepared{&pg_pb3_ld.CommitPrepared{}}
+		case pg_pb3_ld.WireMessageType_WMSG_ROLLBACK_PREPARED:
+			return &RollbackPrepared{&pg_pb3_ld.RollbackPrepared{}}
+		case pg_pb3_ld.WireMessageType_WMSG_STREAM_PREPARE:
+			return &StreamPrepare{&pg_pb3_ld.StreamPrepare{}}
+		case pg_pb3_ld.WireMessageType_WMSG_SEQUENCE:
+			return &Sequence{&pg_pb3_ld.SequenceDescription{}}
+		default:
+			return nil
+	}
+}
+
+func decodeWireMessageHeader(data []byte) (*pg_pb3_ld.WireMessageHeader, []byte, error) {
+	headerLen, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, nil, &TruncatedHeaderError{
+			HeaderLen: 0,
+			DataLen: len(data),
+		}
+	}
+	data = data[n:]
+	if headerLen > uint64(len(data)) {
+		return nil, nil, &TruncatedHeaderError{
+			HeaderLen: headerLen,
+			DataLen: len(data),
+		}
+	}
+
+	header := &pg_pb3_ld.WireMessageHeader{}
+	err := proto.Unmarshal(data[:headerLen], header)
+	if err != nil {
+		return nil, nil, &MalformedHeaderError{Err: err}
+	}
+	if len(header.Types) != len(header.Offsets) {
+		return nil, nil, &MalformedHeaderError{
+			Err: fmt.Errorf("len(Types) %d != len(Offsets) %d", len(header.Types), len(header.Offsets)),
+		}
+	}
+	body, err := decompressBody(header, data[headerLen:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, body, nil
+}
+
+// wireMessageBody returns the part of body which contains message number i.
+func wireMessageBody(header *pg_pb3_ld.WireMessageHeader, body []byte, i int) ([]byte, error) {
+	offset := header.Offsets[i]
+	previousOffset := int32(0)
+	if i > 0 {
+		previousOffset = header.Offsets[i - 1]
+	}
+	if offset < previousOffset || offset > int32(len(body)) {
+		return nil, &OffsetOverflowError{
+			Index: i,
+			Offset: offset,
+			PreviousOffset: previousOffset,
+			BodyLen: len(body),
+		}
+	}
+	if i + 1 < len(header.Offsets) {
+		nextOffset := header.Offsets[i + 1]
+		if nextOffset < offset || nextOffset > int32(len(body)) {
+			return nil, &OffsetOverflowError{
+				Index: i + 1,
+				Offset: nextOffset,
+				PreviousOffset: offset,
+				BodyLen: len(body),
+			}
+		}
+		return body[offset:nextOffset], nil
+	}
+	return body[offset:], nil
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld"
+	"testing"
+)
+
+// buildWireMessage builds a wire message the same way the output plugin does.
+func buildWireMessage(t *testing.T, types []pg_pb3_ld.WireMessageType, messages []proto.Message) []byte {
+	var body []byte
+	header := &pg_pb3_ld.WireMessageHeader{}
+	for i, msg := range messages {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header.Types = append(header.Types, types[i])
+		header.Offsets = append(header.Offsets, int32(len(body)))
+		body = append(body, data...)
+	}
+	headerData, err := proto.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return appendFrame(headerData, body)
+}
+
+func appendFrame(headerData []byte, body []byte) []byte {
+	frame := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(frame, uint64(len(headerData)))
+	frame = append(frame[:n], headerData...)
+	return append(frame, body...)
+}
+
+func TestDecodeWireMessage(t *testing.T) {
+	ins := &pg_pb3_ld.InsertDescription{
+		Table: &pg_pb3_ld.TableDescription{
+			SchemaName: "public",
+			TableName: "tenk1",
+		},
+		NewValues: &pg_pb3_ld.FieldSetDescription{
+			Names: []string{"unique1", "unique2"},
+			Values: [][]byte{[]byte("1"), []byte{}},
+			Nulls: []byte{0, 1},
+		},
+	}
+	del := &pg_pb3_ld.DeleteDescription{
+		Table: ins.Table,
+		KeyFields: &pg_pb3_ld.FieldSetDescription{
+			Names: []string{"unique1"},
+			Values: [][]byte{[]byte("1")},
+			Nulls: []byte{0},
+		},
+	}
+	trunc := &pg_pb3_ld.TruncateDescription{
+		Tables: []*pg_pb3_ld.TableDescription{ins.Table},
+		Cascade: true,
+	}
+	expected := []proto.Message{
+		&pg_pb3_ld.BeginTransaction{},
+		ins,
+		del,
+		trunc,
+		&pg_pb3_ld.CommitTransaction{},
+	}
+	data := buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_BEGIN,
+		pg_pb3_ld.WireMessageType_WMSG_INSERT,
+		pg_pb3_ld.WireMessageType_WMSG_DELETE,
+		pg_pb3_ld.WireMessageType_WMSG_TRUNCATE,
+		pg_pb3_ld.WireMessageType_WMSG_COMMIT,
+	}, expected)
+
+	messages, err := DecodeWireMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != len(expected) {
+		t.Fatalf("got %d messages; expected %d", len(messages), len(expected))
+	}
+	for i, msg := range messages {
+		if !proto.Equal(msg, expected[i]) {
+			t.Errorf("message %d %s does not match %s", i, proto.MarshalTextString(msg), proto.MarshalTextString(expected[i]))
+		}
+	}
+	if _, ok := messages[1].(*Insert); !ok {
+		t.Errorf("unexpected type %T for message 1", messages[1])
+	}
+	if _, ok := messages[2].(*Delete); !ok {
+		t.Errorf("unexpected type %T for message 2", messages[2])
+	}
+	if _, ok := messages[3].(*Truncate); !ok {
+		t.Errorf("unexpected type %T for message 3", messages[3])
+	}
+}
+
+func TestDecodeWireMessageErrors(t *testing.T) {
+	valid := buildWireMessage(t, []pg_pb3_ld.WireMessageType{
+		pg_pb3_ld.WireMessageType_WMSG_BEGIN,
+		pg_pb3_ld.WireMessageType_WMSG_COMMIT,
+	}, []proto.Message{
+		&pg_pb3_ld.BeginTransaction{},
+		&pg_pb3_ld.CommitTransaction{},
+	})
+
+	headerFrame := func(header *pg_pb3_ld.WireMessageHeader, body []byte) []byte {
+		headerData, err := proto.Marshal(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return appendFrame(headerData, body)
+	}
+
+	tests := []struct{
+		name string
+		data []byte
+		check func(err error) bool
+	}{
+		{"empty", nil, func(err error) bool {
+			var e *TruncatedHeaderError
+			return errors.As(err, &e)
+		}},
+		{"unterminated varint", []byte{0x80, 0x80}, func(err error) bool {
+			var e *TruncatedHeaderError
+			return errors.As(err, &e)
+		}},
+		{"truncated header", valid[:3], func(err error) bool {
+			var e *TruncatedHeaderError
+			return errors.As(err, &e) && e.HeaderLen > 0
+		}},
+		{"types and offsets mismatch", headerFrame(&pg_pb3_ld.WireMessageHeader{
+			Types: []pg_pb3_ld.WireMessageType{pg_pb3_ld.WireMessageType_WMSG_BEGIN},
+		}, nil), func(err error) bool {
+			var e *MalformedHeaderError
+			return errors.As(err, &e)
+		}},
+		{"offset overflow", headerFrame(&pg_pb3_ld.WireMessageHeader{
+			Types: []pg_pb3_ld.WireMessageType{pg_pb3_ld.WireMessageType_WMSG_BEGIN},
+			Offsets: []int32{5},
+		}, []byte{}), func(err error) bool {
+			var e *OffsetOverflowError
+			return errors.As(err, &e) && e.Offset == 5
+		}},
+		{"decreasing offsets", headerFrame(&pg_pb3_ld.WireMessageHeader{
+			Types: []pg_pb3_ld.WireMessageType{
+				pg_pb3_ld.WireMessageType_WMSG_BEGIN,
+				pg_pb3_ld.WireMessageType_WMSG_COMMIT,
+			},
+			Offsets: []int32{2, 1},
+		}, []byte{0, 0}), func(err error) bool {
+			var e *OffsetOverflowError
+			return errors.As(err, &e) && e.Index == 1
+		}},
+		{"unknown type", headerFrame(&pg_pb3_ld.WireMessageHeader{
+			Types: []pg_pb3_ld.WireMessageType{pg_pb3_ld.WireMessageType(1000)},
+			Offsets: []int32{0},
+		}, nil), func(err error) bool {
+			var e *UnknownMessageTypeError
+			return errors.As(err, &e) && e.Type == 1000
+		}},
+		{"garbage body", headerFrame(&pg_pb3_ld.WireMessageHeader{
+			Types: []pg_pb3_ld.WireMessageType{pg_pb3_ld.WireMessageType_WMSG_INSERT},
+			Offsets: []int32{0},
+		}, []byte{0xFF}), func(err error) bool {
+			var e *UnmarshalError
+			return errors.As(err, &e)
+		}},
+	}
+
+	for _, test := range tests {
+		_, err := DecodeWireMessage(test.data)
+		if err == nil {
+			t.Errorf("test %q succeeded unexpectedly", test.name)
+			continue
+		}
+		if !test.check(err) {
+			t.Errorf("test %q failed with an unexpected error %T: %s", test.name, err, err)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+// pg_recvpb3 receives changes from a pg_pb3_ld logical replication slot and
+// writes them to a file, similarly to pg_recvlogical.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/jackc/pglogrepl"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/johto/pg_pb3_ld/client"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// optionalBool is a boolean flag which remembers whether it was set, so that
+// unset plugin options can be left to the plugin's defaults.
+type optionalBool struct {
+	value *bool
+}
+
+func (b *optionalBool) String() string {
+	if b.value == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b.value)
+}
+
+func (b *optionalBool) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	b.value = &v
+	return nil
+}
+
+func (b *optionalBool) IsBoolFlag() bool {
+	return true
+}
+
+// optionalInt is the integer counterpart of optionalBool.
+type optionalInt struct {
+	value *int
+}
+
+func (i *optionalInt) String() string {
+	if i.value == nil {
+		return ""
+	}
+	return strconv.Itoa(*i.value)
+}
+
+func (i *optionalInt) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	i.value = &v
+	return nil
+}
+
+type lsnFlag struct {
+	lsn pglogrepl.LSN
+}
+
+func (l *lsnFlag) String() string {
+	return l.lsn.String()
+}
+
+func (l *lsnFlag) Set(s string) error {
+	lsn, err := pglogrepl.ParseLSN(s)
+	if err != nil {
+		return err
+	}
+	l.lsn = lsn
+	return nil
+}
+
+type oidRangesFlag struct {
+	ranges []client.OidRange
+}
+
+func (r *oidRangesFlag) String() string {
+	return ""
+}
+
+func (r *oidRangesFlag) Set(s string) error {
+	ranges, err := client.ParseOidRanges(s)
+	if err != nil {
+		return err
+	}
+	r.ranges = ranges
+	return nil
+}
+
+type tablePatternsFlag struct {
+	optionName string
+	patterns []client.TablePattern
+}
+
+func (p *tablePatternsFlag) String() string {
+	return ""
+}
+
+func (p *tablePatternsFlag) Set(s string) error {
+	patterns, err := client.ParseTablePatterns(s, p.optionName)
+	if err != nil {
+		return err
+	}
+	p.patterns = patterns
+	return nil
+}
+
+type columnProjectionsFlag struct {
+	projections []client.ColumnProjection
+}
+
+func (p *columnProjectionsFlag) String() string {
+	return ""
+}
+
+func (p *columnProjectionsFlag) Set(s string) error {
+	projections, err := client.ParseColumnProjections(s)
+	if err != nil {
+		return err
+	}
+	p.projections = projections
+	return nil
+}
+
+type rowFiltersFlag struct {
+	filters []client.RowFilter
+}
+
+func (f *rowFiltersFlag) String() string {
+	return ""
+}
+
+// Set is called once for every occurrence of the flag.
+func (f *rowFiltersFlag) Set(s string) error {
+	filter, err := client.ParseRowFilter(s)
+	if err != nil {
+		return err
+	}
+	f.filters = append(f.filters, filter)
+	return nil
+}
+
+// writer writes the received wire messages to the output in one of the
+// supported formats.
+type writer interface {
+	writeWireMessage(lsn pglogrepl.LSN, data []byte) error
+}
+
+// rawWriter writes each wire message prefixed by its length as a 32-bit
+// big-endian integer.
+type rawWriter struct {
+	out io.Writer
+}
+
+func (w *rawWriter) writeWireMessage(lsn pglogrepl.LSN, data []byte) error {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	_, err := w.out.Write(length[:])
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(data)
+	return err
+}
+
+// ndjsonWriter writes one JSON object per decoded message.
+type ndjsonWriter struct {
+	out io.Writer
+	decoder *client.Decoder
+}
+
+func (w *ndjsonWriter) writeWireMessage(lsn pglogrepl.LSN, data []byte) error {
+	messages, err := w.decoder.Decode(data)
+	if err != nil {
+		return fmt.Errorf("could not decode wire message at %s: %w", lsn, err)
+	}
+	for _, msg := range messages {
+		body, err := protojson.Marshal(proto.MessageV2(msg))
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(struct{
+			LSN string `json:"lsn"`
+			Type string `json:"type"`
+			Message json.RawMessage `json:"message"`
+		}{
+			LSN: lsn.String(),
+			Type: msg.Type().String(),
+			Message: json.RawMessage(body),
+		})
+		if err != nil {
+			return err
+		}
+		_, err = w.out.Write(append(line, '\n'))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textWriter writes the decoded messages in the protobuf text format, each
+// preceded by a comment line containing its position and type.
+type textWriter struct {
+	out io.Writer
+	decoder *client.Decoder
+}
+
+func (w *textWriter) writeWireMessage(lsn pglogrepl.LSN, data []byte) error {
+	messages, err := w.decoder.Decode(data)
+	if err != nil {
+		return fmt.Errorf("could not decode wire message at %s: %w", lsn, err)
+	}
+	for _, msg := range messages {
+		body, err := prototext.MarshalOptions{Multiline: true}.Marshal(proto.MessageV2(msg))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "# %s %s\n%s\n", lsn, msg.Type(), body)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errEndposReached = errors.New("endpos reached")
+
+func main() {
+	var (
+		dbname string
+		slot string
+		file string
+		format string
+		startLSN lsnFlag
+		endpos lsnFlag
+		statusInterval time.Duration
+		fsyncInterval time.Duration
+
+		enableBeginMessages optionalBool
+		enableCommitMessages optionalBool
+		enableTransactionMetadata optionalBool
+		enableTruncateMessages optionalBool
+		enableLogicalMessages optionalBool
+		logicalMessagePrefixes string
+		typeOidsMode string
+		binaryOidRanges oidRangesFlag
+		formatsMode string
+		enableTableOids optionalBool
+		enableRelationMessages optionalBool
+		missingReplicaIdentity string
+		enableOldValues optionalBool
+		enableUnchangedToastBitmap optionalBool
+		includeTables = tablePatternsFlag{optionName: "include_tables"}
+		excludeTables = tablePatternsFlag{optionName: "exclude_tables"}
+		columns columnProjectionsFlag
+		rowFilters rowFiltersFlag
+		origin string
+		wireMessageTargetSize optionalInt
+		streaming optionalBool
+		twoPhase optionalBool
+		enableSequenceMessages optionalBool
+		protoVersion string
+		compression string
+		strict bool
+		operations string
+	)
+
+	log.SetFlags(0)
+	log.SetPrefix("pg_recvpb3: ")
+
+	flag.StringVar(&dbname, "dbname", "", "connection string")
+	flag.StringVar(&slot, "slot", "", "name of the logical replication slot")
+	flag.StringVar(&file, "file", "-", "receive changes into this file, - for stdout")
+	flag.StringVar(&format, "format", "ndjson", "output format: raw, ndjson or text")
+	flag.Var(&startLSN, "start-lsn", "where in an existing slot should the streaming start")
+	flag.Var(&endpos, "endpos", "exit after receiving the specified LSN")
+	flag.DurationVar(&statusInterval, "status-interval", 10 * time.Second, "time between status updates sent to the server")
+	flag.DurationVar(&fsyncInterval, "fsync-interval", 10 * time.Second, "time between fsyncs to the output file")
+
+	flag.Var(&enableBeginMessages, "enable-begin-messages", "value of the enable_begin_messages plugin option")
+	flag.Var(&enableCommitMessages, "enable-commit-messages", "value of the enable_commit_messages plugin option")
+	flag.Var(&enableTransactionMetadata, "enable-transaction-metadata", "value of the enable_transaction_metadata plugin option")
+	flag.Var(&enableTruncateMessages, "enable-truncate-messages", "value of the enable_truncate_messages plugin option")
+	flag.Var(&enableLogicalMessages, "enable-logical-messages", "value of the enable_logical_messages plugin option")
+	flag.StringVar(&logicalMessagePrefixes, "logical-message-prefixes", "", "value of the logical_message_prefixes plugin option")
+	flag.StringVar(&typeOidsMode, "type-oids-mode", "", "value of the type_oids_mode plugin option")
+	flag.Var(&binaryOidRanges, "binary-oid-ranges", "value of the binary_oid_ranges plugin option")
+	flag.StringVar(&formatsMode, "formats-mode", "", "value of the formats_mode plugin option")
+	flag.Var(&enableTableOids, "enable-table-oids", "value of the enable_table_oids plugin option")
+	flag.Var(&enableRelationMessages, "enable-relation-messages", "value of the enable_relation_messages plugin option")
+	flag.StringVar(&missingReplicaIdentity, "missing-replica-identity", "", "value of the missing_replica_identity plugin option")
+	flag.Var(&enableOldValues, "enable-old-values", "value of the enable_old_values plugin option")
+	flag.Var(&enableUnchangedToastBitmap, "enable-unchanged-toast-bitmap", "value of the enable_unchanged_toast_bitmap plugin option")
+	flag.Var(&includeTables, "include-tables", "value of the include_tables plugin option")
+	flag.Var(&excludeTables, "exclude-tables", "value of the exclude_tables plugin option")
+	flag.Var(&columns, "columns", "value of the columns plugin option")
+	flag.StringVar(&operations, "operations", "", "value of the operations plugin option")
+	flag.Var(&rowFilters, "row-filter", "value of a row_filter plugin option; can be specified more than once")
+	flag.StringVar(&origin, "origin", "", "value of the origin plugin option")
+	flag.Var(&wireMessageTargetSize, "wire-message-target-size", "value of the wire_message_target_size plugin option, in bytes")
+	flag.Var(&streaming, "streaming", "value of the streaming plugin option")
+	flag.Var(&twoPhase, "two-phase", "value of the two_phase plugin option")
+	flag.Var(&enableSequenceMessages, "enable-sequence-messages", "value of the enable_sequence_messages plugin option")
+	flag.StringVar(&protoVersion, "proto-version", "", "value of the proto_version plugin option, e.g. " + client.NegotiateProtoVersion)
+	flag.StringVar(&compression, "compression", "", "value of the compression plugin option")
+	flag.BoolVar(&strict, "strict", false, "fail on messages of unknown types instead of skipping them")
+
+	flag.Parse()
+	if flag.NArg() > 0 {
+		log.Fatalf("too many command-line arguments (first is %q)", flag.Arg(0))
+	}
+	if slot == "" {
+		log.Fatal("no slot specified")
+	}
+
+	options := client.Options{
+		EnableBeginMessages: enableBeginMessages.value,
+		EnableCommitMessages: enableCommitMessages.value,
+		EnableTransactionMetadata: enableTransactionMetadata.value,
+		EnableTruncateMessages: enableTruncateMessages.value,
+		EnableLogicalMessages: enableLogicalMessages.value,
+		TypeOidsMode: client.TypeOidsMode(typeOidsMode),
+		BinaryOidRanges: binaryOidRanges.ranges,
+		FormatsMode: client.FormatsMode(formatsMode),
+		EnableTableOids: enableTableOids.value,
+		EnableRelationMessages: enableRelationMessages.value,
+		MissingReplicaIdentity: client.MissingReplicaIdentityMode(missingReplicaIdentity),
+		EnableOldValues: enableOldValues.value,
+		EnableUnchangedToastBitmap: enableUnchangedToastBitmap.value,
+		IncludeTables: includeTables.patterns,
+		ExcludeTables: excludeTables.patterns,
+		Columns: columns.projections,
+		RowFilters: rowFilters.filters,
+		Origin: origin,
+		WireMessageTargetSize: wireMessageTargetSize.value,
+		Streaming: streaming.value,
+		TwoPhase: twoPhase.value,
+		EnableSequenceMessages: enableSequenceMessages.value,
+		ProtoVersion: protoVersion,
+		Compression: client.Compression(compression),
+	}
+	if operations != "" {
+		for _, op := range strings.Split(operations, ",") {
+			options.Operations = append(options.Operations, client.Operation(strings.TrimSpace(op)))
+		}
+	}
+	if logicalMessagePrefixes != "" {
+		for _, prefix := range strings.Split(logicalMessagePrefixes, ",") {
+			options.LogicalMessagePrefixes = append(options.LogicalMessagePrefixes, strings.TrimSpace(prefix))
+		}
+	}
+	err := options.Validate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var outFile *os.File
+	if file == "-" {
+		outFile = os.Stdout
+	} else {
+		outFile, err = os.OpenFile(file, os.O_WRONLY | os.O_CREATE | os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	out := bufio.NewWriter(outFile)
+
+	decoder := client.NewDecoder()
+	decoder.Strict = strict
+
+	var w writer
+	switch format {
+		case "raw":
+			w = &rawWriter{out}
+		case "ndjson":
+			w = &ndjsonWriter{out, decoder}
+		case "text":
+			w = &textWriter{out, decoder}
+		default:
+			log.Fatalf("invalid output format %q", format)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	stream, err := client.OpenStream(ctx, client.StreamConfig{
+		ConnString: dbname,
+		SlotName: slot,
+		StartLSN: startLSN.lsn,
+		Options: options,
+		StatusInterval: statusInterval,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Only positions which have been written out and fsynced are acknowledged
+	// to the server.
+	var writtenLSN pglogrepl.LSN
+	lastSync := time.Now()
+	syncOutput := func() error {
+		err := out.Flush()
+		if err != nil {
+			return err
+		}
+		if outFile != os.Stdout {
+			err = outFile.Sync()
+			if err != nil {
+				return err
+			}
+		}
+		stream.Flush(writtenLSN)
+		lastSync = time.Now()
+		return nil
+	}
+
+	err = stream.RunWireMessages(ctx, func(lsn pglogrepl.LSN, data []byte) error {
+		if endpos.lsn != 0 && lsn > endpos.lsn {
+			return errEndposReached
+		}
+		err := w.writeWireMessage(lsn, data)
+		if err != nil {
+			return err
+		}
+		writtenLSN = lsn
+		if outFile == os.Stdout {
+			err = out.Flush()
+			if err != nil {
+				return err
+			}
+		}
+		if endpos.lsn != 0 && lsn == endpos.lsn {
+			return errEndposReached
+		}
+		if time.Since(lastSync) >= fsyncInterval {
+			return syncOutput()
+		}
+		return nil
+	})
+	if err != nil && err != errEndposReached && !errors.Is(err, context.Canceled) {
+		log.Fatal(err)
+	}
+
+	err = syncOutput()
+	if err != nil {
+		log.Fatal(err)
+	}
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+	err = stream.Close(closeCtx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if outFile != os.Stdout {
+		err = outFile.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -15,6 +15,8 @@
 #define PB3LD_FSD_TYPE_OIDS		4
 #define PB3LD_FSD_NULLS			5
 #define PB3LD_FSD_FORMATS		6
+#define PB3LD_FSD_COLUMN_NUMBERS	7
+#define PB3LD_FSD_UNCHANGED_TOAST	8
 
 #define EXTERNAL_ONDISK_OK		true
 #define EXTERNAL_ONDISK_NOTOK	false
@@ -22,6 +24,7 @@
 
 static void fsd_add_attribute(PB3LD_FieldSetDescription *fsd,
 							  Relation relation,
+							  int column_number,
 							  const char *attname,
 							  Oid typid,
 							  Datum valdatum,
@@ -43,6 +46,8 @@ fsd_reset(PB3LD_FieldSetDescription *fsd)
 	const int next = 0;
 
 	fsd->num_columns = 0;
+	fsd->num_expected_columns = 0;
+	fsd->has_unchanged_toast = false;
 
 	fsd->names[next] = NULL;
 	fsd->values[next] = NULL;
@@ -52,11 +57,13 @@ fsd_reset(PB3LD_FieldSetDescription *fsd)
 void
 fsd_populate_from_tuple(PB3LD_FieldSetDescription *fsd,
 						Relation relation,
-						ReorderBufferTupleBuf *tuple)
+						ReorderBufferTupleBuf *tuple,
+						const Bitmapset *excluded_columns)
 {
 	TupleDesc tupdesc;
 	HeapTuple htup;
 	int natt;
+	int column_number = 0;
 
 	htup = &tuple->tuple;
 	tupdesc = RelationGetDescr(relation);
@@ -71,12 +78,17 @@ fsd_populate_from_tuple(PB3LD_FieldSetDescription *fsd,
 		attr = TupleDescAttr(tupdesc, natt);
 		if (attr->attisdropped || attr->attnum < 0)
 			continue;
+		/* before looking at the value, so that it's never detoasted */
+		if (bms_is_member(attr->attnum, excluded_columns))
+			continue;
 
 		typid = attr->atttypid;
 		valdatum = heap_getattr(htup, natt + 1, tupdesc, &isnull);
-		fsd_add_attribute(fsd, relation, NameStr(attr->attname), typid,
+		fsd_add_attribute(fsd, relation, column_number, NameStr(attr->attname), typid,
 						  valdatum, isnull, EXTERNAL_ONDISK_OK);
+		column_number++;
 	}
+	fsd->num_expected_columns = column_number;
 }
 
 void
@@ -107,14 +119,16 @@ fsd_populate_via_index(PB3LD_FieldSetDescription *fsd,
 
 		typid = attr->atttypid;
 		valdatum = heap_getattr(htup, relattr, tupdesc, &isnull);
-		fsd_add_attribute(fsd, relation, NameStr(attr->attname), typid, valdatum, isnull, EXTERNAL_ONDISK_NOTOK);
+		fsd_add_attribute(fsd, relation, natt, NameStr(attr->attname), typid, valdatum, isnull, EXTERNAL_ONDISK_NOTOK);
 	}
+	fsd->num_expected_columns = indexrel->rd_index->indnatts;
 	index_close(indexrel, NoLock);
 }
 
 static void
 fsd_add_attribute(PB3LD_FieldSetDescription *fsd,
 				  Relation relation,
+				  int column_number,
 				  const char *attname,
 				  Oid typid,
 				  Datum valdatum,
@@ -138,11 +152,13 @@ fsd_add_attribute(PB3LD_FieldSetDescription *fsd,
 	if (isnull)
 	{
 		fsd->names[current] = attname;
+		fsd->column_numbers[current] = column_number;
 		fsd->values[current] = "";
 		fsd->value_lengths[current] = 0;
 		fsd->type_oids[current] = typid;
 		fsd->nulls[current] = true;
 		fsd->binary_formats[current] = false;
+		fsd->unchanged_toast[current] = false;
 		fsd->num_columns++;
 	}
 	else
@@ -168,9 +184,22 @@ fsd_add_attribute(PB3LD_FieldSetDescription *fsd,
 				 * TOASTed datum whose value did not change.  The value itself
 				 * is not written to WAL in this case, and in the real database
 				 * it might have been VACUUMed away.  We don't really have any
-				 * options other than to omit the column.
+				 * options other than to omit the column, or to send it without
+				 * a value if the client has asked us to.
 				 */
-
+				if (!fsd->privdata->unchanged_toast_bitmap_enabled)
+					return;
+
+				fsd->names[current] = attname;
+				fsd->column_numbers[current] = column_number;
+				fsd->values[current] = "";
+				fsd->value_lengths[current] = 0;
+				fsd->type_oids[current] = typid;
+				fsd->nulls[current] = false;
+				fsd->binary_formats[current] = binary_output;
+				fsd->unchanged_toast[current] = true;
+				fsd->has_unchanged_toast = true;
+				fsd->num_columns++;
 				return;
 			}
 			else
@@ -199,11 +228,13 @@ fsd_add_attribute(PB3LD_FieldSetDescription *fsd,
 		}
 
 		fsd->names[current] = attname;
+		fsd->column_numbers[current] = column_number;
 		fsd->values[current] = valuedata;
 		fsd->value_lengths[current] = valuelen;
 		fsd->type_oids[current] = typid;
 		fsd->nulls[current] = false;
 		fsd->binary_formats[current] = binary_output;
+		fsd->unchanged_toast[current] = false;
 		fsd->num_columns++;
 	}
 }
@@ -241,7 +272,8 @@ fsd_serialize(PB3LD_FieldSetDescription *fsd, int32 field_number, StringInfo out
 		Assert(fsd->names[i] != NULL);
 		Assert(fsd->values[i] != NULL);
 
-		pb3_append_string_kv(&tmpbuf, PB3LD_FSD_NAMES, fsd->names[i]);
+		if (!privdata->relation_messages_enabled)
+			pb3_append_string_kv(&tmpbuf, PB3LD_FSD_NAMES, fsd->names[i]);
 
 		if (fsd->nulls[i])
 		{
@@ -264,6 +296,19 @@ fsd_serialize(PB3LD_FieldSetDescription *fsd, int32 field_number, StringInfo out
 		}
 	}
 
+	/*
+	 * Without names, the client can't tell which columns are present if any
+	 * of them were omitted, so tell it explicitly.  The numbers refer to the
+	 * columns or the key columns of the RelationDescription.
+	 */
+	if (privdata->relation_messages_enabled &&
+		fsd->num_columns < fsd->num_expected_columns)
+	{
+		for (i = 0; i < fsd->num_columns; i++)
+			pb3_append_uint32_kv(&tmpbuf, PB3LD_FSD_COLUMN_NUMBERS,
+								 (uint32) fsd->column_numbers[i]);
+	}
+
 	pb3_append_varlen_key(&tmpbuf, PB3LD_FSD_NULLS);
 	pb3_append_int32(&tmpbuf, (int32) fsd->num_columns);
 	for (i = 0; i < fsd->num_columns; i++)
@@ -274,6 +319,20 @@ fsd_serialize(PB3LD_FieldSetDescription *fsd, int32 field_number, StringInfo out
 			appendStringInfoChar(&tmpbuf, '\000');
 	}
 
+	/* same format as the nulls, but only sent if any column is marked */
+	if (fsd->has_unchanged_toast)
+	{
+		pb3_append_varlen_key(&tmpbuf, PB3LD_FSD_UNCHANGED_TOAST);
+		pb3_append_int32(&tmpbuf, (int32) fsd->num_columns);
+		for (i = 0; i < fsd->num_columns; i++)
+		{
+			if (fsd->unchanged_toast[i])
+				appendStringInfoChar(&tmpbuf, '\001');
+			else
+				appendStringInfoChar(&tmpbuf, '\000');
+		}
+	}
+
 	if (privdata->formats_mode != PB3LD_FSD_FORMATS_DISABLED)
 	{
 		initStringInfo(&formatsbuf);
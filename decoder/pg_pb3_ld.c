@@ -1,35 +1,152 @@
 #include "postgres.h"
 
+#include "access/genam.h"
 #include "access/sysattr.h"
+#include "catalog/dependency.h"
 #include "catalog/pg_class.h"
+#include "access/xact.h"
+#include "commands/sequence.h"
 #include "nodes/parsenodes.h"
 #include "replication/output_plugin.h"
 #include "replication/logical.h"
+#include "replication/origin.h"
+#include "storage/bufmgr.h"
+#include "storage/smgr.h"
 #include "utils/builtins.h"
+#include "utils/guc.h"
 #include "utils/lsyscache.h"
 #include "utils/memutils.h"
 #include "utils/rel.h"
+#include "utils/relcache.h"
+#include "utils/timestamp.h"
 
 #include "pg_pb3_ld.h"
 
+#if PG_VERSION_NUM < 110000
+/* memory units aren't accepted for wire_message_target_size before 11 */
+#define GUC_UNIT_BYTE 0
+#endif
+
 #define PB3LD_WMSG_BEGIN	0
 #define PB3LD_WMSG_COMMIT	1
 #define PB3LD_WMSG_INSERT	2
 #define PB3LD_WMSG_UPDATE	3
 #define PB3LD_WMSG_DELETE	4
+#define PB3LD_WMSG_TRUNCATE	5
+#define PB3LD_WMSG_MESSAGE	6
+#define PB3LD_WMSG_RELATION	7
+#define PB3LD_WMSG_STREAM_START		8
+#define PB3LD_WMSG_STREAM_STOP		9
+#define PB3LD_WMSG_STREAM_COMMIT	10
+#define PB3LD_WMSG_STREAM_ABORT		11
+#define PB3LD_WMSG_PREPARE				12
+#define PB3LD_WMSG_COMMIT_PREPARED		13
+#define PB3LD_WMSG_ROLLBACK_PREPARED	14
+#define PB3LD_WMSG_STREAM_PREPARE		15
+#define PB3LD_WMSG_SEQUENCE				16
+#define PB3LD_WMSG_SESSION_START		17
+
+/* SessionStart */
+#define PB3LD_SS_PROTOCOL_VERSION	1
+
+/* BeginTransaction */
+#define PB3LD_BEGIN_XID			1
+#define PB3LD_BEGIN_FINAL_LSN	2
+#define PB3LD_BEGIN_COMMIT_TIME	3
+#define PB3LD_BEGIN_ORIGIN_NAME	4
+
+/* CommitTransaction */
+#define PB3LD_COMMIT_XID			1
+#define PB3LD_COMMIT_COMMIT_LSN		2
+#define PB3LD_COMMIT_END_LSN		3
+#define PB3LD_COMMIT_COMMIT_TIME	4
+
+/* StreamStart */
+#define PB3LD_SSTART_XID			1
+#define PB3LD_SSTART_FIRST_SEGMENT	2
+
+/* StreamStop */
+#define PB3LD_SSTOP_XID		1
+
+/* StreamCommit */
+#define PB3LD_SCOMMIT_XID			1
+#define PB3LD_SCOMMIT_COMMIT_LSN	2
+#define PB3LD_SCOMMIT_END_LSN		3
+#define PB3LD_SCOMMIT_COMMIT_TIME	4
+
+/* StreamAbort */
+#define PB3LD_SABORT_XID		1
+#define PB3LD_SABORT_SUBXID		2
+#define PB3LD_SABORT_ABORT_LSN	3
+
+/* PrepareTransaction and StreamPrepare */
+#define PB3LD_PREPARE_XID			1
+#define PB3LD_PREPARE_GID			2
+#define PB3LD_PREPARE_PREPARE_LSN	3
+#define PB3LD_PREPARE_END_LSN		4
+#define PB3LD_PREPARE_PREPARE_TIME	5
+
+/* CommitPrepared */
+#define PB3LD_CPREP_XID				1
+#define PB3LD_CPREP_GID				2
+#define PB3LD_CPREP_COMMIT_LSN		3
+#define PB3LD_CPREP_END_LSN			4
+#define PB3LD_CPREP_COMMIT_TIME		5
+
+/* RollbackPrepared */
+#define PB3LD_RPREP_XID				1
+#define PB3LD_RPREP_GID				2
+#define PB3LD_RPREP_PREPARE_END_LSN	3
+#define PB3LD_RPREP_END_LSN			4
+#define PB3LD_RPREP_PREPARE_TIME	5
+#define PB3LD_RPREP_ROLLBACK_TIME	6
 
 /* InsertDescription */
 #define PB3LD_INS_TABLE_DESC	1
 #define PB3LD_INS_NEW_VALUES	3
+#define PB3LD_INS_STREAM_XID	15
 
 /* UpdateDescription */
 #define PB3LD_UPD_TABLE_DESC	1
 #define PB3LD_UPD_KEY_FIELDS	3
 #define PB3LD_UPD_NEW_VALUES	5
+#define PB3LD_UPD_OLD_VALUES	7
+#define PB3LD_UPD_STREAM_XID	15
 
 /* DeleteDescription */
 #define PB3LD_DEL_TABLE_DESC	1
 #define PB3LD_DEL_KEY_FIELDS	3
+#define PB3LD_DEL_OLD_VALUES	5
+#define PB3LD_DEL_STREAM_XID	15
+
+/* TruncateDescription */
+#define PB3LD_TRUNC_TABLES				1
+#define PB3LD_TRUNC_CASCADE				2
+#define PB3LD_TRUNC_RESTART_IDENTITY	3
+#define PB3LD_TRUNC_STREAM_XID			15
+
+/* LogicalMessage */
+#define PB3LD_MSG_PREFIX		1
+#define PB3LD_MSG_CONTENT		2
+#define PB3LD_MSG_TRANSACTIONAL	3
+#define PB3LD_MSG_LSN			4
+#define PB3LD_MSG_STREAM_XID	15
+
+/* RelationDescription */
+#define PB3LD_REL_RELATION_ID		1
+#define PB3LD_REL_SCHEMANAME		2
+#define PB3LD_REL_TABLENAME			3
+#define PB3LD_REL_COLUMN_NAMES		4
+#define PB3LD_REL_TYPE_OIDS			5
+#define PB3LD_REL_TYPE_MODIFIERS	6
+#define PB3LD_REL_KEY_COLUMNS		7
+
+/* SequenceDescription */
+#define PB3LD_SEQ_SCHEMANAME	1
+#define PB3LD_SEQ_SEQUENCENAME	2
+#define PB3LD_SEQ_SEQUENCEOID	3
+#define PB3LD_SEQ_LAST_VALUE	4
+#define PB3LD_SEQ_IS_CALLED		5
 
 /* TableDescription */
 #define PB3LD_TD_SCHEMANAME		1
@@ -47,11 +164,68 @@ static void pb3ld_shutdown(LogicalDecodingContext *ctx);
 static void pb3ld_begin_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn);
 static void pb3ld_commit_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
 							 XLogRecPtr commit_lsn);
+static int64 pb3ld_commit_time(ReorderBufferTXN *txn);
+static int64 pb3ld_unix_time(TimestampTz timestamp);
+static bool pb3ld_filter_by_origin(LogicalDecodingContext *ctx, RepOriginId origin_id);
+static List *pb3ld_resolve_origin_names(List *names);
+static Oid pb3ld_replica_identity_index(Relation relation);
+static bool pb3ld_relation_filtered_out(PB3LD_Private *privdata, Relation relation);
+static const Bitmapset *pb3ld_relation_excluded_columns(PB3LD_Private *privdata, Relation relation);
+static PB3LD_Row_Filter_State *pb3ld_relation_row_filter(PB3LD_Private *privdata, Relation relation);
+static bool pb3ld_apply_row_filter(PB3LD_Row_Filter_State *row_filter, Relation relation,
+								   char relreplident, ReorderBufferChange *change,
+								   ReorderBufferChangeType *action);
+static ReorderBufferTupleBuf *pb3ld_fill_unchanged_toast(Relation relation,
+														 ReorderBufferTupleBuf *newtuple,
+														 ReorderBufferTupleBuf *oldtuple);
+static void pb3ld_maybe_send_RelationDescription(PB3LD_Private *privdata,
+												  Relation relation);
 static void pb3ld_write_TableDescription(const PB3LD_Private *privdata,
 										 StringInfo out,
 										 Relation relation);
+static List *pb3ld_relation_owned_sequences(Oid relid);
+static PB3LD_Pending_Sequences *pb3ld_find_pending_sequences(PB3LD_Private *privdata,
+																TransactionId xid);
+static void pb3ld_note_sequences(PB3LD_Private *privdata, ReorderBufferTXN *txn,
+								 Relation relation);
+static void pb3ld_send_sequences(PB3LD_Private *privdata, ReorderBufferTXN *txn,
+								 bool streamed);
+static void pb3ld_forget_sequences(PB3LD_Private *privdata, TransactionId xid);
+static void pb3ld_maybe_send_SequenceDescription(PB3LD_Private *privdata, Oid seqid,
+												 ReorderBufferTXN *stream_txn);
+static void pb3ld_append_stream_xid(PB3LD_Private *privdata, int32 field_number,
+									ReorderBufferTXN *txn);
 static void pb3ld_change(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
 						 Relation relation, ReorderBufferChange *change);
+static void pb3ld_message(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+						  XLogRecPtr message_lsn, bool transactional,
+						  const char *prefix, Size message_size,
+						  const char *message);
+#if PG_VERSION_NUM >= 110000
+static void pb3ld_truncate(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+						   int nrelations, Relation relations[],
+						   ReorderBufferChange *change);
+#endif
+#if PG_VERSION_NUM >= 140000
+static void pb3ld_write_StreamStart(PB3LD_Private *privdata, ReorderBufferTXN *txn);
+static void pb3ld_write_StreamStop(PB3LD_Private *privdata, ReorderBufferTXN *txn);
+static void pb3ld_stream_start(LogicalDecodingContext *ctx, ReorderBufferTXN *txn);
+static void pb3ld_stream_stop(LogicalDecodingContext *ctx, ReorderBufferTXN *txn);
+static void pb3ld_stream_abort(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+							   XLogRecPtr abort_lsn);
+static void pb3ld_stream_commit(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+								XLogRecPtr commit_lsn);
+static void pb3ld_write_prepare(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+								XLogRecPtr prepare_lsn, int32 msgtype);
+static void pb3ld_prepare_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+							  XLogRecPtr prepare_lsn);
+static void pb3ld_commit_prepared_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+									  XLogRecPtr commit_lsn);
+static void pb3ld_rollback_prepared_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+										XLogRecPtr prepare_end_lsn, TimestampTz prepare_time);
+static void pb3ld_stream_prepare(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+								 XLogRecPtr prepare_lsn);
+#endif
 
 void
 _PG_init(void)
@@ -68,6 +242,27 @@ _PG_output_plugin_init(OutputPluginCallbacks *cb)
 	cb->begin_cb = pb3ld_begin_txn;
 	cb->commit_cb = pb3ld_commit_txn;
 	cb->change_cb = pb3ld_change;
+	cb->message_cb = pb3ld_message;
+	cb->filter_by_origin_cb = pb3ld_filter_by_origin;
+#if PG_VERSION_NUM >= 110000
+	cb->truncate_cb = pb3ld_truncate;
+#endif
+#if PG_VERSION_NUM >= 140000
+	cb->stream_start_cb = pb3ld_stream_start;
+	cb->stream_stop_cb = pb3ld_stream_stop;
+	cb->stream_abort_cb = pb3ld_stream_abort;
+	cb->stream_commit_cb = pb3ld_stream_commit;
+	/* changes in a stream block are sent just like any other changes */
+	cb->stream_change_cb = pb3ld_change;
+	cb->stream_message_cb = pb3ld_message;
+	cb->stream_truncate_cb = pb3ld_truncate;
+
+	cb->begin_prepare_cb = pb3ld_begin_txn;
+	cb->prepare_cb = pb3ld_prepare_txn;
+	cb->commit_prepared_cb = pb3ld_commit_prepared_txn;
+	cb->rollback_prepared_cb = pb3ld_rollback_prepared_txn;
+	cb->stream_prepare_cb = pb3ld_stream_prepare;
+#endif
 }
 
 static void
@@ -92,9 +287,10 @@ pb3ld_startup(LogicalDecodingContext *ctx, OutputPluginOptions *opt,
 
 	ctx->output_plugin_private = privdata;
 
+	privdata->protocol_version = PB3LD_MIN_PROTOCOL_VERSION;
+	privdata->session_start_sent = false;
 	privdata->sent_message_this_transaction = false;
-	/* TODO: this could be configurable */
-	privdata->wire_message_target_size = 4 * 1024 * 1024;
+	privdata->wire_message_target_size = PB3LD_DEFAULT_WIRE_MESSAGE_TARGET_SIZE;
 	privdata->buf_context = AllocSetContextCreate(ctx->context,
 												  "PB3LD internal buffer memory context",
 												  ALLOCSET_DEFAULT_MINSIZE,
@@ -104,17 +300,39 @@ pb3ld_startup(LogicalDecodingContext *ctx, OutputPluginOptions *opt,
 	privdata->header_buf = makeStringInfo();
 	privdata->message_buf = makeStringInfo();
 	MemoryContextSwitchTo(oldcxt);
+	privdata->compression = PB3LD_COMPRESSION_NONE;
+	privdata->compress_buf = NULL;
 
 	privdata->begin_messages_enabled = false;
 	privdata->commit_messages_enabled = true;
-
-	privdata->repl_identity_required = true;
+	privdata->transaction_metadata_enabled = false;
+	privdata->truncate_messages_enabled = false;
+	privdata->logical_messages_enabled = false;
+	privdata->logical_message_prefixes = NIL;
+
+	privdata->missing_replica_identity = PB3LD_MISSING_REPLICA_IDENTITY_ERROR;
+	privdata->old_values_enabled = false;
+	privdata->unchanged_toast_bitmap_enabled = false;
+	privdata->include_tables = NIL;
+	privdata->exclude_tables = NIL;
+	privdata->column_projections = NIL;
+	privdata->row_filters = NIL;
+	privdata->origin_mode = PB3LD_ORIGIN_ANY;
+	privdata->origin_ids = NIL;
+	privdata->operations = PB3LD_OP_ALL;
+	privdata->streaming_enabled = false;
+	privdata->in_stream_block = false;
+	privdata->stream_block_number = 0;
+	privdata->two_phase_enabled = false;
+	privdata->sequence_messages_enabled = false;
+	privdata->pending_sequences = NIL;
 
 	privdata->type_oids_mode = PB3LD_FSD_TYPE_OIDS_DISABLED;
 	privdata->binary_oid_ranges = NULL;
 	privdata->formats_mode = PB3LD_FSD_FORMATS_DISABLED;
 
 	privdata->table_oids_enabled = false;
+	privdata->relation_messages_enabled = false;
 
 	foreach(option, ctx->output_plugin_options)
 	{
@@ -142,6 +360,198 @@ pb3ld_startup(LogicalDecodingContext *ctx, OutputPluginOptions *opt,
 						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
 								strVal(elem->arg), elem->defname)));
 		}
+		else if (strcmp(elem->defname, "enable_transaction_metadata") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->transaction_metadata_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->transaction_metadata_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "enable_truncate_messages") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->truncate_messages_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->truncate_messages_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "enable_sequence_messages") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->sequence_messages_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->sequence_messages_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "enable_logical_messages") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->logical_messages_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->logical_messages_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "logical_message_prefixes") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("logical_message_prefixes requires an argument")));
+			privdata->logical_message_prefixes = pb3ld_parse_logical_message_prefixes(strVal(elem->arg));
+		}
+		else if (strcmp(elem->defname, "include_tables") == 0 ||
+				 strcmp(elem->defname, "exclude_tables") == 0)
+		{
+			List *patterns;
+
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("%s requires an argument", elem->defname)));
+			patterns = pb3ld_parse_table_patterns(strVal(elem->arg), elem->defname);
+			if (strcmp(elem->defname, "include_tables") == 0)
+				privdata->include_tables = patterns;
+			else
+				privdata->exclude_tables = patterns;
+		}
+		else if (strcmp(elem->defname, "columns") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("columns requires an argument")));
+			privdata->column_projections = pb3ld_parse_column_projections(strVal(elem->arg));
+		}
+		else if (strcmp(elem->defname, "row_filter") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("row_filter requires an argument")));
+			/* can be passed once per table */
+			privdata->row_filters = lappend(privdata->row_filters,
+											pb3ld_parse_row_filter(strVal(elem->arg)));
+		}
+		else if (strcmp(elem->defname, "origin") == 0)
+		{
+			char *value;
+
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("origin requires an argument")));
+			value = strVal(elem->arg);
+			if (strcmp(value, "any") == 0)
+				privdata->origin_mode = PB3LD_ORIGIN_ANY;
+			else if (strcmp(value, "none") == 0)
+				privdata->origin_mode = PB3LD_ORIGIN_NONE;
+			else
+			{
+				privdata->origin_mode = PB3LD_ORIGIN_LIST;
+				privdata->origin_ids = pb3ld_resolve_origin_names(pb3ld_parse_origin_names(value));
+			}
+		}
+		else if (strcmp(elem->defname, "operations") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("operations requires an argument")));
+			privdata->operations = pb3ld_parse_operations(strVal(elem->arg));
+		}
+		else if (strcmp(elem->defname, "streaming") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->streaming_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->streaming_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "two_phase") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->two_phase_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->two_phase_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "missing_replica_identity") == 0)
+		{
+			char *mode;
+
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("missing_replica_identity requires an argument")));
+			mode = strVal(elem->arg);
+			if (strcmp(mode, "error") == 0)
+				privdata->missing_replica_identity = PB3LD_MISSING_REPLICA_IDENTITY_ERROR;
+			else if (strcmp(mode, "skip") == 0)
+				privdata->missing_replica_identity = PB3LD_MISSING_REPLICA_IDENTITY_SKIP;
+			else if (strcmp(mode, "send_without_keys") == 0)
+				privdata->missing_replica_identity = PB3LD_MISSING_REPLICA_IDENTITY_SEND_WITHOUT_KEYS;
+			else if (strcmp(mode, "send_full_old_tuple_if_available") == 0)
+				privdata->missing_replica_identity = PB3LD_MISSING_REPLICA_IDENTITY_SEND_FULL_OLD_TUPLE;
+			else
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("\"%s\" is not a valid value for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "compression") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("compression requires an argument")));
+			privdata->compression = pb3ld_parse_compression(strVal(elem->arg));
+		}
+		else if (strcmp(elem->defname, "proto_version") == 0)
+		{
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("proto_version requires an argument")));
+			privdata->protocol_version = pb3ld_parse_protocol_version(strVal(elem->arg));
+		}
+		else if (strcmp(elem->defname, "wire_message_target_size") == 0)
+		{
+			int size;
+			const char *hintmsg;
+
+			if (elem->arg == NULL)
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("wire_message_target_size requires an argument")));
+			if (!parse_int(strVal(elem->arg), &size, GUC_UNIT_BYTE, &hintmsg))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname),
+						 hintmsg ? errhint("%s", _(hintmsg)) : 0));
+			if (size != 0 &&
+				(size < PB3LD_MIN_WIRE_MESSAGE_TARGET_SIZE ||
+				 size > PB3LD_MAX_WIRE_MESSAGE_TARGET_SIZE))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("wire_message_target_size must be 0 or between %d and %d bytes",
+								PB3LD_MIN_WIRE_MESSAGE_TARGET_SIZE,
+								PB3LD_MAX_WIRE_MESSAGE_TARGET_SIZE)));
+			privdata->wire_message_target_size = size;
+		}
 		else if (strcmp(elem->defname, "type_oids_mode") == 0)
 		{
 			char *mode;
@@ -203,6 +613,36 @@ pb3ld_startup(LogicalDecodingContext *ctx, OutputPluginOptions *opt,
 						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
 								strVal(elem->arg), elem->defname)));
 		}
+		else if (strcmp(elem->defname, "enable_relation_messages") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->relation_messages_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->relation_messages_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "enable_old_values") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->old_values_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->old_values_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
+		else if (strcmp(elem->defname, "enable_unchanged_toast_bitmap") == 0)
+		{
+			if (elem->arg == NULL)
+				privdata->unchanged_toast_bitmap_enabled = true;
+			else if (!parse_bool(strVal(elem->arg), &privdata->unchanged_toast_bitmap_enabled))
+				ereport(ERROR,
+						(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+						 errmsg("could not parse value \"%s\" for parameter \"%s\"",
+								strVal(elem->arg), elem->defname)));
+		}
 		else
 		{
 			ereport(ERROR,
@@ -213,7 +653,38 @@ pb3ld_startup(LogicalDecodingContext *ctx, OutputPluginOptions *opt,
 		}
 	}
 
-	enlargeStringInfo(privdata->message_buf, 2 * privdata->wire_message_target_size);
+#if PG_VERSION_NUM >= 140000
+	/* the server streams whenever the callbacks are present unless told not to */
+	ctx->streaming &= privdata->streaming_enabled;
+#else
+	if (privdata->streaming_enabled)
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("streaming requires PostgreSQL 14 or later")));
+#endif
+
+#if PG_VERSION_NUM >= 150000
+	/*
+	 * Giving the option marks the slot for two-phase decoding, after which
+	 * the server decodes prepared transactions whenever the callbacks are
+	 * present unless told not to.
+	 */
+	ctx->twophase &= privdata->two_phase_enabled;
+	ctx->twophase_opt_given = privdata->two_phase_enabled;
+#else
+	/* before 15 only slots created with two_phase could decode PREPAREs */
+	if (privdata->two_phase_enabled)
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("two_phase requires PostgreSQL 15 or later")));
+#if PG_VERSION_NUM >= 140000
+	ctx->twophase = false;
+#endif
+#endif
+
+	enlargeStringInfo(privdata->message_buf, pb3ld_message_buffer_alloc_size(privdata));
+
+	pb3ld_relcache_init(ctx->context);
 }
 
 static void
@@ -240,6 +711,24 @@ pb3ld_begin_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn)
 	if (privdata->begin_messages_enabled)
 	{
 		pb3ld_wire_message_begin(privdata, PB3LD_WMSG_BEGIN);
+		if (privdata->transaction_metadata_enabled)
+		{
+			pb3_append_uint32_kv(privdata->message_buf, PB3LD_BEGIN_XID, txn->xid);
+			pb3_append_uint64_kv(privdata->message_buf, PB3LD_BEGIN_FINAL_LSN, txn->final_lsn);
+			pb3_append_int64_kv(privdata->message_buf, PB3LD_BEGIN_COMMIT_TIME,
+								pb3ld_commit_time(txn));
+		}
+		/* not metadata; the client needs it to make sense of the origin option */
+		if (txn->origin_id != InvalidRepOriginId)
+		{
+			char *origin_name;
+
+			if (replorigin_by_oid(txn->origin_id, true, &origin_name))
+			{
+				pb3_append_string_kv(privdata->message_buf, PB3LD_BEGIN_ORIGIN_NAME, origin_name);
+				pfree(origin_name);
+			}
+		}
 		pb3ld_wire_message_end(privdata, PB3LD_WMSG_BEGIN);
 	}
 }
@@ -257,9 +746,19 @@ pb3ld_commit_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
 		return;
 	}
 
+	pb3ld_send_sequences(privdata, txn, false);
+
 	if (privdata->commit_messages_enabled)
 	{
 		pb3ld_wire_message_begin(privdata, PB3LD_WMSG_COMMIT);
+		if (privdata->transaction_metadata_enabled)
+		{
+			pb3_append_uint32_kv(privdata->message_buf, PB3LD_COMMIT_XID, txn->xid);
+			pb3_append_uint64_kv(privdata->message_buf, PB3LD_COMMIT_COMMIT_LSN, commit_lsn);
+			pb3_append_uint64_kv(privdata->message_buf, PB3LD_COMMIT_END_LSN, txn->end_lsn);
+			pb3_append_int64_kv(privdata->message_buf, PB3LD_COMMIT_COMMIT_TIME,
+								pb3ld_commit_time(txn));
+		}
 		pb3ld_wire_message_end(privdata, PB3LD_WMSG_COMMIT);
 	}
 
@@ -271,149 +770,1046 @@ pb3ld_commit_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
 	}
 }
 
-static void
-pb3ld_write_TableDescription(const PB3LD_Private *privdata, StringInfo out, Relation relation)
+/*
+ * Returns true if changes from origin_id should not be decoded.  Called for
+ * every record with an origin before any work is done on it.
+ */
+static bool
+pb3ld_filter_by_origin(LogicalDecodingContext *ctx, RepOriginId origin_id)
 {
-	Form_pg_class class_form;
-	StringInfoData tmpbuf;
-
-	initStringInfo(&tmpbuf);
-
-	pb3_append_string_kv(&tmpbuf, PB3LD_TD_SCHEMANAME,
-						 get_namespace_name(get_rel_namespace(RelationGetRelid(relation))));
-	class_form = RelationGetForm(relation);
-	pb3_append_string_kv(&tmpbuf, PB3LD_TD_TABLENAME,
-						 NameStr(class_form->relname));
+	PB3LD_Private *privdata = ctx->output_plugin_private;
 
-	if (privdata->table_oids_enabled)
-		pb3_append_oid_kv(&tmpbuf, PB3LD_TD_TABLEOID, RelationGetRelid(relation));
+	if (origin_id == InvalidRepOriginId)
+		return false;
 
-	pb3_append_int32(out, (int32) tmpbuf.len);
-	appendBinaryStringInfo(out, tmpbuf.data, tmpbuf.len);
+	switch (privdata->origin_mode)
+	{
+		case PB3LD_ORIGIN_ANY:
+			return false;
+		case PB3LD_ORIGIN_NONE:
+			return true;
+		case PB3LD_ORIGIN_LIST:
+			return !list_member_oid(privdata->origin_ids, (Oid) origin_id);
+	}
+	return false;
 }
 
-static void
-pb3ld_change(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
-			 Relation relation, ReorderBufferChange *change)
+/*
+ * Looks up the ids of the named replication origins.  The filter callback
+ * can't access the catalogs, so this has to be done when decoding starts,
+ * and origins created after that aren't known.
+ */
+static List *
+pb3ld_resolve_origin_names(List *names)
 {
-	PB3LD_Private *privdata = ctx->output_plugin_private;
-	char relreplident = relation->rd_rel->relreplident;
-	Oid rd_replidindex = InvalidOid;
-	MemoryContext oldcxt;
+	MemoryContext oldcxt = CurrentMemoryContext;
+	bool started_transaction = false;
+	List *origin_ids = NIL;
+	ListCell *lc;
 
-	if (relreplident == REPLICA_IDENTITY_NOTHING)
+	/* there's no transaction when called by a walsender */
+	if (!IsTransactionState())
 	{
-		/*
-		 * System catalog and/or whatnot; don't replicate.
-		 */
-		return;
+		StartTransactionCommand();
+		started_transaction = true;
 	}
-	else if (relreplident == REPLICA_IDENTITY_DEFAULT)
+
+	foreach(lc, names)
 	{
-		if (change->action == REORDER_BUFFER_CHANGE_UPDATE ||
-			change->action == REORDER_BUFFER_CHANGE_DELETE)
-		{
-			RelationGetIndexList(relation);
-			rd_replidindex = relation->rd_replidindex;
-			/* TODO */
-			if (privdata->repl_identity_required && !OidIsValid(rd_replidindex))
-			{
-				Form_pg_class class_form;
+		RepOriginId origin_id = replorigin_by_name((const char *) lfirst(lc), false);
+		MemoryContext txncxt = MemoryContextSwitchTo(oldcxt);
 
-				class_form = RelationGetForm(relation);
-				ereport(ERROR,
-						(errcode(ERRCODE_OBJECT_NOT_IN_PREREQUISITE_STATE),
-						 errmsg("foo bar %s sucks", NameStr(class_form->relname))));
-			}
-		}
+		origin_ids = lappend_oid(origin_ids, (Oid) origin_id);
+		MemoryContextSwitchTo(txncxt);
 	}
-	else if (relreplident != REPLICA_IDENTITY_FULL)
+
+	if (started_transaction)
 	{
-		/* TODO: REPLICA_IDENTITY_INDEX */
-		elog(ERROR, "unexpected replica identity %d", relreplident);
+		CommitTransactionCommand();
+		MemoryContextSwitchTo(oldcxt);
 	}
 
-	oldcxt = MemoryContextSwitchTo(privdata->change_context);
+	return origin_ids;
+}
 
-	switch (change->action)
-	{
-		case REORDER_BUFFER_CHANGE_INSERT:
-			Assert(change->data.tp.newtuple != NULL);
+/*
+ * Returns the commit timestamp of the transaction in microseconds since the
+ * Unix epoch.  For a prepared transaction, this is the time of the PREPARE
+ * until its COMMIT PREPARED or ROLLBACK PREPARED is decoded, and the time of
+ * that record afterwards.
+ */
+static int64
+pb3ld_commit_time(ReorderBufferTXN *txn)
+{
+	TimestampTz commit_time;
 
-			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_INSERT);
+#if PG_VERSION_NUM >= 150000
+	commit_time = txn->xact_time.commit_time;
+#else
+	commit_time = txn->commit_time;
+#endif
 
-			pb3_append_varlen_key(privdata->message_buf, PB3LD_INS_TABLE_DESC);
-			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+	return pb3ld_unix_time(commit_time);
+}
 
-			fsd_reset(&privdata->change_fsd);
-			fsd_populate_from_tuple(&privdata->change_fsd, relation, change->data.tp.newtuple);
-			fsd_serialize(&privdata->change_fsd, PB3LD_INS_NEW_VALUES, privdata->message_buf);
+/*
+ * Converts a timestamp to microseconds since the Unix epoch, which is what
+ * clients outside of PostgreSQL are going to want.
+ */
+static int64
+pb3ld_unix_time(TimestampTz timestamp)
+{
+	return (int64) timestamp +
+		((int64) (POSTGRES_EPOCH_JDATE - UNIX_EPOCH_JDATE) * SECS_PER_DAY * USECS_PER_SEC);
+}
 
-			pb3ld_wire_message_end(privdata, PB3LD_WMSG_INSERT);
+/*
+ * Returns the index used as the replica identity of a relation with
+ * REPLICA IDENTITY DEFAULT or USING INDEX, or InvalidOid if there isn't one:
+ * the table doesn't have a primary key, or the index has been dropped.
+ */
+static Oid
+pb3ld_replica_identity_index(Relation relation)
+{
+	/* rd_replidindex is only valid after the index list has been loaded */
+	RelationGetIndexList(relation);
+	return relation->rd_replidindex;
+}
 
-			if (change->data.tp.oldtuple != NULL)
-				elog(ERROR, "oldtuple is not NULL in INSERT");
-			break;
-		case REORDER_BUFFER_CHANGE_UPDATE:
-			Assert(change->data.tp.newtuple != NULL);
+/*
+ * Returns true if the relation should not be decoded because of the
+ * include_tables and exclude_tables options.  The result is cached in the
+ * relation cache until the relation is invalidated.
+ */
+static bool
+pb3ld_relation_filtered_out(PB3LD_Private *privdata, Relation relation)
+{
+	PB3LD_RelationCacheEntry *entry;
+	char *schema_name;
+	const char *table_name;
+
+	if (privdata->include_tables == NIL && privdata->exclude_tables == NIL)
+		return false;
+
+	entry = pb3ld_relcache_get(RelationGetRelid(relation));
+	if (entry->filter_checked)
+		return entry->filtered_out;
+
+	schema_name = get_namespace_name(RelationGetNamespace(relation));
+	table_name = RelationGetRelationName(relation);
+
+	if (privdata->include_tables != NIL &&
+		!pb3ld_table_patterns_match(privdata->include_tables, schema_name, table_name))
+		entry->filtered_out = true;
+	else
+		entry->filtered_out = pb3ld_table_patterns_match(privdata->exclude_tables,
+														 schema_name, table_name);
+	entry->filter_checked = true;
+	pfree(schema_name);
+
+	return entry->filtered_out;
+}
 
-			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_UPDATE);
+/*
+ * Returns the attnums of the columns of the relation excluded by the columns
+ * option, or NULL if there are none.  Replica identity index columns are
+ * never excluded, since the key fields are always sent.
+ */
+static const Bitmapset *
+pb3ld_relation_excluded_columns(PB3LD_Private *privdata, Relation relation)
+{
+	PB3LD_RelationCacheEntry *entry;
+	const PB3LD_Column_Projection *projection = NULL;
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	Bitmapset *identity_key;
+	Bitmapset *excluded = NULL;
+	char *schema_name;
+	const char *table_name;
+	ListCell *lc;
+	int natt;
+
+	if (privdata->column_projections == NIL)
+		return NULL;
+
+	entry = pb3ld_relcache_get(RelationGetRelid(relation));
+	if (entry->projection_checked)
+		return entry->excluded_columns;
+
+	schema_name = get_namespace_name(RelationGetNamespace(relation));
+	table_name = RelationGetRelationName(relation);
+	foreach(lc, privdata->column_projections)
+	{
+		const PB3LD_Column_Projection *p = (const PB3LD_Column_Projection *) lfirst(lc);
 
-			pb3_append_varlen_key(privdata->message_buf, PB3LD_UPD_TABLE_DESC);
-			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+		if (pb3ld_table_pattern_matches(&p->table, schema_name, table_name))
+		{
+			projection = p;
+			break;
+		}
+	}
+	pfree(schema_name);
 
-			fsd_reset(&privdata->change_fsd);
-			fsd_populate_from_tuple(&privdata->change_fsd, relation, change->data.tp.newtuple);
-			fsd_serialize(&privdata->change_fsd, PB3LD_UPD_NEW_VALUES, privdata->message_buf);
+	if (projection == NULL)
+	{
+		pb3ld_relcache_set_excluded_columns(entry, NULL);
+		return NULL;
+	}
 
-			fsd_reset(&privdata->change_fsd);
-			if (OidIsValid(rd_replidindex))
-			{
-				ReorderBufferTupleBuf *keytuple;
+	identity_key = RelationGetIndexAttrBitmap(relation, INDEX_ATTR_BITMAP_IDENTITY_KEY);
+	for (natt = 0; natt < tupdesc->natts; natt++)
+	{
+		Form_pg_attribute attr = TupleDescAttr(tupdesc, natt);
+		bool listed = false;
 
-				if (change->data.tp.oldtuple != NULL)
-					keytuple = change->data.tp.oldtuple;
-				else
-					keytuple = change->data.tp.newtuple;
+		if (attr->attisdropped || attr->attnum < 0)
+			continue;
+		if (bms_is_member(attr->attnum - FirstLowInvalidHeapAttributeNumber, identity_key))
+			continue;
 
-				fsd_populate_via_index(&privdata->change_fsd, relation, keytuple, rd_replidindex);
+		foreach(lc, projection->column_names)
+		{
+			if (strcmp((const char *) lfirst(lc), NameStr(attr->attname)) == 0)
+			{
+				listed = true;
+				break;
 			}
-			else if (change->data.tp.oldtuple != NULL)
-				fsd_populate_from_tuple(&privdata->change_fsd, relation, change->data.tp.oldtuple);
+		}
+		if (listed == projection->exclude)
+			excluded = bms_add_member(excluded, attr->attnum);
+	}
+	bms_free(identity_key);
 
-			fsd_serialize(&privdata->change_fsd, PB3LD_UPD_KEY_FIELDS, privdata->message_buf);
+	pb3ld_relcache_set_excluded_columns(entry, excluded);
+	bms_free(excluded);
+	return entry->excluded_columns;
+}
 
-			pb3ld_wire_message_end(privdata, PB3LD_WMSG_UPDATE);
+/*
+ * Returns the prepared row_filter expression which applies to the relation,
+ * or NULL if there isn't one.  The result is cached in the relation cache
+ * until the relation is invalidated.
+ */
+static PB3LD_Row_Filter_State *
+pb3ld_relation_row_filter(PB3LD_Private *privdata, Relation relation)
+{
+	PB3LD_RelationCacheEntry *entry;
+	const PB3LD_Row_Filter *filter = NULL;
+	char *schema_name;
+	const char *table_name;
+	ListCell *lc;
+
+	if (privdata->row_filters == NIL)
+		return NULL;
+
+	entry = pb3ld_relcache_get(RelationGetRelid(relation));
+	if (entry->row_filter_checked)
+		return entry->row_filter;
+
+	schema_name = get_namespace_name(RelationGetNamespace(relation));
+	table_name = RelationGetRelationName(relation);
+	foreach(lc, privdata->row_filters)
+	{
+		const PB3LD_Row_Filter *f = (const PB3LD_Row_Filter *) lfirst(lc);
+
+		if (pb3ld_table_pattern_matches(&f->table, schema_name, table_name))
+		{
+			filter = f;
 			break;
-		case REORDER_BUFFER_CHANGE_DELETE:
-			if (change->data.tp.newtuple != NULL)
-				elog(ERROR, "newtuple is not NULL in DELETE");
+		}
+	}
+	pfree(schema_name);
+
+	if (filter == NULL)
+		pb3ld_relcache_set_row_filter(entry, NULL);
+	else
+		pb3ld_relcache_set_row_filter(entry,
+									  pb3ld_row_filter_state_create(filter, relation,
+																	pb3ld_relcache_memory_context()));
+	return entry->row_filter;
+}
 
-			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_DELETE);
+/*
+ * Returns whether the tuple has TOASTed values which weren't written to WAL
+ * because an UPDATE didn't change them.
+ */
+static bool
+pb3ld_has_unchanged_toast(Relation relation, ReorderBufferTupleBuf *tuple)
+{
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	int natt;
 
-			pb3_append_varlen_key(privdata->message_buf, PB3LD_DEL_TABLE_DESC);
-			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+	for (natt = 0; natt < tupdesc->natts; natt++)
+	{
+		Form_pg_attribute attr = TupleDescAttr(tupdesc, natt);
+		Datum valdatum;
+		bool isnull;
+
+		if (attr->attisdropped || attr->attlen != -1)
+			continue;
+		valdatum = heap_getattr(&tuple->tuple, natt + 1, tupdesc, &isnull);
+		if (!isnull && VARATT_IS_EXTERNAL_ONDISK(DatumGetPointer(valdatum)))
+			return true;
+	}
+	return false;
+}
 
-			if (change->data.tp.oldtuple != NULL)
-			{
-				fsd_reset(&privdata->change_fsd);
+/*
+ * Evaluates the row filter against the change, and decides what to send for
+ * it the same way publication row filters do: an UPDATE which moves a row into
+ * the filter is sent as an INSERT, and one which moves it out of the filter as
+ * a DELETE.  Returns false if nothing should be sent for the change.
+ *
+ * The old version of the row is only known with REPLICA IDENTITY FULL, or if
+ * the expression only references replica identity key columns.  Otherwise
+ * DELETEs are always sent, and UPDATEs are only sent if the new version of the
+ * row matches, and always as UPDATEs.  An UPDATE which would become an INSERT
+ * is also sent as is if some of its TOASTed values are unchanged and the old
+ * tuple can't supply them, since the INSERT would be missing those columns.
+ */
+static bool
+pb3ld_apply_row_filter(PB3LD_Row_Filter_State *row_filter, Relation relation,
+					   char relreplident, ReorderBufferChange *change,
+					   ReorderBufferChangeType *action)
+{
+	ReorderBufferTupleBuf *oldtuple = change->data.tp.oldtuple;
+	ReorderBufferTupleBuf *newtuple = change->data.tp.newtuple;
+	PB3LD_Row_Filter_Result old_result;
+	PB3LD_Row_Filter_Result new_result;
+	bool old_row_known;
 
-				if (OidIsValid(rd_replidindex))
+	if (relreplident == REPLICA_IDENTITY_FULL)
+		old_row_known = oldtuple != NULL;
+	else
+		old_row_known = pb3ld_row_filter_is_key_only(row_filter);
+
+	switch (change->action)
+	{
+		case REORDER_BUFFER_CHANGE_INSERT:
+			new_result = pb3ld_row_filter_evaluate(row_filter, relation, newtuple, NULL);
+			return new_result != PB3LD_ROW_FILTER_NO_MATCH;
+		case REORDER_BUFFER_CHANGE_DELETE:
+			if (!old_row_known || oldtuple == NULL)
+				return true;
+			old_result = pb3ld_row_filter_evaluate(row_filter, relation, oldtuple, NULL);
+			return old_result != PB3LD_ROW_FILTER_NO_MATCH;
+		case REORDER_BUFFER_CHANGE_UPDATE:
+			break;
+		default:
+			/* complained about by the caller */
+			return true;
+	}
+
+	new_result = pb3ld_row_filter_evaluate(row_filter, relation, newtuple,
+										   relreplident == REPLICA_IDENTITY_FULL ? oldtuple : NULL);
+	if (new_result == PB3LD_ROW_FILTER_UNKNOWN)
+		return true;
+	if (!old_row_known)
+		return new_result == PB3LD_ROW_FILTER_MATCH;
+
+	/* unless the identity is FULL, the old key is only logged if it changed */
+	old_result = pb3ld_row_filter_evaluate(row_filter, relation,
+										   oldtuple != NULL ? oldtuple : newtuple, NULL);
+	if (old_result == PB3LD_ROW_FILTER_UNKNOWN)
+		return new_result == PB3LD_ROW_FILTER_MATCH;
+
+	if (old_result == PB3LD_ROW_FILTER_MATCH && new_result == PB3LD_ROW_FILTER_MATCH)
+		return true;
+	else if (old_result == PB3LD_ROW_FILTER_MATCH)
+		*action = REORDER_BUFFER_CHANGE_DELETE;
+	else if (new_result == PB3LD_ROW_FILTER_MATCH)
+	{
+		if (relreplident != REPLICA_IDENTITY_FULL &&
+			pb3ld_has_unchanged_toast(relation, newtuple))
+			return true;
+		*action = REORDER_BUFFER_CHANGE_INSERT;
+	}
+	else
+		return false;
+	return true;
+}
+
+/*
+ * Returns a copy of newtuple with its unchanged TOASTed values taken from
+ * oldtuple, or newtuple itself if it doesn't have any.  Used when an UPDATE is
+ * sent as an INSERT, since the receiver doesn't have the previous version of
+ * the row to take the values from.
+ */
+static ReorderBufferTupleBuf *
+pb3ld_fill_unchanged_toast(Relation relation, ReorderBufferTupleBuf *newtuple,
+						   ReorderBufferTupleBuf *oldtuple)
+{
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	ReorderBufferTupleBuf *result;
+	HeapTuple heaptuple;
+	Datum *values;
+	bool *nulls;
+	Datum *old_values = NULL;
+	bool *old_nulls = NULL;
+	int natt;
+
+	values = palloc(tupdesc->natts * sizeof(Datum));
+	nulls = palloc(tupdesc->natts * sizeof(bool));
+	heap_deform_tuple(&newtuple->tuple, tupdesc, values, nulls);
+
+	for (natt = 0; natt < tupdesc->natts; natt++)
+	{
+		Form_pg_attribute attr = TupleDescAttr(tupdesc, natt);
+
+		if (attr->attisdropped || attr->attlen != -1 || nulls[natt])
+			continue;
+		if (!VARATT_IS_EXTERNAL_ONDISK(DatumGetPointer(values[natt])))
+			continue;
+
+		if (old_values == NULL)
+		{
+			old_values = palloc(tupdesc->natts * sizeof(Datum));
+			old_nulls = palloc(tupdesc->natts * sizeof(bool));
+			heap_deform_tuple(&oldtuple->tuple, tupdesc, old_values, old_nulls);
+		}
+		values[natt] = old_values[natt];
+		nulls[natt] = old_nulls[natt];
+	}
+
+	if (old_values == NULL)
+		return newtuple;
+
+	heaptuple = heap_form_tuple(tupdesc, values, nulls);
+	result = palloc0(sizeof(ReorderBufferTupleBuf));
+	result->tuple = *heaptuple;
+	return result;
+}
+
+/*
+ * Sends a RelationDescription for the relation unless one has already been
+ * sent for its current definition.  Must be called before the wire message of
+ * the change referring to the relation is started.
+ */
+static void
+pb3ld_maybe_send_RelationDescription(PB3LD_Private *privdata, Relation relation)
+{
+	PB3LD_RelationCacheEntry *entry;
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	StringInfo out = privdata->message_buf;
+	char relreplident = relation->rd_rel->relreplident;
+	const Bitmapset *excluded_columns;
+	int column_numbers[NUM_MAX_COLUMNS];
+	int num_columns = 0;
+	int natt;
+
+	if (!privdata->relation_messages_enabled)
+		return;
+
+	/*
+	 * A streamed transaction might have changed the relation without having
+	 * committed yet, so in stream blocks the relation is described in every
+	 * block it's used in, and again before it's used outside of one.
+	 */
+	entry = pb3ld_relcache_get(RelationGetRelid(relation));
+	if (privdata->in_stream_block)
+	{
+		if (entry->stream_block_described == privdata->stream_block_number)
+			return;
+	}
+	else if (entry->description_sent)
+		return;
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_RELATION);
+
+	pb3_append_oid_kv(out, PB3LD_REL_RELATION_ID, RelationGetRelid(relation));
+	pb3_append_string_kv(out, PB3LD_REL_SCHEMANAME,
+						 get_namespace_name(RelationGetNamespace(relation)));
+	pb3_append_string_kv(out, PB3LD_REL_TABLENAME, RelationGetRelationName(relation));
+
+	excluded_columns = pb3ld_relation_excluded_columns(privdata, relation);
+
+	/* must match the columns fsd_populate_from_tuple produces */
+	for (natt = 0; natt < tupdesc->natts; natt++)
+	{
+		Form_pg_attribute attr = TupleDescAttr(tupdesc, natt);
+
+		if (attr->attisdropped || attr->attnum < 0 ||
+			bms_is_member(attr->attnum, excluded_columns))
+		{
+			column_numbers[natt] = -1;
+			continue;
+		}
+
+		pb3_append_string_kv(out, PB3LD_REL_COLUMN_NAMES, NameStr(attr->attname));
+		pb3_append_oid_kv(out, PB3LD_REL_TYPE_OIDS, attr->atttypid);
+		pb3_append_int64_kv(out, PB3LD_REL_TYPE_MODIFIERS, (int64) attr->atttypmod);
+		column_numbers[natt] = num_columns++;
+	}
+
+	/* must match the key fields pb3ld_change produces */
+	if (relreplident == REPLICA_IDENTITY_FULL)
+	{
+		for (natt = 0; natt < num_columns; natt++)
+			pb3_append_uint32_kv(out, PB3LD_REL_KEY_COLUMNS, (uint32) natt);
+	}
+	else if (relreplident == REPLICA_IDENTITY_DEFAULT ||
+			 relreplident == REPLICA_IDENTITY_INDEX)
+	{
+		Oid rd_replidindex = pb3ld_replica_identity_index(relation);
+
+		/*
+		 * Without an index there are no key columns.  Even in
+		 * send_full_old_tuple_if_available mode there's no old tuple to send,
+		 * since PostgreSQL doesn't write one to WAL for such tables.
+		 */
+		if (OidIsValid(rd_replidindex))
+		{
+			Relation indexrel;
+
+			indexrel = index_open(rd_replidindex, AccessShareLock);
+			for (natt = 0; natt < indexrel->rd_index->indnatts; natt++)
+			{
+				int relattr = indexrel->rd_index->indkey.values[natt];
+
+				if (relattr <= 0 || column_numbers[relattr - 1] < 0)
+					elog(ERROR, "attribute %d of index %u is dropped or a system column",
+						 natt, rd_replidindex);
+				pb3_append_uint32_kv(out, PB3LD_REL_KEY_COLUMNS,
+									 (uint32) column_numbers[relattr - 1]);
+			}
+			index_close(indexrel, NoLock);
+		}
+	}
+
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_RELATION);
+
+	if (privdata->in_stream_block)
+	{
+		entry->stream_block_described = privdata->stream_block_number;
+		entry->description_sent = false;
+	}
+	else
+		entry->description_sent = true;
+}
+
+/*
+ * Writes the SessionStart message which tells the client the protocol
+ * version in use.  Called by pb3ld_wire_message_begin before the first
+ * message of the session.
+ */
+void
+pb3ld_write_SessionStart(PB3LD_Private *privdata)
+{
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_SESSION_START);
+	pb3_append_varint_kv(privdata->message_buf, PB3LD_SS_PROTOCOL_VERSION,
+						 privdata->protocol_version);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_SESSION_START);
+}
+
+static void
+pb3ld_write_TableDescription(const PB3LD_Private *privdata, StringInfo out, Relation relation)
+{
+	Form_pg_class class_form;
+	StringInfoData tmpbuf;
+
+	initStringInfo(&tmpbuf);
+
+	/*
+	 * The client knows everything else about the relation from the
+	 * RelationDescription already.
+	 */
+	if (privdata->relation_messages_enabled)
+	{
+		pb3_append_oid_kv(&tmpbuf, PB3LD_TD_TABLEOID, RelationGetRelid(relation));
+
+		pb3_append_int32(out, (int32) tmpbuf.len);
+		appendBinaryStringInfo(out, tmpbuf.data, tmpbuf.len);
+		return;
+	}
+
+	pb3_append_string_kv(&tmpbuf, PB3LD_TD_SCHEMANAME,
+						 get_namespace_name(get_rel_namespace(RelationGetRelid(relation))));
+	class_form = RelationGetForm(relation);
+	pb3_append_string_kv(&tmpbuf, PB3LD_TD_TABLENAME,
+						 NameStr(class_form->relname));
+
+	if (privdata->table_oids_enabled)
+		pb3_append_oid_kv(&tmpbuf, PB3LD_TD_TABLEOID, RelationGetRelid(relation));
+
+	pb3_append_int32(out, (int32) tmpbuf.len);
+	appendBinaryStringInfo(out, tmpbuf.data, tmpbuf.len);
+}
+
+/*
+ * Returns the oids of the sequences owned by the columns of the relation,
+ * i.e. those of serial and identity columns.
+ */
+static List *
+pb3ld_relation_owned_sequences(Oid relid)
+{
+	PB3LD_RelationCacheEntry *entry = pb3ld_relcache_get(relid);
+
+	if (!entry->sequences_checked)
+	{
+#if PG_VERSION_NUM >= 100000 && PG_VERSION_NUM < 120000
+		List *owned_sequences = getOwnedSequences(relid, 0);
+#else
+		List *owned_sequences = getOwnedSequences(relid);
+#endif
+
+		pb3ld_relcache_set_owned_sequences(entry, owned_sequences);
+		list_free(owned_sequences);
+	}
+	return entry->owned_sequences;
+}
+
+static PB3LD_Pending_Sequences *
+pb3ld_find_pending_sequences(PB3LD_Private *privdata, TransactionId xid)
+{
+	ListCell *lc;
+
+	foreach(lc, privdata->pending_sequences)
+	{
+		PB3LD_Pending_Sequences *pending = lfirst(lc);
+
+		if (pending->xid == xid)
+			return pending;
+	}
+	return NULL;
+}
+
+/*
+ * Remembers that a change to the relation was sent, so that the values of its
+ * sequences are sent at the end of the transaction.  txn is always the
+ * top-level transaction; changes made in subtransactions are decoded as a
+ * part of it.
+ */
+static void
+pb3ld_note_sequences(PB3LD_Private *privdata, ReorderBufferTXN *txn, Relation relation)
+{
+	PB3LD_Pending_Sequences *pending;
+	MemoryContext oldcxt;
+
+	if (!privdata->sequence_messages_enabled)
+		return;
+	if (pb3ld_relation_owned_sequences(RelationGetRelid(relation)) == NIL)
+		return;
+
+	oldcxt = MemoryContextSwitchTo(pb3ld_relcache_memory_context());
+	pending = pb3ld_find_pending_sequences(privdata, txn->xid);
+	if (pending == NULL)
+	{
+		pending = palloc(sizeof(PB3LD_Pending_Sequences));
+		pending->xid = txn->xid;
+		pending->relids = NIL;
+		privdata->pending_sequences = lappend(privdata->pending_sequences, pending);
+	}
+	pending->relids = list_append_unique_oid(pending->relids, RelationGetRelid(relation));
+	MemoryContextSwitchTo(oldcxt);
+}
+
+/*
+ * There's no way for an output plugin to decode changes to sequences, so
+ * before the end of every transaction which changed a relation with owned
+ * sequences, the current values of those sequences are sent instead.  The
+ * values are read from the sequences as they are now, so they're never older
+ * than the transaction, but can be newer.
+ *
+ * The changes of a streamed transaction can only be sent in stream blocks, so
+ * if streamed is set, the values are sent in a stream block of their own.  The
+ * block is only started once there's a value to send.
+ */
+static void
+pb3ld_send_sequences(PB3LD_Private *privdata, ReorderBufferTXN *txn, bool streamed)
+{
+	PB3LD_Pending_Sequences *pending;
+	MemoryContext oldcxt;
+	ListCell *lc;
+
+	pending = pb3ld_find_pending_sequences(privdata, txn->xid);
+	if (pending == NULL)
+		return;
+
+	oldcxt = MemoryContextSwitchTo(privdata->change_context);
+
+	foreach(lc, pending->relids)
+	{
+		ListCell *seqlc;
+
+		foreach(seqlc, pb3ld_relation_owned_sequences(lfirst_oid(lc)))
+			pb3ld_maybe_send_SequenceDescription(privdata, lfirst_oid(seqlc),
+												 streamed ? txn : NULL);
+	}
+#if PG_VERSION_NUM >= 140000
+	if (privdata->in_stream_block)
+		pb3ld_write_StreamStop(privdata, txn);
+#endif
+
+	MemoryContextSwitchTo(oldcxt);
+	MemoryContextReset(privdata->change_context);
+
+	pb3ld_forget_sequences(privdata, txn->xid);
+}
+
+/*
+ * Forgets the relations changed by a transaction without sending their
+ * sequences.
+ */
+static void
+pb3ld_forget_sequences(PB3LD_Private *privdata, TransactionId xid)
+{
+	PB3LD_Pending_Sequences *pending;
+
+	pending = pb3ld_find_pending_sequences(privdata, xid);
+	if (pending == NULL)
+		return;
+
+	privdata->pending_sequences = list_delete_ptr(privdata->pending_sequences, pending);
+	list_free(pending->relids);
+	pfree(pending);
+}
+
+/*
+ * Sends the current value of the sequence unless it's the same as the value
+ * sent last time.
+ */
+static void
+pb3ld_maybe_send_SequenceDescription(PB3LD_Private *privdata, Oid seqid,
+									 ReorderBufferTXN *stream_txn)
+{
+	PB3LD_RelationCacheEntry *entry;
+	Relation seqrel;
+	SMgrRelation smgr;
+	Buffer buf;
+	Page page;
+	ItemId lp;
+	HeapTupleData tuple;
+	int64 last_value;
+	bool is_called;
+	char *schema_name;
+
+	seqrel = RelationIdGetRelation(seqid);
+	if (!RelationIsValid(seqrel))
+		return;
+
+#if PG_VERSION_NUM >= 150000
+	smgr = RelationGetSmgr(seqrel);
+#else
+	RelationOpenSmgr(seqrel);
+	smgr = seqrel->rd_smgr;
+#endif
+	/*
+	 * The relation is looked up with the catalog snapshot of the transaction
+	 * being decoded, so the sequence might have since been dropped or
+	 * rewritten by ALTER SEQUENCE.  The file of a dropped relation is
+	 * truncated right away but only removed at the next checkpoint, so it
+	 * might still exist without any blocks.
+	 */
+	if (!smgrexists(smgr, MAIN_FORKNUM) ||
+		smgrnblocks(smgr, MAIN_FORKNUM) == 0)
+	{
+		RelationClose(seqrel);
+		return;
+	}
+
+	buf = ReadBuffer(seqrel, 0);
+	LockBuffer(buf, BUFFER_LOCK_SHARE);
+	page = BufferGetPage(buf);
+	if (PageIsNew(page) || PageGetMaxOffsetNumber(page) < FirstOffsetNumber)
+	{
+		UnlockReleaseBuffer(buf);
+		RelationClose(seqrel);
+		return;
+	}
+	lp = PageGetItemId(page, FirstOffsetNumber);
+	tuple.t_data = (HeapTupleHeader) PageGetItem(page, lp);
+	tuple.t_len = ItemIdGetLength(lp);
+	{
+#if PG_VERSION_NUM >= 100000
+		Form_pg_sequence_data seq = (Form_pg_sequence_data) GETSTRUCT(&tuple);
+#else
+		Form_pg_sequence seq = (Form_pg_sequence) GETSTRUCT(&tuple);
+#endif
+
+		last_value = seq->last_value;
+		is_called = seq->is_called;
+	}
+	UnlockReleaseBuffer(buf);
+
+	entry = pb3ld_relcache_get(seqid);
+	if (entry->sequence_value_sent &&
+		entry->sequence_last_value == last_value &&
+		entry->sequence_is_called == is_called)
+	{
+		RelationClose(seqrel);
+		return;
+	}
+
+	schema_name = get_namespace_name(RelationGetNamespace(seqrel));
+
+#if PG_VERSION_NUM >= 140000
+	if (stream_txn != NULL && !privdata->in_stream_block)
+		pb3ld_write_StreamStart(privdata, stream_txn);
+#endif
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_SEQUENCE);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_SEQ_SCHEMANAME, schema_name);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_SEQ_SEQUENCENAME,
+						 RelationGetRelationName(seqrel));
+	pb3_append_oid_kv(privdata->message_buf, PB3LD_SEQ_SEQUENCEOID, seqid);
+	pb3_append_int64_kv(privdata->message_buf, PB3LD_SEQ_LAST_VALUE, last_value);
+	if (is_called)
+		pb3_append_varint_kv(privdata->message_buf, PB3LD_SEQ_IS_CALLED, 1);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_SEQUENCE);
+
+	entry->sequence_value_sent = true;
+	entry->sequence_last_value = last_value;
+	entry->sequence_is_called = is_called;
+
+	pfree(schema_name);
+	RelationClose(seqrel);
+}
+
+/*
+ * Changes sent in a stream block carry the xid of the (sub)transaction they
+ * belong to, so that the client can discard them if a subtransaction is
+ * rolled back.
+ */
+static void
+pb3ld_append_stream_xid(PB3LD_Private *privdata, int32 field_number, ReorderBufferTXN *txn)
+{
+	if (privdata->in_stream_block)
+		pb3_append_uint32_kv(privdata->message_buf, field_number, txn->xid);
+}
+
+static void
+pb3ld_change(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+			 Relation relation, ReorderBufferChange *change)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+	char relreplident = relation->rd_rel->relreplident;
+	ReorderBufferChangeType action = change->action;
+	ReorderBufferTupleBuf *oldtuple = change->data.tp.oldtuple;
+	ReorderBufferTupleBuf *newtuple = change->data.tp.newtuple;
+	Oid rd_replidindex = InvalidOid;
+	bool old_tuple_as_key = true;
+	bool send_old_values;
+	PB3LD_Row_Filter_State *row_filter;
+	const Bitmapset *excluded_columns;
+	MemoryContext oldcxt;
+#if PG_VERSION_NUM >= 140000
+	/* the subtransaction the change was made in */
+	ReorderBufferTXN *change_txn = change->txn;
+#else
+	ReorderBufferTXN *change_txn = txn;
+#endif
+
+	switch (change->action)
+	{
+		case REORDER_BUFFER_CHANGE_INSERT:
+			if ((privdata->operations & PB3LD_OP_INSERT) == 0)
+				return;
+			break;
+		case REORDER_BUFFER_CHANGE_UPDATE:
+			if ((privdata->operations & PB3LD_OP_UPDATE) == 0)
+				return;
+			break;
+		case REORDER_BUFFER_CHANGE_DELETE:
+			if ((privdata->operations & PB3LD_OP_DELETE) == 0)
+				return;
+			break;
+		default:
+			/* complained about below */
+			break;
+	}
+
+	if (pb3ld_relation_filtered_out(privdata, relation))
+		return;
+
+	if (relreplident == REPLICA_IDENTITY_NOTHING)
+	{
+		/*
+		 * System catalog and/or whatnot; don't replicate.
+		 */
+		return;
+	}
+	else if (relreplident == REPLICA_IDENTITY_DEFAULT ||
+			 relreplident == REPLICA_IDENTITY_INDEX)
+	{
+		if (change->action == REORDER_BUFFER_CHANGE_UPDATE ||
+			change->action == REORDER_BUFFER_CHANGE_DELETE)
+		{
+			rd_replidindex = pb3ld_replica_identity_index(relation);
+			if (!OidIsValid(rd_replidindex))
+			{
+				switch (privdata->missing_replica_identity)
+				{
+					case PB3LD_MISSING_REPLICA_IDENTITY_ERROR:
+						ereport(ERROR,
+								(errcode(ERRCODE_OBJECT_NOT_IN_PREREQUISITE_STATE),
+								 errmsg("cannot decode %s on table \"%s\" because it does not have a replica identity",
+										change->action == REORDER_BUFFER_CHANGE_UPDATE ? "UPDATE" : "DELETE",
+										quote_qualified_identifier(get_namespace_name(RelationGetNamespace(relation)),
+																   RelationGetRelationName(relation))),
+								 errhint("Add a primary key to the table, change its replica identity with ALTER TABLE ... REPLICA IDENTITY, or use the missing_replica_identity option.")));
+						break;
+					case PB3LD_MISSING_REPLICA_IDENTITY_SKIP:
+						return;
+					case PB3LD_MISSING_REPLICA_IDENTITY_SEND_WITHOUT_KEYS:
+						old_tuple_as_key = false;
+						break;
+					case PB3LD_MISSING_REPLICA_IDENTITY_SEND_FULL_OLD_TUPLE:
+						break;
+				}
+			}
+		}
+	}
+	else if (relreplident != REPLICA_IDENTITY_FULL)
+	{
+		elog(ERROR, "unexpected replica identity %d", relreplident);
+	}
+
+	oldcxt = MemoryContextSwitchTo(privdata->change_context);
+
+	row_filter = pb3ld_relation_row_filter(privdata, relation);
+	if (row_filter != NULL &&
+		!pb3ld_apply_row_filter(row_filter, relation, relreplident, change, &action))
+	{
+		MemoryContextSwitchTo(oldcxt);
+		MemoryContextReset(privdata->change_context);
+		return;
+	}
+
+	if (action != change->action)
+	{
+		Assert(change->action == REORDER_BUFFER_CHANGE_UPDATE);
+
+		if (action == REORDER_BUFFER_CHANGE_INSERT)
+		{
+			if (relreplident == REPLICA_IDENTITY_FULL && oldtuple != NULL)
+				newtuple = pb3ld_fill_unchanged_toast(relation, newtuple, oldtuple);
+			oldtuple = NULL;
+		}
+		else
+		{
+			/* unless the identity is FULL, the old key is only logged if it changed */
+			if (oldtuple == NULL)
+				oldtuple = newtuple;
+			newtuple = NULL;
+		}
+	}
+
+	/*
+	 * With identities other than FULL the old tuple only contains the key
+	 * columns, and the rest of them are NULL.  Don't pretend that's the old
+	 * version of the row.
+	 */
+	send_old_values = privdata->old_values_enabled &&
+		relreplident == REPLICA_IDENTITY_FULL &&
+		oldtuple != NULL;
+
+	pb3ld_maybe_send_RelationDescription(privdata, relation);
+	excluded_columns = pb3ld_relation_excluded_columns(privdata, relation);
+
+	switch (action)
+	{
+		case REORDER_BUFFER_CHANGE_INSERT:
+			Assert(newtuple != NULL);
+
+			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_INSERT);
+
+			pb3_append_varlen_key(privdata->message_buf, PB3LD_INS_TABLE_DESC);
+			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+
+			fsd_reset(&privdata->change_fsd);
+			fsd_populate_from_tuple(&privdata->change_fsd, relation,
+									newtuple, excluded_columns);
+			fsd_serialize(&privdata->change_fsd, PB3LD_INS_NEW_VALUES, privdata->message_buf);
+
+			pb3ld_append_stream_xid(privdata, PB3LD_INS_STREAM_XID, change_txn);
+			pb3ld_wire_message_end(privdata, PB3LD_WMSG_INSERT);
+
+			if (oldtuple != NULL)
+				elog(ERROR, "oldtuple is not NULL in INSERT");
+			break;
+		case REORDER_BUFFER_CHANGE_UPDATE:
+			Assert(newtuple != NULL);
+
+			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_UPDATE);
+
+			pb3_append_varlen_key(privdata->message_buf, PB3LD_UPD_TABLE_DESC);
+			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+
+			fsd_reset(&privdata->change_fsd);
+			fsd_populate_from_tuple(&privdata->change_fsd, relation,
+									newtuple, excluded_columns);
+			fsd_serialize(&privdata->change_fsd, PB3LD_UPD_NEW_VALUES, privdata->message_buf);
+
+			fsd_reset(&privdata->change_fsd);
+			if (OidIsValid(rd_replidindex))
+			{
+				ReorderBufferTupleBuf *keytuple;
+
+				if (oldtuple != NULL)
+					keytuple = oldtuple;
+				else
+					keytuple = newtuple;
+
+				fsd_populate_via_index(&privdata->change_fsd, relation, keytuple, rd_replidindex);
+			}
+			else if (oldtuple != NULL && old_tuple_as_key)
+				fsd_populate_from_tuple(&privdata->change_fsd, relation,
+										oldtuple, excluded_columns);
+
+			fsd_serialize(&privdata->change_fsd, PB3LD_UPD_KEY_FIELDS, privdata->message_buf);
+
+			if (send_old_values)
+			{
+				fsd_reset(&privdata->change_fsd);
+				fsd_populate_from_tuple(&privdata->change_fsd, relation,
+										oldtuple, excluded_columns);
+				fsd_serialize(&privdata->change_fsd, PB3LD_UPD_OLD_VALUES, privdata->message_buf);
+			}
+
+			pb3ld_append_stream_xid(privdata, PB3LD_UPD_STREAM_XID, change_txn);
+			pb3ld_wire_message_end(privdata, PB3LD_WMSG_UPDATE);
+			break;
+		case REORDER_BUFFER_CHANGE_DELETE:
+			if (newtuple != NULL)
+				elog(ERROR, "newtuple is not NULL in DELETE");
+
+			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_DELETE);
+
+			pb3_append_varlen_key(privdata->message_buf, PB3LD_DEL_TABLE_DESC);
+			pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+
+			if (oldtuple != NULL &&
+				(OidIsValid(rd_replidindex) || old_tuple_as_key))
+			{
+				fsd_reset(&privdata->change_fsd);
+
+				if (OidIsValid(rd_replidindex))
 					fsd_populate_via_index(&privdata->change_fsd, relation,
-										   change->data.tp.oldtuple, rd_replidindex);
+										   oldtuple, rd_replidindex);
 				else
-					fsd_populate_from_tuple(&privdata->change_fsd, relation, change->data.tp.newtuple);
+					fsd_populate_from_tuple(&privdata->change_fsd, relation,
+											oldtuple, excluded_columns);
 
 				fsd_serialize(&privdata->change_fsd, PB3LD_DEL_KEY_FIELDS, privdata->message_buf);
 			}
 
+			if (send_old_values)
+			{
+				fsd_reset(&privdata->change_fsd);
+				fsd_populate_from_tuple(&privdata->change_fsd, relation,
+										oldtuple, excluded_columns);
+				fsd_serialize(&privdata->change_fsd, PB3LD_DEL_OLD_VALUES, privdata->message_buf);
+			}
+
+			pb3ld_append_stream_xid(privdata, PB3LD_DEL_STREAM_XID, change_txn);
 			pb3ld_wire_message_end(privdata, PB3LD_WMSG_DELETE);
 			break;
 		default:
-			elog(ERROR, "unexpected change action %d", change->action);
+			elog(ERROR, "unexpected change action %d", action);
 			break;
 	}
 
+	pb3ld_note_sequences(privdata, txn, relation);
+
 	if (pb3ld_should_flush_message_buffer(privdata))
 	{
 		OutputPluginPrepareWrite(ctx, true);
@@ -425,3 +1821,372 @@ pb3ld_change(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
 	MemoryContextReset(privdata->change_context);
 	fsd_reset(&privdata->change_fsd);
 }
+
+static bool
+pb3ld_logical_message_prefix_allowed(const PB3LD_Private *privdata, const char *prefix)
+{
+	ListCell *lc;
+
+	if (privdata->logical_message_prefixes == NIL)
+		return true;
+
+	foreach(lc, privdata->logical_message_prefixes)
+	{
+		if (strcmp((const char *) lfirst(lc), prefix) == 0)
+			return true;
+	}
+	return false;
+}
+
+/*
+ * Transactional messages are sent as part of their transaction just like any
+ * other change.  Non-transactional messages are decoded immediately, i.e.
+ * never while a transaction is being sent, and are sent in a wire message of
+ * their own.
+ */
+static void
+pb3ld_message(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+			  XLogRecPtr message_lsn, bool transactional,
+			  const char *prefix, Size message_size,
+			  const char *message)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	if (!privdata->logical_messages_enabled)
+		return;
+	if (!pb3ld_logical_message_prefix_allowed(privdata, prefix))
+		return;
+
+	if (!transactional)
+	{
+		Assert(privdata->header_buf->len == 0);
+		Assert(privdata->message_buf->len == 0);
+	}
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_MESSAGE);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_MSG_PREFIX, prefix);
+	pb3_append_bytes_kv(privdata->message_buf, PB3LD_MSG_CONTENT, message, (int) message_size);
+	if (transactional)
+		pb3_append_varint_kv(privdata->message_buf, PB3LD_MSG_TRANSACTIONAL, 1);
+	pb3_append_uint64_kv(privdata->message_buf, PB3LD_MSG_LSN, message_lsn);
+	/* the server doesn't tell us which subtransaction the message is from */
+	if (transactional)
+		pb3ld_append_stream_xid(privdata, PB3LD_MSG_STREAM_XID, txn);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_MESSAGE);
+
+	if (!transactional || pb3ld_should_flush_message_buffer(privdata))
+	{
+		OutputPluginPrepareWrite(ctx, true);
+		pb3ld_flush_message_buffer(privdata, ctx->out);
+		OutputPluginWrite(ctx, true);
+	}
+}
+
+#if PG_VERSION_NUM >= 110000
+static void
+pb3ld_truncate(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+			   int nrelations, Relation relations[],
+			   ReorderBufferChange *change)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+	MemoryContext oldcxt;
+	int num_tables = 0;
+	int i;
+#if PG_VERSION_NUM >= 140000
+	ReorderBufferTXN *change_txn = change->txn;
+#else
+	ReorderBufferTXN *change_txn = txn;
+#endif
+
+	if (!privdata->truncate_messages_enabled ||
+		(privdata->operations & PB3LD_OP_TRUNCATE) == 0)
+		return;
+
+	oldcxt = MemoryContextSwitchTo(privdata->change_context);
+
+	for (i = 0; i < nrelations; i++)
+	{
+		if (relations[i]->rd_rel->relreplident != REPLICA_IDENTITY_NOTHING &&
+			!pb3ld_relation_filtered_out(privdata, relations[i]))
+			pb3ld_maybe_send_RelationDescription(privdata, relations[i]);
+	}
+
+	for (i = 0; i < nrelations; i++)
+	{
+		Relation relation = relations[i];
+
+		/* skip the same relations pb3ld_change would */
+		if (relation->rd_rel->relreplident == REPLICA_IDENTITY_NOTHING ||
+			pb3ld_relation_filtered_out(privdata, relation))
+			continue;
+
+		if (num_tables == 0)
+			pb3ld_wire_message_begin(privdata, PB3LD_WMSG_TRUNCATE);
+
+		pb3_append_varlen_key(privdata->message_buf, PB3LD_TRUNC_TABLES);
+		pb3ld_write_TableDescription(privdata, privdata->message_buf, relation);
+		num_tables++;
+
+		/* RESTART IDENTITY resets the sequences */
+		pb3ld_note_sequences(privdata, txn, relation);
+	}
+
+	if (num_tables > 0)
+	{
+		if (change->data.truncate.cascade)
+			pb3_append_varint_kv(privdata->message_buf, PB3LD_TRUNC_CASCADE, 1);
+		if (change->data.truncate.restart_seqs)
+			pb3_append_varint_kv(privdata->message_buf, PB3LD_TRUNC_RESTART_IDENTITY, 1);
+		pb3ld_append_stream_xid(privdata, PB3LD_TRUNC_STREAM_XID, change_txn);
+
+		pb3ld_wire_message_end(privdata, PB3LD_WMSG_TRUNCATE);
+
+		if (pb3ld_should_flush_message_buffer(privdata))
+		{
+			OutputPluginPrepareWrite(ctx, true);
+			pb3ld_flush_message_buffer(privdata, ctx->out);
+			OutputPluginWrite(ctx, true);
+		}
+	}
+
+	MemoryContextSwitchTo(oldcxt);
+	MemoryContextReset(privdata->change_context);
+}
+#endif
+
+#if PG_VERSION_NUM >= 140000
+/*
+ * Once a transaction has used more than logical_decoding_work_mem, the server
+ * sends the changes decoded so far in a block delimited by calls to
+ * pb3ld_stream_start and pb3ld_stream_stop, possibly many times over, and
+ * finally calls either pb3ld_stream_commit or pb3ld_stream_abort.  Blocks of
+ * different transactions can be interleaved with each other and with ordinary
+ * transactions, but never nested.  The client has to hold on to the changes
+ * until it knows whether the transaction committed.
+ */
+static void
+pb3ld_stream_start(LogicalDecodingContext *ctx, ReorderBufferTXN *txn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	Assert(privdata->header_buf->len == 0);
+	Assert(privdata->message_buf->len == 0);
+
+	pb3ld_write_StreamStart(privdata, txn);
+}
+
+static void
+pb3ld_stream_stop(LogicalDecodingContext *ctx, ReorderBufferTXN *txn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	pb3ld_write_StreamStop(privdata, txn);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+
+static void
+pb3ld_write_StreamStart(PB3LD_Private *privdata, ReorderBufferTXN *txn)
+{
+	Assert(!privdata->in_stream_block);
+
+	privdata->in_stream_block = true;
+	privdata->stream_block_number++;
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_STREAM_START);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_SSTART_XID, txn->xid);
+	if (!rbtxn_is_streamed(txn))
+		pb3_append_varint_kv(privdata->message_buf, PB3LD_SSTART_FIRST_SEGMENT, 1);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_STREAM_START);
+}
+
+static void
+pb3ld_write_StreamStop(PB3LD_Private *privdata, ReorderBufferTXN *txn)
+{
+	Assert(privdata->in_stream_block);
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_STREAM_STOP);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_SSTOP_XID, txn->xid);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_STREAM_STOP);
+
+	privdata->in_stream_block = false;
+}
+
+/*
+ * Called when a streamed transaction or one of its subtransactions rolls back.
+ * For a subtransaction, only the changes carrying its xid are discarded.
+ */
+static void
+pb3ld_stream_abort(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+				   XLogRecPtr abort_lsn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+	ReorderBufferTXN *toptxn = txn->toptxn != NULL ? txn->toptxn : txn;
+
+	Assert(!privdata->in_stream_block);
+
+	/*
+	 * The sequences of a rolled back subtransaction are still sent with the
+	 * top-level transaction, which is harmless since their values can be
+	 * ahead of the transaction anyway.
+	 */
+	if (txn->toptxn == NULL)
+		pb3ld_forget_sequences(privdata, txn->xid);
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_STREAM_ABORT);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_SABORT_XID, toptxn->xid);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_SABORT_SUBXID, txn->xid);
+	pb3_append_uint64_kv(privdata->message_buf, PB3LD_SABORT_ABORT_LSN, abort_lsn);
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_STREAM_ABORT);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+
+/*
+ * Sent regardless of enable_commit_messages, since the client can't tell when
+ * to apply the streamed changes without it.
+ */
+static void
+pb3ld_stream_commit(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+					XLogRecPtr commit_lsn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	Assert(!privdata->in_stream_block);
+
+	pb3ld_send_sequences(privdata, txn, true);
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_STREAM_COMMIT);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_SCOMMIT_XID, txn->xid);
+	if (privdata->transaction_metadata_enabled)
+	{
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_SCOMMIT_COMMIT_LSN, commit_lsn);
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_SCOMMIT_END_LSN, txn->end_lsn);
+		pb3_append_int64_kv(privdata->message_buf, PB3LD_SCOMMIT_COMMIT_TIME,
+							pb3ld_commit_time(txn));
+	}
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_STREAM_COMMIT);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+
+/*
+ * If two_phase is enabled, a transaction which is prepared is sent when it's
+ * prepared instead of when it commits.  It starts with the begin callback
+ * like any other transaction, but ends with a PrepareTransaction instead of
+ * a CommitTransaction.  Its outcome is sent later in a CommitPrepared or a
+ * RollbackPrepared of its own, possibly in a different session.
+ */
+static void
+pb3ld_prepare_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+				  XLogRecPtr prepare_lsn)
+{
+	pb3ld_send_sequences(ctx->output_plugin_private, txn, false);
+	pb3ld_write_prepare(ctx, txn, prepare_lsn, PB3LD_WMSG_PREPARE);
+}
+
+/*
+ * Called in place of pb3ld_stream_commit when a streamed transaction is
+ * prepared.
+ */
+static void
+pb3ld_stream_prepare(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+					 XLogRecPtr prepare_lsn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	Assert(!privdata->in_stream_block);
+
+	pb3ld_send_sequences(privdata, txn, true);
+	pb3ld_write_prepare(ctx, txn, prepare_lsn, PB3LD_WMSG_STREAM_PREPARE);
+}
+
+/*
+ * Unlike a CommitTransaction, the message is sent even if the transaction had
+ * no decoded changes and regardless of enable_commit_messages, since the
+ * client has to be able to match the CommitPrepared or RollbackPrepared which
+ * follows with it.
+ */
+static void
+pb3ld_write_prepare(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+					XLogRecPtr prepare_lsn, int32 msgtype)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	pb3ld_wire_message_begin(privdata, msgtype);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_PREPARE_XID, txn->xid);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_PREPARE_GID, txn->gid);
+	if (privdata->transaction_metadata_enabled)
+	{
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_PREPARE_PREPARE_LSN, prepare_lsn);
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_PREPARE_END_LSN, txn->end_lsn);
+		pb3_append_int64_kv(privdata->message_buf, PB3LD_PREPARE_PREPARE_TIME,
+							pb3ld_commit_time(txn));
+	}
+	pb3ld_wire_message_end(privdata, msgtype);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+
+static void
+pb3ld_commit_prepared_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+						  XLogRecPtr commit_lsn)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	Assert(privdata->header_buf->len == 0);
+	Assert(privdata->message_buf->len == 0);
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_COMMIT_PREPARED);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_CPREP_XID, txn->xid);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_CPREP_GID, txn->gid);
+	if (privdata->transaction_metadata_enabled)
+	{
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_CPREP_COMMIT_LSN, commit_lsn);
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_CPREP_END_LSN, txn->end_lsn);
+		pb3_append_int64_kv(privdata->message_buf, PB3LD_CPREP_COMMIT_TIME,
+							pb3ld_commit_time(txn));
+	}
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_COMMIT_PREPARED);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+
+static void
+pb3ld_rollback_prepared_txn(LogicalDecodingContext *ctx, ReorderBufferTXN *txn,
+							XLogRecPtr prepare_end_lsn, TimestampTz prepare_time)
+{
+	PB3LD_Private *privdata = ctx->output_plugin_private;
+
+	Assert(privdata->header_buf->len == 0);
+	Assert(privdata->message_buf->len == 0);
+
+	pb3ld_wire_message_begin(privdata, PB3LD_WMSG_ROLLBACK_PREPARED);
+	pb3_append_uint32_kv(privdata->message_buf, PB3LD_RPREP_XID, txn->xid);
+	pb3_append_string_kv(privdata->message_buf, PB3LD_RPREP_GID, txn->gid);
+	if (privdata->transaction_metadata_enabled)
+	{
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_RPREP_PREPARE_END_LSN, prepare_end_lsn);
+		pb3_append_uint64_kv(privdata->message_buf, PB3LD_RPREP_END_LSN, txn->end_lsn);
+		pb3_append_int64_kv(privdata->message_buf, PB3LD_RPREP_PREPARE_TIME,
+							pb3ld_unix_time(prepare_time));
+		pb3_append_int64_kv(privdata->message_buf, PB3LD_RPREP_ROLLBACK_TIME,
+							pb3ld_commit_time(txn));
+	}
+	pb3ld_wire_message_end(privdata, PB3LD_WMSG_ROLLBACK_PREPARED);
+
+	OutputPluginPrepareWrite(ctx, true);
+	pb3ld_flush_message_buffer(privdata, ctx->out);
+	OutputPluginWrite(ctx, true);
+}
+#endif
@@ -5,6 +5,8 @@
 
 #include "access/htup_details.h"
 #include "lib/stringinfo.h"
+#include "nodes/bitmapset.h"
+#include "nodes/pg_list.h"
 #include "replication/output_plugin.h"
 
 #define NUM_MAX_COLUMNS (MaxHeapAttributeNumber + 1)
@@ -18,38 +20,179 @@ typedef struct {
 	Oid max;
 } PB3LD_Oid_Range;
 
+typedef struct {
+	/* for error messages */
+	const char *option_name;
+	const char *pattern;
+
+	const char *schema_pattern;
+	const char *table_pattern;
+} PB3LD_Table_Pattern;
+
+typedef struct {
+	PB3LD_Table_Pattern table;
+	/* if set, column_names are excluded, and all other columns included */
+	bool exclude;
+	List *column_names;
+} PB3LD_Column_Projection;
+
 extern PB3LD_Oid_Range *pb3ld_parse_binary_oid_ranges(const char *input);
+extern List *pb3ld_parse_logical_message_prefixes(const char *input);
+extern List *pb3ld_parse_origin_names(const char *input);
+extern int32 pb3ld_parse_protocol_version(const char *input);
+extern int pb3ld_parse_compression(const char *input);
+extern void pb3ld_parse_table_pattern(PB3LD_Table_Pattern *pattern);
+extern List *pb3ld_parse_table_patterns(const char *input, const char *option_name);
+extern List *pb3ld_parse_column_projections(const char *input);
+
+/* bits of the operations bitmask */
+#define PB3LD_OP_INSERT		0x01
+#define PB3LD_OP_UPDATE		0x02
+#define PB3LD_OP_DELETE		0x04
+#define PB3LD_OP_TRUNCATE	0x08
+#define PB3LD_OP_ALL		(PB3LD_OP_INSERT | PB3LD_OP_UPDATE | PB3LD_OP_DELETE | PB3LD_OP_TRUNCATE)
+
+extern int pb3ld_parse_operations(const char *input);
+extern bool pb3ld_table_pattern_matches(const PB3LD_Table_Pattern *pattern,
+										const char *schema_name, const char *table_name);
+extern bool pb3ld_table_patterns_match(List *patterns, const char *schema_name, const char *table_name);
+extern int pb3ld_message_buffer_alloc_size(const struct PB3LD_Private *privdata);
+extern void pb3ld_write_SessionStart(struct PB3LD_Private *privdata);
 extern void pb3ld_wire_message_begin(struct PB3LD_Private *privdata, int32 msgtype);
 extern void pb3ld_wire_message_end(struct PB3LD_Private *privdata, int32 msgtype);
 extern bool pb3ld_should_flush_message_buffer(struct PB3LD_Private *privdata);
 extern void pb3ld_flush_message_buffer(struct PB3LD_Private *privdata, StringInfo out);
 
+/* rowfilter.c */
+
+typedef struct {
+	PB3LD_Table_Pattern table;
+
+	/* the value of the option, for error messages */
+	const char *input;
+	/* the query the expression was parsed as a part of */
+	const char *query;
+	/* the raw parse tree of the expression */
+	Node *expression;
+} PB3LD_Row_Filter;
+
+typedef enum {
+	PB3LD_ROW_FILTER_NO_MATCH,
+	PB3LD_ROW_FILTER_MATCH,
+	/* the expression couldn't be evaluated against the tuple */
+	PB3LD_ROW_FILTER_UNKNOWN,
+} PB3LD_Row_Filter_Result;
+
+typedef struct PB3LD_Row_Filter_State PB3LD_Row_Filter_State;
+
+extern PB3LD_Row_Filter *pb3ld_parse_row_filter(const char *input);
+extern PB3LD_Row_Filter_State *pb3ld_row_filter_state_create(const PB3LD_Row_Filter *filter,
+															 Relation relation,
+															 MemoryContext parent);
+extern void pb3ld_row_filter_state_free(PB3LD_Row_Filter_State *state);
+extern bool pb3ld_row_filter_is_key_only(const PB3LD_Row_Filter_State *state);
+extern PB3LD_Row_Filter_Result pb3ld_row_filter_evaluate(PB3LD_Row_Filter_State *state,
+														 Relation relation,
+														 ReorderBufferTupleBuf *tuple,
+														 ReorderBufferTupleBuf *old_tuple);
+
 /* fsd.c */
 
 typedef struct {
 	const struct PB3LD_Private *privdata;
 
 	int num_columns;
+	/*
+	 * The number of columns the field set would have if no columns had been
+	 * omitted.  Only used when relation messages are enabled.
+	 */
+	int num_expected_columns;
 
 	const char *names[NUM_MAX_COLUMNS];
+	int column_numbers[NUM_MAX_COLUMNS];
 	const char *values[NUM_MAX_COLUMNS];
 	int value_lengths[NUM_MAX_COLUMNS];
 	Oid type_oids[NUM_MAX_COLUMNS];
 	bool nulls[NUM_MAX_COLUMNS];
 	bool binary_formats[NUM_MAX_COLUMNS];
+	/* only set when unchanged TOASTed values are marked instead of omitted */
+	bool unchanged_toast[NUM_MAX_COLUMNS];
+	bool has_unchanged_toast;
 } PB3LD_FieldSetDescription;
 
 extern void fsd_init(PB3LD_FieldSetDescription *fsd, const struct PB3LD_Private *privdata);
 extern void fsd_reset(PB3LD_FieldSetDescription *fsd);
 extern void fsd_populate_from_tuple(PB3LD_FieldSetDescription *fds,
 									Relation relation,
-									ReorderBufferTupleBuf *tuple);
+									ReorderBufferTupleBuf *tuple,
+									const Bitmapset *excluded_columns);
 extern void fsd_populate_via_index(PB3LD_FieldSetDescription *fds,
 								   Relation relation,
 								   ReorderBufferTupleBuf *tuple,
 								   Oid rd_replidindex);
 extern void fsd_serialize(PB3LD_FieldSetDescription *fsd, int32 field_number, StringInfo out);
 
+/* relcache.c */
+
+typedef struct {
+	Oid relid;		/* hash key; must be first */
+
+	/* has a RelationDescription been sent for the current version? */
+	bool description_sent;
+
+	/*
+	 * Whether the relation is excluded by include_tables or exclude_tables.
+	 * Only valid if filter_checked is set.
+	 */
+	bool filter_checked;
+	bool filtered_out;
+
+	/*
+	 * The attnums of the columns excluded by the columns option, or NULL if
+	 * none are.  Only valid if projection_checked is set.
+	 */
+	bool projection_checked;
+	Bitmapset *excluded_columns;
+
+	/*
+	 * The prepared row_filter expression for the relation, or NULL if there
+	 * isn't one.  Only valid if row_filter_checked is set.
+	 */
+	bool row_filter_checked;
+	PB3LD_Row_Filter_State *row_filter;
+
+	/*
+	 * The number of the stream block a RelationDescription was last sent in,
+	 * or 0 if none has been.
+	 */
+	uint64 stream_block_described;
+
+	/*
+	 * The oids of the sequences owned by the relation.  Only valid if
+	 * sequences_checked is set.
+	 */
+	bool sequences_checked;
+	List *owned_sequences;
+
+	/*
+	 * For a sequence, the value last sent in a SequenceDescription.  Not
+	 * reset by invalidation, since changes to the value don't cause any.
+	 */
+	bool sequence_value_sent;
+	int64 sequence_last_value;
+	bool sequence_is_called;
+} PB3LD_RelationCacheEntry;
+
+extern void pb3ld_relcache_init(MemoryContext context);
+extern PB3LD_RelationCacheEntry *pb3ld_relcache_get(Oid relid);
+extern void pb3ld_relcache_set_excluded_columns(PB3LD_RelationCacheEntry *entry,
+												const Bitmapset *excluded_columns);
+extern void pb3ld_relcache_set_row_filter(PB3LD_RelationCacheEntry *entry,
+										  PB3LD_Row_Filter_State *row_filter);
+extern void pb3ld_relcache_set_owned_sequences(PB3LD_RelationCacheEntry *entry,
+											   List *owned_sequences);
+extern MemoryContext pb3ld_relcache_memory_context(void);
+
 /* pg_pb3_ld.c */
 
 typedef enum {
@@ -64,12 +207,53 @@ typedef enum {
 	PB3LD_FSD_FORMATS_FULL,
 } PB3LD_FSD_Formats_Mode;
 
+typedef enum {
+	PB3LD_MISSING_REPLICA_IDENTITY_ERROR,
+	PB3LD_MISSING_REPLICA_IDENTITY_SKIP,
+	PB3LD_MISSING_REPLICA_IDENTITY_SEND_WITHOUT_KEYS,
+	PB3LD_MISSING_REPLICA_IDENTITY_SEND_FULL_OLD_TUPLE,
+} PB3LD_Missing_Replica_Identity_Mode;
+
+/*
+ * The versions of the wire protocol the plugin can speak.  Version 1 is the
+ * protocol as it was before versioning; version 2 added the SessionStart
+ * message.
+ */
+#define PB3LD_MIN_PROTOCOL_VERSION				1
+#define PB3LD_MAX_PROTOCOL_VERSION				2
+#define PB3LD_SESSION_START_PROTOCOL_VERSION	2
+
+#define PB3LD_DEFAULT_WIRE_MESSAGE_TARGET_SIZE	(4 * 1024 * 1024)
+#define PB3LD_MIN_WIRE_MESSAGE_TARGET_SIZE		1024
+/* twice this has to fit in a single allocation */
+#define PB3LD_MAX_WIRE_MESSAGE_TARGET_SIZE		(256 * 1024 * 1024)
+
+/* the values match the CompressionMethod enum of the protocol */
+typedef enum {
+	PB3LD_COMPRESSION_NONE = 0,
+	PB3LD_COMPRESSION_PGLZ = 1,
+	PB3LD_COMPRESSION_LZ4 = 2,
+	PB3LD_COMPRESSION_ZSTD = 3,
+} PB3LD_Compression_Method;
+
+typedef enum {
+	PB3LD_ORIGIN_ANY,
+	PB3LD_ORIGIN_NONE,
+	PB3LD_ORIGIN_LIST,
+} PB3LD_Origin_Mode;
+
 typedef struct {
 	char *schema_name;
 	char *table_name;
 	Oid table_oid;
 } PB3LD_TableDescription;
 
+/* the relations with owned sequences changed by a top-level transaction */
+typedef struct {
+	TransactionId xid;
+	List *relids;
+} PB3LD_Pending_Sequences;
+
 typedef struct PB3LD_Private
 {
 	/*
@@ -81,9 +265,15 @@ typedef struct PB3LD_Private
 	/* Pre-allocated memory for the change code to work with. */
 	PB3LD_FieldSetDescription change_fsd;
 
+	/* the version negotiated via the proto_version option */
 	int32	protocol_version;
+	bool	session_start_sent;
 
 	bool	sent_message_this_transaction;
+	/*
+	 * The message buffer is flushed once it's larger than this.  0 means that
+	 * it's flushed after every change.
+	 */
 	int		wire_message_target_size;
 
 	/*
@@ -94,16 +284,86 @@ typedef struct PB3LD_Private
 	StringInfo header_buf;
 	StringInfo message_buf;
 
+	/*
+	 * How the message buffer is compressed when it's flushed.  compress_buf
+	 * is allocated in buf_context on first use.
+	 */
+	PB3LD_Compression_Method compression;
+	StringInfo compress_buf;
+
 	bool	begin_messages_enabled;
 	bool	commit_messages_enabled;
+	bool	transaction_metadata_enabled;
+	bool	truncate_messages_enabled;
+	bool	sequence_messages_enabled;
+	/*
+	 * List of PB3LD_Pending_Sequences for the transactions which haven't
+	 * ended yet.  There can be more than one with streaming, since stream
+	 * blocks of different transactions are interleaved.  Lives in the memory
+	 * context of the relation cache.
+	 */
+	List   *pending_sequences;
+	bool	logical_messages_enabled;
+	/* NIL means that messages with any prefix are sent */
+	List   *logical_message_prefixes;
+
+	/*
+	 * What to do about UPDATEs and DELETEs on tables with REPLICA IDENTITY
+	 * DEFAULT or USING INDEX which don't have an index to use.
+	 */
+	PB3LD_Missing_Replica_Identity_Mode missing_replica_identity;
+	bool	old_values_enabled;
+	bool	unchanged_toast_bitmap_enabled;
+
+	/* lists of PB3LD_Table_Pattern; NIL include_tables means all tables */
+	List   *include_tables;
+	List   *exclude_tables;
 
-	bool	repl_identity_required;
+	/* list of PB3LD_Column_Projection; the first matching one applies */
+	List   *column_projections;
+
+	/* list of PB3LD_Row_Filter; the first matching one applies */
+	List   *row_filters;
+
+	/*
+	 * Which replication origins changes are decoded from.  Changes which
+	 * didn't come from a replication origin are always decoded.  With
+	 * PB3LD_ORIGIN_LIST, origin_ids lists the ids of the allowed origins.
+	 */
+	PB3LD_Origin_Mode origin_mode;
+	List   *origin_ids;
+
+	/*
+	 * If enabled, large transactions are streamed in blocks while they're
+	 * still in progress instead of being sent after they commit.
+	 */
+	bool	streaming_enabled;
+	/* set between the stream start and stop callbacks */
+	bool	in_stream_block;
+	/* incremented by every stream start callback */
+	uint64	stream_block_number;
+
+	/*
+	 * If enabled, prepared transactions are sent at PREPARE TRANSACTION time,
+	 * and their outcome separately.
+	 */
+	bool	two_phase_enabled;
+
+	/* PB3LD_OP_* bits of the operations which are decoded */
+	int		operations;
 
 	PB3LD_FSD_Type_Oids_Mode type_oids_mode;
 	PB3LD_Oid_Range *binary_oid_ranges;
 	PB3LD_FSD_Formats_Mode formats_mode;
 
 	bool	table_oids_enabled;
+
+	/*
+	 * If enabled, a RelationDescription is sent before the first change to
+	 * each relation, and the changes themselves only refer to the relation by
+	 * its oid.
+	 */
+	bool	relation_messages_enabled;
 } PB3LD_Private;
 
 /* protobuf.c */
@@ -116,6 +376,12 @@ extern void pb3_append_varint_kv(StringInfo s, int32 field_number, int32 val);
 
 extern void pb3_append_oid_kv(StringInfo s, int32 field_number, Oid oid);
 
+extern void pb3_append_uint32_kv(StringInfo s, int32 field_number, uint32 val);
+
+extern void pb3_append_uint64_kv(StringInfo s, int32 field_number, uint64 val);
+
+extern void pb3_append_int64_kv(StringInfo s, int32 field_number, int64 val);
+
 extern void pb3_append_enum_kv(StringInfo s, int32 field_number, int32 value);
 
 extern void pb3_append_string_kv(StringInfo s, int32 field_number, const char *str);
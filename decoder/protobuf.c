@@ -29,6 +29,17 @@ pb3_append_uint32(StringInfo s, uint32 val)
 	appendStringInfoCharMacro(s, (char) ((uint8) val));
 }
 
+static void
+pb3_append_uint64(StringInfo s, uint64 val)
+{
+	while (val > 127)
+	{
+		appendStringInfoCharMacro(s, (char) (0x80 | ((uint8) val & 0x7F)));
+		val >>= 7;
+	}
+	appendStringInfoCharMacro(s, (char) ((uint8) val));
+}
+
 void
 pb3_append_wmsg_header(StringInfo s, int32 msgtype)
 {
@@ -49,6 +60,31 @@ pb3_append_oid_kv(StringInfo s, int32 field_number, Oid oid)
 	pb3_append_uint32(s, (uint32) oid);
 }
 
+void
+pb3_append_uint32_kv(StringInfo s, int32 field_number, uint32 val)
+{
+	pb3_append_varint_key(s, field_number);
+	pb3_append_uint32(s, val);
+}
+
+void
+pb3_append_uint64_kv(StringInfo s, int32 field_number, uint64 val)
+{
+	pb3_append_varint_key(s, field_number);
+	pb3_append_uint64(s, val);
+}
+
+/*
+ * Negative values are encoded in ten bytes as per the protobuf spec for int64
+ * (as opposed to sint64) fields.
+ */
+void
+pb3_append_int64_kv(StringInfo s, int32 field_number, int64 val)
+{
+	pb3_append_varint_key(s, field_number);
+	pb3_append_uint64(s, (uint64) val);
+}
+
 void
 pb3_append_enum_kv(StringInfo s, int32 field_number, int32 value)
 {
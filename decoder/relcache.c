@@ -0,0 +1,214 @@
+#include "postgres.h"
+
+#include "utils/hsearch.h"
+#include "utils/inval.h"
+#include "utils/memutils.h"
+#include "utils/syscache.h"
+
+#include "pg_pb3_ld.h"
+
+/*
+ * Per-relation state which needs to survive across changes and transactions.
+ * The cache lives in the decoding context's memory, and is reset via a memory
+ * context callback when the decoding context goes away, regardless of whether
+ * that happens through our shutdown callback or through error cleanup.
+ */
+static HTAB *RelationCache = NULL;
+static MemoryContext RelationCacheContext = NULL;
+static bool relcache_callback_registered = false;
+
+static void pb3ld_relcache_reset(void *arg);
+static void pb3ld_relcache_invalidate(Datum arg, Oid relid);
+#if PG_VERSION_NUM >= 100000
+static void pb3ld_relcache_invalidate_sequences(Datum arg, int cacheid, uint32 hashvalue);
+#endif
+
+void
+pb3ld_relcache_init(MemoryContext context)
+{
+	HASHCTL ctl;
+	MemoryContextCallback *reset_callback;
+
+	Assert(RelationCache == NULL);
+
+	MemSet(&ctl, 0, sizeof(ctl));
+	ctl.keysize = sizeof(Oid);
+	ctl.entrysize = sizeof(PB3LD_RelationCacheEntry);
+	ctl.hcxt = context;
+	RelationCache = hash_create("pg_pb3_ld relation cache", 128, &ctl,
+								HASH_ELEM | HASH_BLOBS | HASH_CONTEXT);
+	RelationCacheContext = context;
+
+	reset_callback = MemoryContextAlloc(context, sizeof(MemoryContextCallback));
+	reset_callback->func = pb3ld_relcache_reset;
+	reset_callback->arg = NULL;
+	MemoryContextRegisterResetCallback(context, reset_callback);
+
+	/* there's no way to unregister the callbacks, so only register them once */
+	if (!relcache_callback_registered)
+	{
+		CacheRegisterRelcacheCallback(pb3ld_relcache_invalidate, (Datum) 0);
+#if PG_VERSION_NUM >= 100000
+		CacheRegisterSyscacheCallback(SEQRELID, pb3ld_relcache_invalidate_sequences, (Datum) 0);
+#endif
+		relcache_callback_registered = true;
+	}
+}
+
+PB3LD_RelationCacheEntry *
+pb3ld_relcache_get(Oid relid)
+{
+	PB3LD_RelationCacheEntry *entry;
+	bool found;
+
+	Assert(RelationCache != NULL);
+
+	entry = (PB3LD_RelationCacheEntry *) hash_search(RelationCache,
+													 (void *) &relid,
+													 HASH_ENTER, &found);
+	if (!found)
+	{
+		entry->description_sent = false;
+		entry->filter_checked = false;
+		entry->projection_checked = false;
+		entry->excluded_columns = NULL;
+		entry->row_filter_checked = false;
+		entry->row_filter = NULL;
+		entry->stream_block_described = 0;
+		entry->sequences_checked = false;
+		entry->owned_sequences = NIL;
+		entry->sequence_value_sent = false;
+	}
+	return entry;
+}
+
+/*
+ * Stores a copy of excluded_columns in the entry.  The previous set isn't
+ * freed by invalidation, since the invalidation might happen while a change
+ * is still using it; it's only freed here.
+ */
+void
+pb3ld_relcache_set_excluded_columns(PB3LD_RelationCacheEntry *entry,
+									const Bitmapset *excluded_columns)
+{
+	MemoryContext oldcxt;
+
+	bms_free(entry->excluded_columns);
+	oldcxt = MemoryContextSwitchTo(RelationCacheContext);
+	entry->excluded_columns = bms_copy(excluded_columns);
+	MemoryContextSwitchTo(oldcxt);
+	entry->projection_checked = true;
+}
+
+/*
+ * Stores row_filter in the entry, which takes ownership of it.  Like with
+ * excluded_columns, the previous state is only freed here.
+ */
+void
+pb3ld_relcache_set_row_filter(PB3LD_RelationCacheEntry *entry,
+							  PB3LD_Row_Filter_State *row_filter)
+{
+	if (entry->row_filter != NULL)
+		pb3ld_row_filter_state_free(entry->row_filter);
+	entry->row_filter = row_filter;
+	entry->row_filter_checked = true;
+}
+
+/*
+ * Stores a copy of owned_sequences in the entry.  Like with excluded_columns,
+ * the previous list is only freed here.
+ */
+void
+pb3ld_relcache_set_owned_sequences(PB3LD_RelationCacheEntry *entry,
+								   List *owned_sequences)
+{
+	MemoryContext oldcxt;
+
+	list_free(entry->owned_sequences);
+	oldcxt = MemoryContextSwitchTo(RelationCacheContext);
+	entry->owned_sequences = list_copy(owned_sequences);
+	MemoryContextSwitchTo(oldcxt);
+	entry->sequences_checked = true;
+}
+
+/*
+ * Returns the memory context the cache lives in, for per-relation state which
+ * needs a context of its own.
+ */
+MemoryContext
+pb3ld_relcache_memory_context(void)
+{
+	Assert(RelationCacheContext != NULL);
+	return RelationCacheContext;
+}
+
+static void
+pb3ld_relcache_reset(void *arg)
+{
+	RelationCache = NULL;
+	RelationCacheContext = NULL;
+}
+
+static void
+pb3ld_relcache_invalidate(Datum arg, Oid relid)
+{
+	PB3LD_RelationCacheEntry *entry;
+
+	if (RelationCache == NULL)
+		return;
+
+	/* InvalidOid means that all relations should be invalidated */
+	if (!OidIsValid(relid))
+	{
+		HASH_SEQ_STATUS status;
+
+		hash_seq_init(&status, RelationCache);
+		while ((entry = (PB3LD_RelationCacheEntry *) hash_seq_search(&status)) != NULL)
+		{
+			entry->description_sent = false;
+			entry->filter_checked = false;
+			entry->projection_checked = false;
+			entry->row_filter_checked = false;
+			entry->stream_block_described = 0;
+			entry->sequences_checked = false;
+		}
+		return;
+	}
+
+	entry = (PB3LD_RelationCacheEntry *) hash_search(RelationCache,
+													 (void *) &relid,
+													 HASH_FIND, NULL);
+	if (entry != NULL)
+	{
+		entry->description_sent = false;
+		entry->stream_block_described = 0;
+		/* the relation might have been renamed */
+		entry->filter_checked = false;
+		entry->projection_checked = false;
+		entry->row_filter_checked = false;
+		/* a serial column might have been added or dropped */
+		entry->sequences_checked = false;
+	}
+}
+
+#if PG_VERSION_NUM >= 100000
+/*
+ * ALTER SEQUENCE ... OWNED BY only changes pg_depend, which doesn't cause a
+ * relcache invalidation of either the old or the new owner.  It always
+ * updates the pg_sequence row of the sequence though, so forget the owned
+ * sequences of all relations when that happens.
+ */
+static void
+pb3ld_relcache_invalidate_sequences(Datum arg, int cacheid, uint32 hashvalue)
+{
+	PB3LD_RelationCacheEntry *entry;
+	HASH_SEQ_STATUS status;
+
+	if (RelationCache == NULL)
+		return;
+
+	hash_seq_init(&status, RelationCache);
+	while ((entry = (PB3LD_RelationCacheEntry *) hash_seq_search(&status)) != NULL)
+		entry->sequences_checked = false;
+}
+#endif
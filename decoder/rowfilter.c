@@ -0,0 +1,453 @@
+#include "postgres.h"
+
+#include <ctype.h>
+
+#include "access/sysattr.h"
+#include "access/transam.h"
+#include "executor/executor.h"
+#include "nodes/nodeFuncs.h"
+#include "parser/parser.h"
+#include "utils/builtins.h"
+#include "utils/lsyscache.h"
+#include "utils/memutils.h"
+#include "utils/rel.h"
+#include "utils/relcache.h"
+
+#if PG_VERSION_NUM >= 120000
+#include "catalog/heap.h"
+#include "nodes/makefuncs.h"
+#include "optimizer/optimizer.h"
+#include "parser/parse_clause.h"
+#include "parser/parse_collate.h"
+#include "parser/parse_node.h"
+#include "rewrite/rewriteManip.h"
+#endif
+
+#include "pg_pb3_ld.h"
+
+/*
+ * The expression of a row filter is parsed by wrapping it in a query of this
+ * form, and then checking that nothing but the WHERE clause was filled in.
+ */
+#define PB3LD_ROW_FILTER_QUERY_PREFIX "SELECT 1 WHERE "
+
+struct PB3LD_Row_Filter_State
+{
+	/* everything below is allocated in this context */
+	MemoryContext context;
+
+	EState *estate;
+	ExprState *exprstate;
+	TupleTableSlot *slot;
+
+	/* attnums of the columns the expression references, offset like pull_varattnos() */
+	Bitmapset *attnums;
+	/* does the expression only reference replica identity key columns? */
+	bool key_only;
+};
+
+typedef struct {
+	const PB3LD_Row_Filter *filter;
+	/* NULL while the option is being parsed */
+	const char *relation_name;
+} PB3LD_Row_Filter_Error_Context;
+
+static void
+pb3ld_row_filter_error_callback(void *arg)
+{
+	const PB3LD_Row_Filter_Error_Context *context = (const PB3LD_Row_Filter_Error_Context *) arg;
+	int syntaxerrposition;
+
+	/*
+	 * Any error position refers to the query we wrapped the expression in,
+	 * not to the one the client is running, so report it as an internal one.
+	 */
+	syntaxerrposition = geterrposition();
+	if (syntaxerrposition > 0)
+	{
+		errposition(0);
+		internalerrposition(syntaxerrposition);
+		internalerrquery(context->filter->query);
+	}
+
+	if (context->relation_name == NULL)
+		errcontext("while parsing row_filter \"%s\"", context->filter->input);
+	else
+		errcontext("while preparing row_filter \"%s\" for table \"%s\"",
+				   context->filter->input, context->relation_name);
+}
+
+/*
+ * Parses the value of a row_filter option, e.g. "public.orders: status <>
+ * 'draft'".  Only the syntax of the expression is checked here; it's resolved
+ * against the table when the first change to it is decoded, see
+ * pb3ld_row_filter_state_create.
+ */
+PB3LD_Row_Filter *
+pb3ld_parse_row_filter(const char *input)
+{
+	PB3LD_Row_Filter *filter;
+	PB3LD_Row_Filter_Error_Context context;
+	ErrorContextCallback sqlerrcontext;
+	const char *colon;
+	const char *start;
+	const char *end;
+	const char *expression;
+	List *parsetree;
+	SelectStmt *stmt;
+
+	filter = palloc0(sizeof(PB3LD_Row_Filter));
+	filter->input = pstrdup(input);
+	filter->query = filter->input;
+
+	context.filter = filter;
+	context.relation_name = NULL;
+	sqlerrcontext.callback = pb3ld_row_filter_error_callback;
+	sqlerrcontext.arg = (void *) &context;
+	sqlerrcontext.previous = error_context_stack;
+	error_context_stack = &sqlerrcontext;
+
+#if PG_VERSION_NUM < 120000
+	ereport(ERROR,
+			(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+			 errmsg("row_filter requires PostgreSQL 12 or later")));
+#endif
+
+	colon = strchr(input, ':');
+	if (colon == NULL)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("invalid input syntax for row_filter"),
+				 errdetail("The value must consist of a table pattern followed by a colon and an expression.")));
+
+	start = input;
+	while (isspace((unsigned char) *start))
+		start++;
+	end = colon;
+	while (end > start && isspace((unsigned char) end[-1]))
+		end--;
+	filter->table.option_name = "row_filter";
+	filter->table.pattern = pnstrdup(start, (Size) (end - start));
+	pb3ld_parse_table_pattern(&filter->table);
+
+	expression = colon + 1;
+	while (isspace((unsigned char) *expression))
+		expression++;
+	if (*expression == '\0')
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("invalid input syntax for row_filter"),
+				 errdetail("The expression can't be empty.")));
+
+	filter->query = psprintf(PB3LD_ROW_FILTER_QUERY_PREFIX "%s", expression);
+#if PG_VERSION_NUM >= 140000
+	parsetree = raw_parser(filter->query, RAW_PARSE_DEFAULT);
+#else
+	parsetree = raw_parser(filter->query);
+#endif
+
+	/*
+	 * Anything which ends up anywhere else than in the WHERE clause, e.g.
+	 * "true ORDER BY 1" or "true; SELECT 2", means that the input wasn't just
+	 * an expression.
+	 */
+	if (list_length(parsetree) != 1 ||
+		!IsA(((RawStmt *) linitial(parsetree))->stmt, SelectStmt))
+		stmt = NULL;
+	else
+		stmt = (SelectStmt *) ((RawStmt *) linitial(parsetree))->stmt;
+	if (stmt == NULL ||
+		stmt->op != SETOP_NONE ||
+		stmt->withClause != NULL ||
+		stmt->distinctClause != NIL ||
+		list_length(stmt->targetList) != 1 ||
+		stmt->fromClause != NIL ||
+		stmt->groupClause != NIL ||
+		stmt->havingClause != NULL ||
+		stmt->windowClause != NIL ||
+		stmt->sortClause != NIL ||
+		stmt->limitOffset != NULL ||
+		stmt->limitCount != NULL ||
+		stmt->lockingClause != NIL ||
+		stmt->whereClause == NULL)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("invalid input syntax for row_filter"),
+				 errdetail("The expression must be a single scalar expression.")));
+	filter->expression = stmt->whereClause;
+
+	error_context_stack = sqlerrcontext.previous;
+
+	return filter;
+}
+
+#if PG_VERSION_NUM >= 120000
+
+static bool
+pb3ld_row_filter_is_user_defined_function(Oid func_id, void *context)
+{
+	return func_id >= FirstNormalObjectId;
+}
+
+/*
+ * The expression is evaluated with a historic catalog snapshot, and in the
+ * middle of decoding, so only allow what's safe there.  These are the same
+ * rules as the ones for publication row filters.
+ */
+static bool
+pb3ld_row_filter_check_walker(Node *node, void *context)
+{
+	if (node == NULL)
+		return false;
+
+	if (check_functions_in_node(node, pb3ld_row_filter_is_user_defined_function, NULL))
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("user-defined functions and operators are not allowed in row filters")));
+	if (IsA(node, Var) && ((Var *) node)->varattno < 0)
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("system columns are not allowed in row filters")));
+
+	return expression_tree_walker(node, pb3ld_row_filter_check_walker, context);
+}
+
+/*
+ * Resolves a column reference of the expression to a column of the relation,
+ * which is passed in p_ref_hook_state.  The reference can be qualified with
+ * the name of the relation, and the name of its schema.  Returning NULL makes
+ * parse analysis report the reference as unknown, since there's nothing else
+ * it could refer to.
+ */
+static Node *
+pb3ld_row_filter_column_ref_hook(ParseState *pstate, ColumnRef *cref)
+{
+	Relation relation = (Relation) pstate->p_ref_hook_state;
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	int nfields = list_length(cref->fields);
+	const FormData_pg_attribute *sysatt;
+	const char *colname;
+	Var *var = NULL;
+	int i;
+
+	if (nfields > 3 || !IsA(llast(cref->fields), String))
+		return NULL;
+	if (nfields >= 2 &&
+		strcmp(strVal(list_nth(cref->fields, nfields - 2)),
+			   RelationGetRelationName(relation)) != 0)
+		return NULL;
+	if (nfields == 3 &&
+		strcmp(strVal(linitial(cref->fields)),
+			   get_namespace_name(RelationGetNamespace(relation))) != 0)
+		return NULL;
+	colname = strVal(llast(cref->fields));
+
+	for (i = 0; i < tupdesc->natts; i++)
+	{
+		Form_pg_attribute att = TupleDescAttr(tupdesc, i);
+
+		if (!att->attisdropped && strcmp(NameStr(att->attname), colname) == 0)
+		{
+			var = makeVar(1, att->attnum, att->atttypid, att->atttypmod,
+						  att->attcollation, 0);
+			break;
+		}
+	}
+	/* rejected by pb3ld_row_filter_check_walker */
+	if (var == NULL && (sysatt = SystemAttributeByName(colname)) != NULL)
+		var = makeVar(1, sysatt->attnum, sysatt->atttypid, sysatt->atttypmod,
+					  sysatt->attcollation, 0);
+	if (var == NULL)
+		return NULL;
+
+	var->location = cref->location;
+	return (Node *) var;
+}
+
+/*
+ * Resolves the expression of the filter against relation and prepares it for
+ * evaluation.  The state lives in a new child context of parent, and must be
+ * released with pb3ld_row_filter_state_free.
+ */
+PB3LD_Row_Filter_State *
+pb3ld_row_filter_state_create(const PB3LD_Row_Filter *filter, Relation relation,
+							  MemoryContext parent)
+{
+	PB3LD_Row_Filter_State *state;
+	PB3LD_Row_Filter_Error_Context context;
+	ErrorContextCallback sqlerrcontext;
+	MemoryContext filter_context;
+	MemoryContext oldcxt;
+	ParseState *pstate;
+	Node *expr;
+	Bitmapset *identity_key;
+
+	filter_context = AllocSetContextCreate(parent,
+										   "PB3LD row filter context",
+										   ALLOCSET_SMALL_SIZES);
+	oldcxt = MemoryContextSwitchTo(filter_context);
+
+	state = palloc0(sizeof(PB3LD_Row_Filter_State));
+	state->context = filter_context;
+
+	context.filter = filter;
+	context.relation_name = quote_qualified_identifier(get_namespace_name(RelationGetNamespace(relation)),
+													   RelationGetRelationName(relation));
+	sqlerrcontext.callback = pb3ld_row_filter_error_callback;
+	sqlerrcontext.arg = (void *) &context;
+	sqlerrcontext.previous = error_context_stack;
+	error_context_stack = &sqlerrcontext;
+
+	/*
+	 * Adding the relation to the range table would require locking it, and
+	 * waiting for a lock in a decoding callback can deadlock: a session
+	 * holding a conflicting lock could itself be waiting for this walsender
+	 * to confirm its commit for synchronous replication.  The relation was
+	 * opened with the historic snapshot of the change being decoded, so its
+	 * definition can't change under us anyway.  Instead, column references
+	 * are resolved against its descriptor directly.
+	 */
+	pstate = make_parsestate(NULL);
+	pstate->p_sourcetext = filter->query;
+	pstate->p_pre_columnref_hook = pb3ld_row_filter_column_ref_hook;
+	pstate->p_ref_hook_state = (void *) relation;
+
+	expr = transformWhereClause(pstate, copyObject(filter->expression),
+								EXPR_KIND_WHERE, "row_filter");
+	assign_expr_collations(pstate, expr);
+
+	if (checkExprHasSubLink(expr))
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("subqueries are not allowed in row filters")));
+	if (contain_mutable_functions(expr))
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("only immutable functions and operators are allowed in row filters")));
+	(void) pb3ld_row_filter_check_walker(expr, NULL);
+
+	free_parsestate(pstate);
+
+	pull_varattnos(expr, 1, &state->attnums);
+	identity_key = RelationGetIndexAttrBitmap(relation, INDEX_ATTR_BITMAP_IDENTITY_KEY);
+	state->key_only = bms_is_subset(state->attnums, identity_key);
+	bms_free(identity_key);
+
+	state->estate = CreateExecutorState();
+	state->exprstate = ExecPrepareExpr((Expr *) expr, state->estate);
+	/* a copy, so that the slot doesn't pin the relation's descriptor */
+	state->slot = MakeSingleTupleTableSlot(CreateTupleDescCopy(RelationGetDescr(relation)),
+										   &TTSOpsVirtual);
+
+	error_context_stack = sqlerrcontext.previous;
+	MemoryContextSwitchTo(oldcxt);
+
+	return state;
+}
+
+void
+pb3ld_row_filter_state_free(PB3LD_Row_Filter_State *state)
+{
+	FreeExecutorState(state->estate);
+	MemoryContextDelete(state->context);
+}
+
+bool
+pb3ld_row_filter_is_key_only(const PB3LD_Row_Filter_State *state)
+{
+	return state->key_only;
+}
+
+/*
+ * Evaluates the filter against tuple.  A NULL result counts as a mismatch,
+ * like in a WHERE clause.
+ *
+ * Unchanged TOASTed values aren't part of the new tuple of an UPDATE.  If the
+ * expression references one of those, its value is taken from old_tuple, and
+ * if that isn't possible, the result is PB3LD_ROW_FILTER_UNKNOWN.
+ */
+PB3LD_Row_Filter_Result
+pb3ld_row_filter_evaluate(PB3LD_Row_Filter_State *state, Relation relation,
+						  ReorderBufferTupleBuf *tuple, ReorderBufferTupleBuf *old_tuple)
+{
+	TupleDesc tupdesc = RelationGetDescr(relation);
+	TupleTableSlot *slot = state->slot;
+	ExprContext *econtext;
+	Datum *old_values = NULL;
+	bool *old_nulls = NULL;
+	Datum result;
+	bool isnull;
+	int natt;
+
+	ExecClearTuple(slot);
+	heap_deform_tuple(&tuple->tuple, tupdesc, slot->tts_values, slot->tts_isnull);
+
+	for (natt = 0; natt < tupdesc->natts; natt++)
+	{
+		Form_pg_attribute attr = TupleDescAttr(tupdesc, natt);
+
+		if (attr->attisdropped || attr->attlen != -1 || slot->tts_isnull[natt])
+			continue;
+		if (!VARATT_IS_EXTERNAL_ONDISK(DatumGetPointer(slot->tts_values[natt])))
+			continue;
+		if (!bms_is_member(attr->attnum - FirstLowInvalidHeapAttributeNumber, state->attnums))
+			continue;
+
+		if (old_tuple == NULL)
+			return PB3LD_ROW_FILTER_UNKNOWN;
+		if (old_values == NULL)
+		{
+			old_values = palloc(tupdesc->natts * sizeof(Datum));
+			old_nulls = palloc(tupdesc->natts * sizeof(bool));
+			heap_deform_tuple(&old_tuple->tuple, tupdesc, old_values, old_nulls);
+		}
+		if (old_nulls[natt] ||
+			VARATT_IS_EXTERNAL_ONDISK(DatumGetPointer(old_values[natt])))
+			return PB3LD_ROW_FILTER_UNKNOWN;
+		slot->tts_values[natt] = old_values[natt];
+	}
+	ExecStoreVirtualTuple(slot);
+
+	econtext = GetPerTupleExprContext(state->estate);
+	econtext->ecxt_scantuple = slot;
+	result = ExecEvalExprSwitchContext(state->exprstate, econtext, &isnull);
+
+	ExecClearTuple(slot);
+	ResetPerTupleExprContext(state->estate);
+
+	if (!isnull && DatumGetBool(result))
+		return PB3LD_ROW_FILTER_MATCH;
+	return PB3LD_ROW_FILTER_NO_MATCH;
+}
+
+#else							/* PG_VERSION_NUM >= 120000 */
+
+/* pb3ld_parse_row_filter doesn't accept any filters on these versions */
+
+PB3LD_Row_Filter_State *
+pb3ld_row_filter_state_create(const PB3LD_Row_Filter *filter, Relation relation,
+							  MemoryContext parent)
+{
+	elog(ERROR, "row filters are not supported on this version of PostgreSQL");
+	return NULL;
+}
+
+void
+pb3ld_row_filter_state_free(PB3LD_Row_Filter_State *state)
+{
+}
+
+bool
+pb3ld_row_filter_is_key_only(const PB3LD_Row_Filter_State *state)
+{
+	return false;
+}
+
+PB3LD_Row_Filter_Result
+pb3ld_row_filter_evaluate(PB3LD_Row_Filter_State *state, Relation relation,
+						  ReorderBufferTupleBuf *tuple, ReorderBufferTupleBuf *old_tuple)
+{
+	return PB3LD_ROW_FILTER_UNKNOWN;
+}
+
+#endif							/* PG_VERSION_NUM >= 120000 */
@@ -2,13 +2,23 @@
 
 #include "postgres.h"
 
+#include "common/pg_lzcompress.h"
 #include "utils/int8.h"
 
+#ifdef USE_LZ4
+#include <lz4.h>
+#endif
+#ifdef USE_ZSTD
+#include <zstd.h>
+#endif
+
 #include "pg_pb3_ld.h"
 
 /* WireMessageHeader */
-#define PB3LD_WHDR_TYPES	1
-#define PB3LD_WHDR_OFFSETS	2
+#define PB3LD_WHDR_TYPES				1
+#define PB3LD_WHDR_OFFSETS				2
+#define PB3LD_WHDR_COMPRESSION			3
+#define PB3LD_WHDR_UNCOMPRESSED_LENGTH	4
 
 static Oid
 pb3ld_parse_binary_oid_value(const char *value)
@@ -67,6 +77,553 @@ pb3ld_parse_binary_oid_range(char *value, PB3LD_Oid_Range *range)
 	error_context_stack = sqlerrcontext.previous;
 }
 
+static int32
+pb3ld_parse_protocol_version_value(const char *value)
+{
+	char *endptr;
+	long version;
+
+	errno = 0;
+	version = strtol(value, &endptr, 10);
+	if (endptr == value || *endptr != '\0' || errno != 0 ||
+		version < 1 || version > INT_MAX)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("invalid input syntax for proto_version")));
+	return (int32) version;
+}
+
+/*
+ * pb3ld_parse_protocol_version parses the value of the proto_version option,
+ * which is either a single protocol version or a range of versions the
+ * client can speak, e.g. "1-3".  Returns the highest version in the range
+ * supported by the plugin, and raises an exception if there is none.
+ */
+int32
+pb3ld_parse_protocol_version(const char *input)
+{
+	char *value = pstrdup(input);
+	char *hyphen;
+	int32 min;
+	int32 max;
+
+	hyphen = strchr(value, '-');
+	if (hyphen != NULL)
+	{
+		*hyphen = '\0';
+		min = pb3ld_parse_protocol_version_value(value);
+		max = pb3ld_parse_protocol_version_value(hyphen + 1);
+		if (max < min)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("the upper bound of a range can't be lower than its lower bound in proto_version")));
+	}
+	else
+	{
+		min = pb3ld_parse_protocol_version_value(value);
+		max = min;
+	}
+	pfree(value);
+
+	if (min > PB3LD_MAX_PROTOCOL_VERSION || max < PB3LD_MIN_PROTOCOL_VERSION)
+	{
+		if (min == max)
+			ereport(ERROR,
+					(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+					 errmsg("protocol version %d is not supported", min),
+					 errdetail("Supported protocol versions are %d to %d.",
+							   PB3LD_MIN_PROTOCOL_VERSION, PB3LD_MAX_PROTOCOL_VERSION)));
+		else
+			ereport(ERROR,
+					(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+					 errmsg("none of the protocol versions %d to %d are supported", min, max),
+					 errdetail("Supported protocol versions are %d to %d.",
+							   PB3LD_MIN_PROTOCOL_VERSION, PB3LD_MAX_PROTOCOL_VERSION)));
+	}
+	return Min(max, PB3LD_MAX_PROTOCOL_VERSION);
+}
+
+/*
+ * pb3ld_parse_compression parses the value of the compression option.  lz4
+ * and zstd are only available if the server was built with support for them.
+ */
+int
+pb3ld_parse_compression(const char *input)
+{
+	if (strcmp(input, "none") == 0)
+		return PB3LD_COMPRESSION_NONE;
+	else if (strcmp(input, "pglz") == 0)
+		return PB3LD_COMPRESSION_PGLZ;
+	else if (strcmp(input, "lz4") == 0)
+	{
+#ifdef USE_LZ4
+		return PB3LD_COMPRESSION_LZ4;
+#else
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("compression method lz4 is not supported by this build")));
+#endif
+	}
+	else if (strcmp(input, "zstd") == 0)
+	{
+#ifdef USE_ZSTD
+		return PB3LD_COMPRESSION_ZSTD;
+#else
+		ereport(ERROR,
+				(errcode(ERRCODE_FEATURE_NOT_SUPPORTED),
+				 errmsg("compression method zstd is not supported by this build")));
+#endif
+	}
+	ereport(ERROR,
+			(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+			 errmsg("\"%s\" is not a valid value for parameter \"compression\"", input)));
+	return PB3LD_COMPRESSION_NONE;		/* keep compiler quiet */
+}
+
+/*
+ * pb3ld_parse_logical_message_prefixes parses a comma-separated list of
+ * logical message prefixes.  Whitespace around each prefix is ignored.  An
+ * empty input results in NIL.
+ */
+List *
+pb3ld_parse_logical_message_prefixes(const char *input)
+{
+	List *prefixes = NIL;
+	const char *nextp = input;
+
+	for (;;)
+	{
+		const char *start;
+		const char *end;
+
+		while (isspace((unsigned char) *nextp))
+			nextp++;
+		if (*nextp == '\0' && prefixes == NIL)
+			return NIL;
+
+		start = nextp;
+		end = strchr(start, ',');
+		if (end == NULL)
+			end = start + strlen(start);
+		nextp = end;
+		while (end > start && isspace((unsigned char) end[-1]))
+			end--;
+
+		if (end == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for logical_message_prefixes")));
+
+		prefixes = lappend(prefixes, pnstrdup(start, (Size) (end - start)));
+
+		if (*nextp == '\0')
+			break;
+		nextp++;
+	}
+
+	return prefixes;
+}
+
+static void
+pb3ld_table_pattern_parse_error_callback(void *arg)
+{
+	const PB3LD_Table_Pattern *pattern = (const PB3LD_Table_Pattern *) arg;
+
+	errcontext("while parsing %s pattern \"%s\"", pattern->option_name, pattern->pattern);
+}
+
+/*
+ * pb3ld_parse_origin_names parses the comma-separated list of replication
+ * origin names accepted by the origin option.  Whitespace around each name is
+ * ignored.
+ */
+List *
+pb3ld_parse_origin_names(const char *input)
+{
+	List *names = NIL;
+	const char *nextp = input;
+
+	for (;;)
+	{
+		const char *start;
+		const char *end;
+
+		while (isspace((unsigned char) *nextp))
+			nextp++;
+
+		start = nextp;
+		end = strchr(start, ',');
+		if (end == NULL)
+			end = start + strlen(start);
+		nextp = end;
+		while (end > start && isspace((unsigned char) end[-1]))
+			end--;
+
+		if (end == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for origin")));
+
+		names = lappend(names, pnstrdup(start, (Size) (end - start)));
+
+		if (*nextp == '\0')
+			break;
+		nextp++;
+	}
+
+	return names;
+}
+
+/*
+ * Splits pattern->pattern into its schema and table parts.  option_name and
+ * pattern must already be set.
+ */
+void
+pb3ld_parse_table_pattern(PB3LD_Table_Pattern *pattern)
+{
+	ErrorContextCallback sqlerrcontext;
+	const char *dot;
+
+	sqlerrcontext.callback = pb3ld_table_pattern_parse_error_callback;
+	sqlerrcontext.arg = (void *) pattern;
+	sqlerrcontext.previous = error_context_stack;
+	error_context_stack = &sqlerrcontext;
+
+	dot = strchr(pattern->pattern, '.');
+	if (dot == NULL)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("table patterns must be schema-qualified")));
+	else if (strchr(dot + 1, '.') != NULL)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("table patterns must consist of exactly two parts")));
+	else if (dot == pattern->pattern || dot[1] == '\0')
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("the schema and table parts of a table pattern can't be empty")));
+
+	pattern->schema_pattern = pnstrdup(pattern->pattern, (Size) (dot - pattern->pattern));
+	pattern->table_pattern = pstrdup(dot + 1);
+
+	error_context_stack = sqlerrcontext.previous;
+}
+
+/*
+ * pb3ld_parse_table_patterns parses a comma-separated list of table patterns
+ * for the option option_name.  Whitespace around each pattern is ignored.  An
+ * empty input results in NIL.
+ */
+List *
+pb3ld_parse_table_patterns(const char *input, const char *option_name)
+{
+	List *patterns = NIL;
+	const char *nextp = input;
+
+	for (;;)
+	{
+		PB3LD_Table_Pattern *pattern;
+		const char *start;
+		const char *end;
+
+		while (isspace((unsigned char) *nextp))
+			nextp++;
+		if (*nextp == '\0' && patterns == NIL)
+			return NIL;
+
+		start = nextp;
+		end = strchr(start, ',');
+		if (end == NULL)
+			end = start + strlen(start);
+		nextp = end;
+		while (end > start && isspace((unsigned char) end[-1]))
+			end--;
+
+		if (end == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for %s", option_name)));
+
+		pattern = (PB3LD_Table_Pattern *) palloc(sizeof(PB3LD_Table_Pattern));
+		pattern->option_name = option_name;
+		pattern->pattern = pnstrdup(start, (Size) (end - start));
+		pb3ld_parse_table_pattern(pattern);
+		patterns = lappend(patterns, pattern);
+
+		if (*nextp == '\0')
+			break;
+		nextp++;
+	}
+
+	return patterns;
+}
+
+/*
+ * Matches str against pattern, in which '*' matches any sequence of
+ * characters, and every other character matches itself.
+ */
+static bool
+pb3ld_wildcard_match(const char *pattern, const char *str)
+{
+	const char *star = NULL;
+	const char *backtrack = NULL;
+
+	while (*str != '\0')
+	{
+		if (*pattern == '*')
+		{
+			star = pattern++;
+			backtrack = str;
+		}
+		else if (*pattern == *str)
+		{
+			pattern++;
+			str++;
+		}
+		else if (star != NULL)
+		{
+			/* let the last star eat one more character */
+			pattern = star + 1;
+			str = ++backtrack;
+		}
+		else
+			return false;
+	}
+
+	while (*pattern == '*')
+		pattern++;
+	return *pattern == '\0';
+}
+
+bool
+pb3ld_table_pattern_matches(const PB3LD_Table_Pattern *pattern,
+							const char *schema_name, const char *table_name)
+{
+	return pb3ld_wildcard_match(pattern->schema_pattern, schema_name) &&
+		pb3ld_wildcard_match(pattern->table_pattern, table_name);
+}
+
+/*
+ * pb3ld_table_patterns_match returns true if any of the patterns matches the
+ * table.
+ */
+bool
+pb3ld_table_patterns_match(List *patterns, const char *schema_name, const char *table_name)
+{
+	ListCell *lc;
+
+	foreach(lc, patterns)
+	{
+		if (pb3ld_table_pattern_matches((const PB3LD_Table_Pattern *) lfirst(lc),
+										schema_name, table_name))
+			return true;
+	}
+	return false;
+}
+
+static void
+pb3ld_column_projection_parse_error_callback(void *arg)
+{
+	errcontext("while parsing columns entry \"%s\"", (const char *) arg);
+}
+
+/*
+ * Parses a single entry of the columns option, e.g. "public.foo(a, b)" or
+ * "public.*(-c)".
+ */
+static PB3LD_Column_Projection *
+pb3ld_parse_column_projection(const char *entry)
+{
+	ErrorContextCallback sqlerrcontext;
+	PB3LD_Column_Projection *projection;
+	const char *open_paren;
+	const char *pattern_end;
+	const char *nextp;
+	const char *end;
+	bool have_included = false;
+	bool have_excluded = false;
+
+	sqlerrcontext.callback = pb3ld_column_projection_parse_error_callback;
+	sqlerrcontext.arg = (void *) entry;
+	sqlerrcontext.previous = error_context_stack;
+	error_context_stack = &sqlerrcontext;
+
+	open_paren = strchr(entry, '(');
+	end = entry + strlen(entry);
+	if (open_paren == NULL || end[-1] != ')')
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("invalid input syntax for columns"),
+				 errdetail("Each entry must consist of a table pattern followed by a list of columns in parentheses.")));
+
+	pattern_end = open_paren;
+	while (pattern_end > entry && isspace((unsigned char) pattern_end[-1]))
+		pattern_end--;
+
+	projection = (PB3LD_Column_Projection *) palloc(sizeof(PB3LD_Column_Projection));
+	projection->table.option_name = "columns";
+	projection->table.pattern = pnstrdup(entry, (Size) (pattern_end - entry));
+	projection->column_names = NIL;
+	pb3ld_parse_table_pattern(&projection->table);
+
+	/* the list of columns, without the parentheses */
+	nextp = open_paren + 1;
+	end--;
+	for (;;)
+	{
+		const char *start;
+		const char *colend;
+
+		while (nextp < end && isspace((unsigned char) *nextp))
+			nextp++;
+
+		start = nextp;
+		colend = memchr(start, ',', end - start);
+		if (colend == NULL)
+			colend = end;
+		nextp = colend;
+		while (colend > start && isspace((unsigned char) colend[-1]))
+			colend--;
+
+		if (start < colend && *start == '-')
+		{
+			have_excluded = true;
+			start++;
+		}
+		else
+			have_included = true;
+
+		if (colend == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("column names in columns can't be empty")));
+		if (memchr(start, '(', colend - start) != NULL ||
+			memchr(start, ')', colend - start) != NULL)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for columns")));
+
+		projection->column_names = lappend(projection->column_names,
+										   pnstrdup(start, (Size) (colend - start)));
+
+		if (nextp == end)
+			break;
+		nextp++;
+	}
+
+	if (have_included && have_excluded)
+		ereport(ERROR,
+				(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+				 errmsg("a columns entry can't both include and exclude columns")));
+	projection->exclude = have_excluded;
+
+	error_context_stack = sqlerrcontext.previous;
+
+	return projection;
+}
+
+/*
+ * pb3ld_parse_column_projections parses the value of the columns option: a
+ * semicolon-separated list of entries, each of which consists of a table
+ * pattern and a parenthesized, comma-separated list of columns.  The columns
+ * are either all included or, if prefixed with '-', all excluded.  An empty
+ * input results in NIL.
+ */
+List *
+pb3ld_parse_column_projections(const char *input)
+{
+	List *projections = NIL;
+	const char *nextp = input;
+
+	for (;;)
+	{
+		const char *start;
+		const char *end;
+
+		while (isspace((unsigned char) *nextp))
+			nextp++;
+		if (*nextp == '\0' && projections == NIL)
+			return NIL;
+
+		start = nextp;
+		end = strchr(start, ';');
+		if (end == NULL)
+			end = start + strlen(start);
+		nextp = end;
+		while (end > start && isspace((unsigned char) end[-1]))
+			end--;
+
+		if (end == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for columns")));
+
+		projections = lappend(projections,
+							  pb3ld_parse_column_projection(pnstrdup(start, (Size) (end - start))));
+
+		if (*nextp == '\0')
+			break;
+		nextp++;
+	}
+
+	return projections;
+}
+
+/*
+ * pb3ld_parse_operations parses a comma-separated list of operation names into
+ * a bitmask of PB3LD_OP_* bits.  Whitespace around each name is ignored.
+ */
+int
+pb3ld_parse_operations(const char *input)
+{
+	int operations = 0;
+	const char *nextp = input;
+
+	for (;;)
+	{
+		const char *start;
+		const char *end;
+		char *name;
+
+		while (isspace((unsigned char) *nextp))
+			nextp++;
+
+		start = nextp;
+		end = strchr(start, ',');
+		if (end == NULL)
+			end = start + strlen(start);
+		nextp = end;
+		while (end > start && isspace((unsigned char) end[-1]))
+			end--;
+
+		if (end == start)
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("invalid input syntax for operations")));
+
+		name = pnstrdup(start, (Size) (end - start));
+		if (strcmp(name, "insert") == 0)
+			operations |= PB3LD_OP_INSERT;
+		else if (strcmp(name, "update") == 0)
+			operations |= PB3LD_OP_UPDATE;
+		else if (strcmp(name, "delete") == 0)
+			operations |= PB3LD_OP_DELETE;
+		else if (strcmp(name, "truncate") == 0)
+			operations |= PB3LD_OP_TRUNCATE;
+		else
+			ereport(ERROR,
+					(errcode(ERRCODE_INVALID_PARAMETER_VALUE),
+					 errmsg("unrecognized operation \"%s\" in operations", name)));
+		pfree(name);
+
+		if (*nextp == '\0')
+			break;
+		nextp++;
+	}
+
+	return operations;
+}
+
 /*
  * pb3ld_parse_binary_oid_ranges parses a comma-separated list of oid ranges
  * into privdata->binary_oid_ranges.  privdata->num_binary_oid_ranges is set to
@@ -158,9 +715,28 @@ pb3ld_parse_binary_oid_ranges(const char *input)
 	return ranges;
 }
 
+/*
+ * Returns the size the message buffer is preallocated to, and shrunk back to
+ * after a message which needed more.  Twice the target size leaves room for
+ * the change which pushes the buffer over the target.
+ */
+int
+pb3ld_message_buffer_alloc_size(const PB3LD_Private *privdata)
+{
+	return Max(2 * privdata->wire_message_target_size, PB3LD_MIN_WIRE_MESSAGE_TARGET_SIZE);
+}
+
 void
 pb3ld_wire_message_begin(PB3LD_Private *privdata, int32 msgtype)
 {
+	if (!privdata->session_start_sent &&
+		privdata->protocol_version >= PB3LD_SESSION_START_PROTOCOL_VERSION)
+	{
+		/* the first message of the session */
+		privdata->session_start_sent = true;
+		pb3ld_write_SessionStart(privdata);
+	}
+
 	pb3_append_enum_kv(privdata->header_buf, PB3LD_WHDR_TYPES, msgtype);
 	pb3_append_varint_kv(privdata->header_buf, PB3LD_WHDR_OFFSETS, privdata->message_buf->len);
 }
@@ -176,17 +752,135 @@ pb3ld_should_flush_message_buffer(PB3LD_Private *privdata)
 	return privdata->message_buf->len > privdata->wire_message_target_size;
 }
 
+/*
+ * Compresses the message buffer into compress_buf.  Returns false if the
+ * message buffer couldn't be compressed into fewer bytes, in which case it
+ * should be sent as is.
+ */
+static bool
+pb3ld_compress_message_buffer(PB3LD_Private *privdata)
+{
+	StringInfo src = privdata->message_buf;
+	StringInfo dst;
+	int bound;
+	int len;
+
+	if (privdata->compress_buf == NULL)
+	{
+		MemoryContext oldcxt = MemoryContextSwitchTo(privdata->buf_context);
+
+		privdata->compress_buf = makeStringInfo();
+		MemoryContextSwitchTo(oldcxt);
+	}
+	dst = privdata->compress_buf;
+
+	switch (privdata->compression)
+	{
+		case PB3LD_COMPRESSION_PGLZ:
+			bound = PGLZ_MAX_OUTPUT(src->len);
+			break;
+#ifdef USE_LZ4
+		case PB3LD_COMPRESSION_LZ4:
+			bound = LZ4_compressBound(src->len);
+			break;
+#endif
+#ifdef USE_ZSTD
+		case PB3LD_COMPRESSION_ZSTD:
+			bound = (int) ZSTD_compressBound(src->len);
+			break;
+#endif
+		default:
+			elog(ERROR, "unexpected compression method %d", privdata->compression);
+			return false;		/* keep compiler quiet */
+	}
+
+	resetStringInfo(dst);
+	enlargeStringInfo(dst, bound);
+
+	switch (privdata->compression)
+	{
+		case PB3LD_COMPRESSION_PGLZ:
+			/* -1 if the data didn't compress well enough */
+			len = pglz_compress(src->data, src->len, dst->data, PGLZ_strategy_default);
+			break;
+#ifdef USE_LZ4
+		case PB3LD_COMPRESSION_LZ4:
+			len = LZ4_compress_default(src->data, dst->data, src->len, bound);
+			if (len <= 0)
+				elog(ERROR, "could not compress wire message body with lz4");
+			break;
+#endif
+#ifdef USE_ZSTD
+		case PB3LD_COMPRESSION_ZSTD:
+			{
+				size_t rc = ZSTD_compress(dst->data, bound, src->data, src->len,
+										  ZSTD_CLEVEL_DEFAULT);
+
+				if (ZSTD_isError(rc))
+					elog(ERROR, "could not compress wire message body with zstd");
+				len = (int) rc;
+			}
+			break;
+#endif
+		default:
+			elog(ERROR, "unexpected compression method %d", privdata->compression);
+			return false;		/* keep compiler quiet */
+	}
+
+	if (len < 0 || len >= src->len)
+		return false;
+	dst->len = len;
+	return true;
+}
+
+/*
+ * Releases the memory of a buffer which grew larger than desired_alloc_len
+ * while processing the last wire message.
+ */
+static void
+pb3ld_shrink_buffer(PB3LD_Private *privdata, StringInfo buf, int desired_alloc_len)
+{
+	MemoryContext oldcxt;
+
+	if (buf->maxlen <= desired_alloc_len)
+		return;
+
+	pfree(buf->data);
+
+	oldcxt = MemoryContextSwitchTo(privdata->buf_context);
+
+	buf->data = palloc(desired_alloc_len);
+	buf->maxlen = desired_alloc_len;
+
+	MemoryContextSwitchTo(oldcxt);
+}
+
 void
 pb3ld_flush_message_buffer(PB3LD_Private *privdata, StringInfo out)
 {
-	const int desired_alloc_len = privdata->wire_message_target_size * 2;
+	const int desired_alloc_len = pb3ld_message_buffer_alloc_size(privdata);
+	StringInfo body = privdata->message_buf;
 
 	Assert(privdata->message_buf->len > 0);
 	Assert(privdata->header_buf->len > 0);
 
+	/*
+	 * Only the body is compressed.  The header says how, and how long the body
+	 * is once decompressed.  The offsets refer to the decompressed body.
+	 */
+	if (privdata->compression != PB3LD_COMPRESSION_NONE &&
+		pb3ld_compress_message_buffer(privdata))
+	{
+		pb3_append_enum_kv(privdata->header_buf, PB3LD_WHDR_COMPRESSION,
+						   privdata->compression);
+		pb3_append_varint_kv(privdata->header_buf, PB3LD_WHDR_UNCOMPRESSED_LENGTH,
+							 privdata->message_buf->len);
+		body = privdata->compress_buf;
+	}
+
 	pb3_append_int32(out, privdata->header_buf->len);
 	appendBinaryStringInfo(out, privdata->header_buf->data, privdata->header_buf->len);
-	appendBinaryStringInfo(out, privdata->message_buf->data, privdata->message_buf->len);
+	appendBinaryStringInfo(out, body->data, body->len);
 
 	privdata->sent_message_this_transaction = true;
 
@@ -194,18 +888,11 @@ pb3ld_flush_message_buffer(PB3LD_Private *privdata, StringInfo out)
 	 * If we needed more memory than expected to process this message, release
 	 * it now.
 	 */
-	if (privdata->message_buf->maxlen > desired_alloc_len)
+	pb3ld_shrink_buffer(privdata, privdata->message_buf, desired_alloc_len);
+	if (privdata->compress_buf != NULL)
 	{
-		MemoryContext oldcxt;
-
-		pfree(privdata->message_buf->data);
-
-		oldcxt = MemoryContextSwitchTo(privdata->buf_context);
-
-		privdata->message_buf->data = palloc(desired_alloc_len);
-		privdata->message_buf->maxlen = desired_alloc_len;
-
-		MemoryContextSwitchTo(oldcxt);
+		pb3ld_shrink_buffer(privdata, privdata->compress_buf, desired_alloc_len);
+		resetStringInfo(privdata->compress_buf);
 	}
 
 	resetStringInfo(privdata->header_buf);
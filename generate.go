@@ -0,0 +1,3 @@
+package pg_pb3_ld
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative pg_pb3.proto
@@ -0,0 +1,21 @@
+module github.com/johto/pg_pb3_ld
+
+go 1.17
+
+require (
+	github.com/golang/protobuf v1.5.2
+	github.com/jackc/pgconn v1.9.0
+	github.com/jackc/pglogrepl v0.0.0-20210628224733-3140d41f7881
+	github.com/jackc/pgproto3/v2 v2.1.1
+	google.golang.org/protobuf v1.27.1
+)
+
+require (
+	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
+	golang.org/x/crypto v0.0.0-20210616213533-5ff15b29337e // indirect
+	golang.org/x/text v0.3.6 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+)
@@ -0,0 +1,2267 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.26.0
+// 	protoc        v3.17.3
+// source: pg_pb3.proto
+
+package pg_pb3_ld
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type WireMessageType int32
+
+const (
+	WireMessageType_WMSG_BEGIN             WireMessageType = 0
+	WireMessageType_WMSG_COMMIT            WireMessageType = 1
+	WireMessageType_WMSG_INSERT            WireMessageType = 2
+	WireMessageType_WMSG_UPDATE            WireMessageType = 3
+	WireMessageType_WMSG_DELETE            WireMessageType = 4
+	WireMessageType_WMSG_TRUNCATE          WireMessageType = 5
+	WireMessageType_WMSG_MESSAGE           WireMessageType = 6
+	WireMessageType_WMSG_RELATION          WireMessageType = 7
+	WireMessageType_WMSG_STREAM_START      WireMessageType = 8
+	WireMessageType_WMSG_STREAM_STOP       WireMessageType = 9
+	WireMessageType_WMSG_STREAM_COMMIT     WireMessageType = 10
+	WireMessageType_WMSG_STREAM_ABORT      WireMessageType = 11
+	WireMessageType_WMSG_PREPARE           WireMessageType = 12
+	WireMessageType_WMSG_COMMIT_PREPARED   WireMessageType = 13
+	WireMessageType_WMSG_ROLLBACK_PREPARED WireMessageType = 14
+	WireMessageType_WMSG_STREAM_PREPARE    WireMessageType = 15
+	WireMessageType_WMSG_SEQUENCE          WireMessageType = 16
+	WireMessageType_WMSG_SESSION_START     WireMessageType = 17
+)
+
+// Enum value maps for WireMessageType.
+var (
+	WireMessageType_name = map[int32]string{
+		0:  "WMSG_BEGIN",
+		1:  "WMSG_COMMIT",
+		2:  "WMSG_INSERT",
+		3:  "WMSG_UPDATE",
+		4:  "WMSG_DELETE",
+		5:  "WMSG_TRUNCATE",
+		6:  "WMSG_MESSAGE",
+		7:  "WMSG_RELATION",
+		8:  "WMSG_STREAM_START",
+		9:  "WMSG_STREAM_STOP",
+		10: "WMSG_STREAM_COMMIT",
+		11: "WMSG_STREAM_ABORT",
+		12: "WMSG_PREPARE",
+		13: "WMSG_COMMIT_PREPARED",
+		14: "WMSG_ROLLBACK_PREPARED",
+		15: "WMSG_STREAM_PREPARE",
+		16: "WMSG_SEQUENCE",
+		17: "WMSG_SESSION_START",
+	}
+	WireMessageType_value = map[string]int32{
+		"WMSG_BEGIN":             0,
+		"WMSG_COMMIT":            1,
+		"WMSG_INSERT":            2,
+		"WMSG_UPDATE":            3,
+		"WMSG_DELETE":            4,
+		"WMSG_TRUNCATE":          5,
+		"WMSG_MESSAGE":           6,
+		"WMSG_RELATION":          7,
+		"WMSG_STREAM_START":      8,
+		"WMSG_STREAM_STOP":       9,
+		"WMSG_STREAM_COMMIT":     10,
+		"WMSG_STREAM_ABORT":      11,
+		"WMSG_PREPARE":           12,
+		"WMSG_COMMIT_PREPARED":   13,
+		"WMSG_ROLLBACK_PREPARED": 14,
+		"WMSG_STREAM_PREPARE":    15,
+		"WMSG_SEQUENCE":          16,
+		"WMSG_SESSION_START":     17,
+	}
+)
+
+func (x WireMessageType) Enum() *WireMessageType {
+	p := new(WireMessageType)
+	*p = x
+	return p
+}
+
+func (x WireMessageType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WireMessageType) Descriptor() protoreflect.EnumDescriptor {
+	return file_pg_pb3_proto_enumTypes[0].Descriptor()
+}
+
+func (WireMessageType) Type() protoreflect.EnumType {
+	return &file_pg_pb3_proto_enumTypes[0]
+}
+
+func (x WireMessageType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WireMessageType.Descriptor instead.
+func (WireMessageType) EnumDescriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{0}
+}
+
+type CompressionMethod int32
+
+const (
+	CompressionMethod_COMPRESSION_NONE CompressionMethod = 0
+	CompressionMethod_COMPRESSION_PGLZ CompressionMethod = 1
+	CompressionMethod_COMPRESSION_LZ4  CompressionMethod = 2
+	CompressionMethod_COMPRESSION_ZSTD CompressionMethod = 3
+)
+
+// Enum value maps for CompressionMethod.
+var (
+	CompressionMethod_name = map[int32]string{
+		0: "COMPRESSION_NONE",
+		1: "COMPRESSION_PGLZ",
+		2: "COMPRESSION_LZ4",
+		3: "COMPRESSION_ZSTD",
+	}
+	CompressionMethod_value = map[string]int32{
+		"COMPRESSION_NONE": 0,
+		"COMPRESSION_PGLZ": 1,
+		"COMPRESSION_LZ4":  2,
+		"COMPRESSION_ZSTD": 3,
+	}
+)
+
+func (x CompressionMethod) Enum() *CompressionMethod {
+	p := new(CompressionMethod)
+	*p = x
+	return p
+}
+
+func (x CompressionMethod) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CompressionMethod) Descriptor() protoreflect.EnumDescriptor {
+	return file_pg_pb3_proto_enumTypes[1].Descriptor()
+}
+
+func (CompressionMethod) Type() protoreflect.EnumType {
+	return &file_pg_pb3_proto_enumTypes[1]
+}
+
+func (x CompressionMethod) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CompressionMethod.Descriptor instead.
+func (CompressionMethod) EnumDescriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{1}
+}
+
+type WireMessageHeader struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Types              []WireMessageType `protobuf:"varint,1,rep,packed,name=types,proto3,enum=pg_pb3_ld.WireMessageType" json:"types,omitempty"`
+	Offsets            []int32           `protobuf:"varint,2,rep,packed,name=offsets,proto3" json:"offsets,omitempty"`
+	Compression        CompressionMethod `protobuf:"varint,3,opt,name=compression,proto3,enum=pg_pb3_ld.CompressionMethod" json:"compression,omitempty"`
+	UncompressedLength int32             `protobuf:"varint,4,opt,name=uncompressed_length,json=uncompressedLength,proto3" json:"uncompressed_length,omitempty"`
+}
+
+func (x *WireMessageHeader) Reset() {
+	*x = WireMessageHeader{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WireMessageHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WireMessageHeader) ProtoMessage() {}
+
+func (x *WireMessageHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WireMessageHeader.ProtoReflect.Descriptor instead.
+func (*WireMessageHeader) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WireMessageHeader) GetTypes() []WireMessageType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WireMessageHeader) GetOffsets() []int32 {
+	if x != nil {
+		return x.Offsets
+	}
+	return nil
+}
+
+func (x *WireMessageHeader) GetCompression() CompressionMethod {
+	if x != nil {
+		return x.Compression
+	}
+	return CompressionMethod_COMPRESSION_NONE
+}
+
+func (x *WireMessageHeader) GetUncompressedLength() int32 {
+	if x != nil {
+		return x.UncompressedLength
+	}
+	return 0
+}
+
+type SessionStart struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProtocolVersion uint32 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+}
+
+func (x *SessionStart) Reset() {
+	*x = SessionStart{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionStart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionStart) ProtoMessage() {}
+
+func (x *SessionStart) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionStart.ProtoReflect.Descriptor instead.
+func (*SessionStart) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SessionStart) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+type BeginTransaction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid        uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	FinalLsn   uint64 `protobuf:"varint,2,opt,name=final_lsn,json=finalLsn,proto3" json:"final_lsn,omitempty"`
+	CommitTime int64  `protobuf:"varint,3,opt,name=commit_time,json=commitTime,proto3" json:"commit_time,omitempty"`
+	OriginName string `protobuf:"bytes,4,opt,name=origin_name,json=originName,proto3" json:"origin_name,omitempty"`
+}
+
+func (x *BeginTransaction) Reset() {
+	*x = BeginTransaction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BeginTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BeginTransaction) ProtoMessage() {}
+
+func (x *BeginTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BeginTransaction.ProtoReflect.Descriptor instead.
+func (*BeginTransaction) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BeginTransaction) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *BeginTransaction) GetFinalLsn() uint64 {
+	if x != nil {
+		return x.FinalLsn
+	}
+	return 0
+}
+
+func (x *BeginTransaction) GetCommitTime() int64 {
+	if x != nil {
+		return x.CommitTime
+	}
+	return 0
+}
+
+func (x *BeginTransaction) GetOriginName() string {
+	if x != nil {
+		return x.OriginName
+	}
+	return ""
+}
+
+type CommitTransaction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid        uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	CommitLsn  uint64 `protobuf:"varint,2,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	EndLsn     uint64 `protobuf:"varint,3,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	CommitTime int64  `protobuf:"varint,4,opt,name=commit_time,json=commitTime,proto3" json:"commit_time,omitempty"`
+}
+
+func (x *CommitTransaction) Reset() {
+	*x = CommitTransaction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CommitTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitTransaction) ProtoMessage() {}
+
+func (x *CommitTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitTransaction.ProtoReflect.Descriptor instead.
+func (*CommitTransaction) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CommitTransaction) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *CommitTransaction) GetCommitLsn() uint64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *CommitTransaction) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *CommitTransaction) GetCommitTime() int64 {
+	if x != nil {
+		return x.CommitTime
+	}
+	return 0
+}
+
+type StreamStart struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid          uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	FirstSegment bool   `protobuf:"varint,2,opt,name=first_segment,json=firstSegment,proto3" json:"first_segment,omitempty"`
+}
+
+func (x *StreamStart) Reset() {
+	*x = StreamStart{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamStart) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStart) ProtoMessage() {}
+
+func (x *StreamStart) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStart.ProtoReflect.Descriptor instead.
+func (*StreamStart) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StreamStart) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *StreamStart) GetFirstSegment() bool {
+	if x != nil {
+		return x.FirstSegment
+	}
+	return false
+}
+
+type StreamStop struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+}
+
+func (x *StreamStop) Reset() {
+	*x = StreamStop{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamStop) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStop) ProtoMessage() {}
+
+func (x *StreamStop) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStop.ProtoReflect.Descriptor instead.
+func (*StreamStop) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StreamStop) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+type StreamCommit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid        uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	CommitLsn  uint64 `protobuf:"varint,2,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	EndLsn     uint64 `protobuf:"varint,3,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	CommitTime int64  `protobuf:"varint,4,opt,name=commit_time,json=commitTime,proto3" json:"commit_time,omitempty"`
+}
+
+func (x *StreamCommit) Reset() {
+	*x = StreamCommit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamCommit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamCommit) ProtoMessage() {}
+
+func (x *StreamCommit) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamCommit.ProtoReflect.Descriptor instead.
+func (*StreamCommit) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StreamCommit) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *StreamCommit) GetCommitLsn() uint64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *StreamCommit) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *StreamCommit) GetCommitTime() int64 {
+	if x != nil {
+		return x.CommitTime
+	}
+	return 0
+}
+
+type StreamAbort struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid      uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	Subxid   uint32 `protobuf:"varint,2,opt,name=subxid,proto3" json:"subxid,omitempty"`
+	AbortLsn uint64 `protobuf:"varint,3,opt,name=abort_lsn,json=abortLsn,proto3" json:"abort_lsn,omitempty"`
+}
+
+func (x *StreamAbort) Reset() {
+	*x = StreamAbort{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamAbort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamAbort) ProtoMessage() {}
+
+func (x *StreamAbort) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamAbort.ProtoReflect.Descriptor instead.
+func (*StreamAbort) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StreamAbort) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *StreamAbort) GetSubxid() uint32 {
+	if x != nil {
+		return x.Subxid
+	}
+	return 0
+}
+
+func (x *StreamAbort) GetAbortLsn() uint64 {
+	if x != nil {
+		return x.AbortLsn
+	}
+	return 0
+}
+
+type PrepareTransaction struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid         uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	Gid         string `protobuf:"bytes,2,opt,name=gid,proto3" json:"gid,omitempty"`
+	PrepareLsn  uint64 `protobuf:"varint,3,opt,name=prepare_lsn,json=prepareLsn,proto3" json:"prepare_lsn,omitempty"`
+	EndLsn      uint64 `protobuf:"varint,4,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	PrepareTime int64  `protobuf:"varint,5,opt,name=prepare_time,json=prepareTime,proto3" json:"prepare_time,omitempty"`
+}
+
+func (x *PrepareTransaction) Reset() {
+	*x = PrepareTransaction{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PrepareTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrepareTransaction) ProtoMessage() {}
+
+func (x *PrepareTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrepareTransaction.ProtoReflect.Descriptor instead.
+func (*PrepareTransaction) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PrepareTransaction) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *PrepareTransaction) GetGid() string {
+	if x != nil {
+		return x.Gid
+	}
+	return ""
+}
+
+func (x *PrepareTransaction) GetPrepareLsn() uint64 {
+	if x != nil {
+		return x.PrepareLsn
+	}
+	return 0
+}
+
+func (x *PrepareTransaction) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *PrepareTransaction) GetPrepareTime() int64 {
+	if x != nil {
+		return x.PrepareTime
+	}
+	return 0
+}
+
+type CommitPrepared struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid        uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	Gid        string `protobuf:"bytes,2,opt,name=gid,proto3" json:"gid,omitempty"`
+	CommitLsn  uint64 `protobuf:"varint,3,opt,name=commit_lsn,json=commitLsn,proto3" json:"commit_lsn,omitempty"`
+	EndLsn     uint64 `protobuf:"varint,4,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	CommitTime int64  `protobuf:"varint,5,opt,name=commit_time,json=commitTime,proto3" json:"commit_time,omitempty"`
+}
+
+func (x *CommitPrepared) Reset() {
+	*x = CommitPrepared{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CommitPrepared) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommitPrepared) ProtoMessage() {}
+
+func (x *CommitPrepared) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommitPrepared.ProtoReflect.Descriptor instead.
+func (*CommitPrepared) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CommitPrepared) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *CommitPrepared) GetGid() string {
+	if x != nil {
+		return x.Gid
+	}
+	return ""
+}
+
+func (x *CommitPrepared) GetCommitLsn() uint64 {
+	if x != nil {
+		return x.CommitLsn
+	}
+	return 0
+}
+
+func (x *CommitPrepared) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *CommitPrepared) GetCommitTime() int64 {
+	if x != nil {
+		return x.CommitTime
+	}
+	return 0
+}
+
+type RollbackPrepared struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid           uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	Gid           string `protobuf:"bytes,2,opt,name=gid,proto3" json:"gid,omitempty"`
+	PrepareEndLsn uint64 `protobuf:"varint,3,opt,name=prepare_end_lsn,json=prepareEndLsn,proto3" json:"prepare_end_lsn,omitempty"`
+	EndLsn        uint64 `protobuf:"varint,4,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	PrepareTime   int64  `protobuf:"varint,5,opt,name=prepare_time,json=prepareTime,proto3" json:"prepare_time,omitempty"`
+	RollbackTime  int64  `protobuf:"varint,6,opt,name=rollback_time,json=rollbackTime,proto3" json:"rollback_time,omitempty"`
+}
+
+func (x *RollbackPrepared) Reset() {
+	*x = RollbackPrepared{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RollbackPrepared) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackPrepared) ProtoMessage() {}
+
+func (x *RollbackPrepared) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackPrepared.ProtoReflect.Descriptor instead.
+func (*RollbackPrepared) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RollbackPrepared) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *RollbackPrepared) GetGid() string {
+	if x != nil {
+		return x.Gid
+	}
+	return ""
+}
+
+func (x *RollbackPrepared) GetPrepareEndLsn() uint64 {
+	if x != nil {
+		return x.PrepareEndLsn
+	}
+	return 0
+}
+
+func (x *RollbackPrepared) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *RollbackPrepared) GetPrepareTime() int64 {
+	if x != nil {
+		return x.PrepareTime
+	}
+	return 0
+}
+
+func (x *RollbackPrepared) GetRollbackTime() int64 {
+	if x != nil {
+		return x.RollbackTime
+	}
+	return 0
+}
+
+type StreamPrepare struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid         uint32 `protobuf:"varint,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	Gid         string `protobuf:"bytes,2,opt,name=gid,proto3" json:"gid,omitempty"`
+	PrepareLsn  uint64 `protobuf:"varint,3,opt,name=prepare_lsn,json=prepareLsn,proto3" json:"prepare_lsn,omitempty"`
+	EndLsn      uint64 `protobuf:"varint,4,opt,name=end_lsn,json=endLsn,proto3" json:"end_lsn,omitempty"`
+	PrepareTime int64  `protobuf:"varint,5,opt,name=prepare_time,json=prepareTime,proto3" json:"prepare_time,omitempty"`
+}
+
+func (x *StreamPrepare) Reset() {
+	*x = StreamPrepare{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamPrepare) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamPrepare) ProtoMessage() {}
+
+func (x *StreamPrepare) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamPrepare.ProtoReflect.Descriptor instead.
+func (*StreamPrepare) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamPrepare) GetXid() uint32 {
+	if x != nil {
+		return x.Xid
+	}
+	return 0
+}
+
+func (x *StreamPrepare) GetGid() string {
+	if x != nil {
+		return x.Gid
+	}
+	return ""
+}
+
+func (x *StreamPrepare) GetPrepareLsn() uint64 {
+	if x != nil {
+		return x.PrepareLsn
+	}
+	return 0
+}
+
+func (x *StreamPrepare) GetEndLsn() uint64 {
+	if x != nil {
+		return x.EndLsn
+	}
+	return 0
+}
+
+func (x *StreamPrepare) GetPrepareTime() int64 {
+	if x != nil {
+		return x.PrepareTime
+	}
+	return 0
+}
+
+type InsertDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Table     *TableDescription    `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	NewValues *FieldSetDescription `protobuf:"bytes,3,opt,name=new_values,json=newValues,proto3" json:"new_values,omitempty"`
+	StreamXid uint32               `protobuf:"varint,15,opt,name=stream_xid,json=streamXid,proto3" json:"stream_xid,omitempty"`
+}
+
+func (x *InsertDescription) Reset() {
+	*x = InsertDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InsertDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InsertDescription) ProtoMessage() {}
+
+func (x *InsertDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InsertDescription.ProtoReflect.Descriptor instead.
+func (*InsertDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *InsertDescription) GetTable() *TableDescription {
+	if x != nil {
+		return x.Table
+	}
+	return nil
+}
+
+func (x *InsertDescription) GetNewValues() *FieldSetDescription {
+	if x != nil {
+		return x.NewValues
+	}
+	return nil
+}
+
+func (x *InsertDescription) GetStreamXid() uint32 {
+	if x != nil {
+		return x.StreamXid
+	}
+	return 0
+}
+
+type UpdateDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Table     *TableDescription    `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	KeyFields *FieldSetDescription `protobuf:"bytes,3,opt,name=key_fields,json=keyFields,proto3" json:"key_fields,omitempty"`
+	NewValues *FieldSetDescription `protobuf:"bytes,5,opt,name=new_values,json=newValues,proto3" json:"new_values,omitempty"`
+	OldValues *FieldSetDescription `protobuf:"bytes,7,opt,name=old_values,json=oldValues,proto3" json:"old_values,omitempty"`
+	StreamXid uint32               `protobuf:"varint,15,opt,name=stream_xid,json=streamXid,proto3" json:"stream_xid,omitempty"`
+}
+
+func (x *UpdateDescription) Reset() {
+	*x = UpdateDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateDescription) ProtoMessage() {}
+
+func (x *UpdateDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateDescription.ProtoReflect.Descriptor instead.
+func (*UpdateDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *UpdateDescription) GetTable() *TableDescription {
+	if x != nil {
+		return x.Table
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetKeyFields() *FieldSetDescription {
+	if x != nil {
+		return x.KeyFields
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetNewValues() *FieldSetDescription {
+	if x != nil {
+		return x.NewValues
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetOldValues() *FieldSetDescription {
+	if x != nil {
+		return x.OldValues
+	}
+	return nil
+}
+
+func (x *UpdateDescription) GetStreamXid() uint32 {
+	if x != nil {
+		return x.StreamXid
+	}
+	return 0
+}
+
+type DeleteDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Table     *TableDescription    `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
+	KeyFields *FieldSetDescription `protobuf:"bytes,3,opt,name=key_fields,json=keyFields,proto3" json:"key_fields,omitempty"`
+	OldValues *FieldSetDescription `protobuf:"bytes,5,opt,name=old_values,json=oldValues,proto3" json:"old_values,omitempty"`
+	StreamXid uint32               `protobuf:"varint,15,opt,name=stream_xid,json=streamXid,proto3" json:"stream_xid,omitempty"`
+}
+
+func (x *DeleteDescription) Reset() {
+	*x = DeleteDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDescription) ProtoMessage() {}
+
+func (x *DeleteDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDescription.ProtoReflect.Descriptor instead.
+func (*DeleteDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteDescription) GetTable() *TableDescription {
+	if x != nil {
+		return x.Table
+	}
+	return nil
+}
+
+func (x *DeleteDescription) GetKeyFields() *FieldSetDescription {
+	if x != nil {
+		return x.KeyFields
+	}
+	return nil
+}
+
+func (x *DeleteDescription) GetOldValues() *FieldSetDescription {
+	if x != nil {
+		return x.OldValues
+	}
+	return nil
+}
+
+func (x *DeleteDescription) GetStreamXid() uint32 {
+	if x != nil {
+		return x.StreamXid
+	}
+	return 0
+}
+
+type TruncateDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Tables          []*TableDescription `protobuf:"bytes,1,rep,name=tables,proto3" json:"tables,omitempty"`
+	Cascade         bool                `protobuf:"varint,2,opt,name=cascade,proto3" json:"cascade,omitempty"`
+	RestartIdentity bool                `protobuf:"varint,3,opt,name=restart_identity,json=restartIdentity,proto3" json:"restart_identity,omitempty"`
+	StreamXid       uint32              `protobuf:"varint,15,opt,name=stream_xid,json=streamXid,proto3" json:"stream_xid,omitempty"`
+}
+
+func (x *TruncateDescription) Reset() {
+	*x = TruncateDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TruncateDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TruncateDescription) ProtoMessage() {}
+
+func (x *TruncateDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TruncateDescription.ProtoReflect.Descriptor instead.
+func (*TruncateDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TruncateDescription) GetTables() []*TableDescription {
+	if x != nil {
+		return x.Tables
+	}
+	return nil
+}
+
+func (x *TruncateDescription) GetCascade() bool {
+	if x != nil {
+		return x.Cascade
+	}
+	return false
+}
+
+func (x *TruncateDescription) GetRestartIdentity() bool {
+	if x != nil {
+		return x.RestartIdentity
+	}
+	return false
+}
+
+func (x *TruncateDescription) GetStreamXid() uint32 {
+	if x != nil {
+		return x.StreamXid
+	}
+	return 0
+}
+
+type LogicalMessage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Prefix        string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Content       []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Transactional bool   `protobuf:"varint,3,opt,name=transactional,proto3" json:"transactional,omitempty"`
+	Lsn           uint64 `protobuf:"varint,4,opt,name=lsn,proto3" json:"lsn,omitempty"`
+	StreamXid     uint32 `protobuf:"varint,15,opt,name=stream_xid,json=streamXid,proto3" json:"stream_xid,omitempty"`
+}
+
+func (x *LogicalMessage) Reset() {
+	*x = LogicalMessage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LogicalMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogicalMessage) ProtoMessage() {}
+
+func (x *LogicalMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogicalMessage.ProtoReflect.Descriptor instead.
+func (*LogicalMessage) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *LogicalMessage) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *LogicalMessage) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *LogicalMessage) GetTransactional() bool {
+	if x != nil {
+		return x.Transactional
+	}
+	return false
+}
+
+func (x *LogicalMessage) GetLsn() uint64 {
+	if x != nil {
+		return x.Lsn
+	}
+	return 0
+}
+
+func (x *LogicalMessage) GetStreamXid() uint32 {
+	if x != nil {
+		return x.StreamXid
+	}
+	return 0
+}
+
+type SequenceDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SchemaName   string `protobuf:"bytes,1,opt,name=schema_name,json=schemaName,proto3" json:"schema_name,omitempty"`
+	SequenceName string `protobuf:"bytes,2,opt,name=sequence_name,json=sequenceName,proto3" json:"sequence_name,omitempty"`
+	SequenceOid  uint32 `protobuf:"varint,3,opt,name=sequence_oid,json=sequenceOid,proto3" json:"sequence_oid,omitempty"`
+	LastValue    int64  `protobuf:"varint,4,opt,name=last_value,json=lastValue,proto3" json:"last_value,omitempty"`
+	IsCalled     bool   `protobuf:"varint,5,opt,name=is_called,json=isCalled,proto3" json:"is_called,omitempty"`
+}
+
+func (x *SequenceDescription) Reset() {
+	*x = SequenceDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SequenceDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SequenceDescription) ProtoMessage() {}
+
+func (x *SequenceDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SequenceDescription.ProtoReflect.Descriptor instead.
+func (*SequenceDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SequenceDescription) GetSchemaName() string {
+	if x != nil {
+		return x.SchemaName
+	}
+	return ""
+}
+
+func (x *SequenceDescription) GetSequenceName() string {
+	if x != nil {
+		return x.SequenceName
+	}
+	return ""
+}
+
+func (x *SequenceDescription) GetSequenceOid() uint32 {
+	if x != nil {
+		return x.SequenceOid
+	}
+	return 0
+}
+
+func (x *SequenceDescription) GetLastValue() int64 {
+	if x != nil {
+		return x.LastValue
+	}
+	return 0
+}
+
+func (x *SequenceDescription) GetIsCalled() bool {
+	if x != nil {
+		return x.IsCalled
+	}
+	return false
+}
+
+type RelationDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RelationId    uint32   `protobuf:"varint,1,opt,name=relation_id,json=relationId,proto3" json:"relation_id,omitempty"`
+	SchemaName    string   `protobuf:"bytes,2,opt,name=schema_name,json=schemaName,proto3" json:"schema_name,omitempty"`
+	TableName     string   `protobuf:"bytes,3,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	ColumnNames   []string `protobuf:"bytes,4,rep,name=column_names,json=columnNames,proto3" json:"column_names,omitempty"`
+	TypeOids      []uint32 `protobuf:"varint,5,rep,packed,name=type_oids,json=typeOids,proto3" json:"type_oids,omitempty"`
+	TypeModifiers []int32  `protobuf:"varint,6,rep,packed,name=type_modifiers,json=typeModifiers,proto3" json:"type_modifiers,omitempty"`
+	KeyColumns    []uint32 `protobuf:"varint,7,rep,packed,name=key_columns,json=keyColumns,proto3" json:"key_columns,omitempty"`
+}
+
+func (x *RelationDescription) Reset() {
+	*x = RelationDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RelationDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationDescription) ProtoMessage() {}
+
+func (x *RelationDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelationDescription.ProtoReflect.Descriptor instead.
+func (*RelationDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RelationDescription) GetRelationId() uint32 {
+	if x != nil {
+		return x.RelationId
+	}
+	return 0
+}
+
+func (x *RelationDescription) GetSchemaName() string {
+	if x != nil {
+		return x.SchemaName
+	}
+	return ""
+}
+
+func (x *RelationDescription) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *RelationDescription) GetColumnNames() []string {
+	if x != nil {
+		return x.ColumnNames
+	}
+	return nil
+}
+
+func (x *RelationDescription) GetTypeOids() []uint32 {
+	if x != nil {
+		return x.TypeOids
+	}
+	return nil
+}
+
+func (x *RelationDescription) GetTypeModifiers() []int32 {
+	if x != nil {
+		return x.TypeModifiers
+	}
+	return nil
+}
+
+func (x *RelationDescription) GetKeyColumns() []uint32 {
+	if x != nil {
+		return x.KeyColumns
+	}
+	return nil
+}
+
+type TableDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SchemaName string `protobuf:"bytes,1,opt,name=schema_name,json=schemaName,proto3" json:"schema_name,omitempty"`
+	TableName  string `protobuf:"bytes,2,opt,name=table_name,json=tableName,proto3" json:"table_name,omitempty"`
+	TableOid   uint32 `protobuf:"varint,3,opt,name=table_oid,json=tableOid,proto3" json:"table_oid,omitempty"`
+}
+
+func (x *TableDescription) Reset() {
+	*x = TableDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TableDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TableDescription) ProtoMessage() {}
+
+func (x *TableDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TableDescription.ProtoReflect.Descriptor instead.
+func (*TableDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *TableDescription) GetSchemaName() string {
+	if x != nil {
+		return x.SchemaName
+	}
+	return ""
+}
+
+func (x *TableDescription) GetTableName() string {
+	if x != nil {
+		return x.TableName
+	}
+	return ""
+}
+
+func (x *TableDescription) GetTableOid() uint32 {
+	if x != nil {
+		return x.TableOid
+	}
+	return 0
+}
+
+type FieldSetDescription struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Names          []string `protobuf:"bytes,2,rep,name=names,proto3" json:"names,omitempty"`
+	Values         [][]byte `protobuf:"bytes,3,rep,name=values,proto3" json:"values,omitempty"`
+	TypeOids       []uint32 `protobuf:"varint,4,rep,packed,name=type_oids,json=typeOids,proto3" json:"type_oids,omitempty"`
+	Nulls          []byte   `protobuf:"bytes,5,opt,name=nulls,proto3" json:"nulls,omitempty"`
+	Formats        []byte   `protobuf:"bytes,6,opt,name=formats,proto3" json:"formats,omitempty"`
+	ColumnNumbers  []uint32 `protobuf:"varint,7,rep,packed,name=column_numbers,json=columnNumbers,proto3" json:"column_numbers,omitempty"`
+	UnchangedToast []byte   `protobuf:"bytes,8,opt,name=unchanged_toast,json=unchangedToast,proto3" json:"unchanged_toast,omitempty"`
+}
+
+func (x *FieldSetDescription) Reset() {
+	*x = FieldSetDescription{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pg_pb3_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FieldSetDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldSetDescription) ProtoMessage() {}
+
+func (x *FieldSetDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_pg_pb3_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldSetDescription.ProtoReflect.Descriptor instead.
+func (*FieldSetDescription) Descriptor() ([]byte, []int) {
+	return file_pg_pb3_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *FieldSetDescription) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetValues() [][]byte {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetTypeOids() []uint32 {
+	if x != nil {
+		return x.TypeOids
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetNulls() []byte {
+	if x != nil {
+		return x.Nulls
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetFormats() []byte {
+	if x != nil {
+		return x.Formats
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetColumnNumbers() []uint32 {
+	if x != nil {
+		return x.ColumnNumbers
+	}
+	return nil
+}
+
+func (x *FieldSetDescription) GetUnchangedToast() []byte {
+	if x != nil {
+		return x.UnchangedToast
+	}
+	return nil
+}
+
+var File_pg_pb3_proto protoreflect.FileDescriptor
+
+var file_pg_pb3_proto_rawDesc = []byte{
+	0x0a, 0x0c, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x09,
+	0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x22, 0xd0, 0x01, 0x0a, 0x11, 0x57, 0x69,
+	0x72, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12,
+	0x30, 0x0a, 0x05, 0x74, 0x79, 0x70, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x1a,
+	0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x57, 0x69, 0x72, 0x65, 0x4d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x54, 0x79, 0x70, 0x65, 0x52, 0x05, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x05, 0x52, 0x07, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x12, 0x3e, 0x0a, 0x0b, 0x63,
+	0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x1c, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x43, 0x6f, 0x6d,
+	0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x52, 0x0b,
+	0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2f, 0x0a, 0x13, 0x75,
+	0x6e, 0x63, 0x6f, 0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x5f, 0x6c, 0x65, 0x6e, 0x67,
+	0x74, 0x68, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x75, 0x6e, 0x63, 0x6f, 0x6d, 0x70,
+	0x72, 0x65, 0x73, 0x73, 0x65, 0x64, 0x4c, 0x65, 0x6e, 0x67, 0x74, 0x68, 0x22, 0x39, 0x0a, 0x0c,
+	0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x29, 0x0a, 0x10,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x83, 0x01, 0x0a, 0x10, 0x42, 0x65, 0x67, 0x69,
+	0x6e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03,
+	0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x1b,
+	0x0a, 0x09, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x08, 0x66, 0x69, 0x6e, 0x61, 0x6c, 0x4c, 0x73, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x6f, 0x72, 0x69, 0x67, 0x69, 0x6e, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0x7e, 0x0a,
+	0x11, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x03, 0x78, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x6c,
+	0x73, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x4c, 0x73, 0x6e, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x1f, 0x0a, 0x0b,
+	0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x44, 0x0a,
+	0x0b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x61, 0x72, 0x74, 0x12, 0x10, 0x0a, 0x03,
+	0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x23,
+	0x0a, 0x0d, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x66, 0x69, 0x72, 0x73, 0x74, 0x53, 0x65, 0x67, 0x6d,
+	0x65, 0x6e, 0x74, 0x22, 0x1e, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x74, 0x6f,
+	0x70, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03,
+	0x78, 0x69, 0x64, 0x22, 0x79, 0x0a, 0x0c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x43, 0x6f, 0x6d,
+	0x6d, 0x69, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f,
+	0x6c, 0x73, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x4c, 0x73, 0x6e, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x1f, 0x0a,
+	0x0b, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x54,
+	0x0a, 0x0b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12,
+	0x16, 0x0a, 0x06, 0x73, 0x75, 0x62, 0x78, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x06, 0x73, 0x75, 0x62, 0x78, 0x69, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x61, 0x62, 0x6f, 0x72, 0x74,
+	0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x61, 0x62, 0x6f, 0x72,
+	0x74, 0x4c, 0x73, 0x6e, 0x22, 0x95, 0x01, 0x0a, 0x12, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x78,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x10, 0x0a,
+	0x03, 0x67, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x67, 0x69, 0x64, 0x12,
+	0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x4c, 0x73, 0x6e,
+	0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x72, 0x65,
+	0x70, 0x61, 0x72, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0b, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x8d, 0x01, 0x0a,
+	0x0e, 0x43, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x64, 0x12,
+	0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78, 0x69,
+	0x64, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x67, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x6c, 0x73,
+	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x4c,
+	0x73, 0x6e, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x63,
+	0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xbf, 0x01, 0x0a,
+	0x10, 0x52, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65,
+	0x64, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03,
+	0x78, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x67, 0x69, 0x64, 0x12, 0x26, 0x0a, 0x0f, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65,
+	0x5f, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d,
+	0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x45, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x17, 0x0a,
+	0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06,
+	0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72,
+	0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x70, 0x72,
+	0x65, 0x70, 0x61, 0x72, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x6f, 0x6c,
+	0x6c, 0x62, 0x61, 0x63, 0x6b, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0c, 0x72, 0x6f, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x90,
+	0x01, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x50, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65,
+	0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x78,
+	0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x67, 0x69, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x5f,
+	0x6c, 0x73, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x70, 0x72, 0x65, 0x70, 0x61,
+	0x72, 0x65, 0x4c, 0x73, 0x6e, 0x12, 0x17, 0x0a, 0x07, 0x65, 0x6e, 0x64, 0x5f, 0x6c, 0x73, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x65, 0x6e, 0x64, 0x4c, 0x73, 0x6e, 0x12, 0x21,
+	0x0a, 0x0c, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x70, 0x72, 0x65, 0x70, 0x61, 0x72, 0x65, 0x54, 0x69, 0x6d,
+	0x65, 0x22, 0xa4, 0x01, 0x0a, 0x11, 0x49, 0x6e, 0x73, 0x65, 0x72, 0x74, 0x44, 0x65, 0x73, 0x63,
+	0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x31, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f,
+	0x6c, 0x64, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x3d, 0x0a, 0x0a, 0x6e, 0x65,
+	0x77, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64,
+	0x53, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09,
+	0x6e, 0x65, 0x77, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x5f, 0x78, 0x69, 0x64, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x58, 0x69, 0x64, 0x22, 0xa2, 0x02, 0x0a, 0x11, 0x55, 0x70, 0x64,
+	0x61, 0x74, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x31,
+	0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e,
+	0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x44,
+	0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x05, 0x74, 0x61, 0x62, 0x6c,
+	0x65, 0x12, 0x3d, 0x0a, 0x0a, 0x6b, 0x65, 0x79, 0x5f, 0x66, 0x69, 0x65, 0x6c, 0x64, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c,
+	0x64, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6b, 0x65, 0x79, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x73,
+	0x12, 0x3d, 0x0a, 0x0a, 0x6e, 0x65, 0x77, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64,
+	0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6e, 0x65, 0x77, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12,
+	0x3d, 0x0a, 0x0a, 0x6f, 0x6c, 0x64, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e,
+	0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6f, 0x6c, 0x64, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x1d,
+	0x0a, 0x0a, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x78, 0x69, 0x64, 0x18, 0x0f, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x58, 0x69, 0x64, 0x22, 0xe3, 0x01,
+	0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x31, 0x0a, 0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x67, 0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x54,
+	0x61, 0x62, 0x6c, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x05, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x3d, 0x0a, 0x0a, 0x6b, 0x65, 0x79, 0x5f, 0x66, 0x69,
+	0x65, 0x6c, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x67, 0x5f,
+	0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44,
+	0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6b, 0x65, 0x79, 0x46,
+	0x69, 0x65, 0x6c, 0x64, 0x73, 0x12, 0x3d, 0x0a, 0x0a, 0x6f, 0x6c, 0x64, 0x5f, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x67, 0x5f, 0x70,
+	0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44, 0x65,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6f, 0x6c, 0x64, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x78,
+	0x69, 0x64, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x58, 0x69, 0x64, 0x22, 0xae, 0x01, 0x0a, 0x13, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65,
+	0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x33, 0x0a, 0x06, 0x74,
+	0x61, 0x62, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x70, 0x67,
+	0x5f, 0x70, 0x62, 0x33, 0x5f, 0x6c, 0x64, 0x2e, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x44, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x73,
+	0x12, 0x18, 0x0a, 0x07, 0x63, 0x61, 0x73, 0x63, 0x61, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x63, 0x61, 0x73, 0x63, 0x61, 0x64, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65,
+	0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x74, 0x79, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0f, 0x72, 0x65, 0x73, 0x74, 0x61, 0x72, 0x74, 0x49, 0x64, 0x65,
+	0x6e, 0x74, 0x69, 0x74, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f,
+	0x78, 0x69, 0x64, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x58, 0x69, 0x64, 0x22, 0x99, 0x01, 0x0a, 0x0e, 0x4c, 0x6f, 0x67, 0x69, 0x63, 0x61, 0x6c,
+	0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69,
+	0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12,
+	0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x74, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0d, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x12,
+	0x10, 0x0a, 0x03, 0x6c, 0x73, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6c, 0x73,
+	0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x5f, 0x78, 0x69, 0x64, 0x18,
+	0x0f, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x58, 0x69, 0x64,
+	0x22, 0xba, 0x01, 0x0a, 0x13, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x44, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x63, 0x68, 0x65,
+	0x6d, 0x61, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73,
+	0x63, 0x68, 0x65, 0x6d, 0x61, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x65, 0x71,
+	0x75, 0x65, 0x6e, 0x63, 0x65, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0c, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x21,
+	0x0a, 0x0c, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x5f, 0x6f, 0x69, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x73, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x4f, 0x69,
+	0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x6c, 0x61, 0x73, 0x74, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x12, 0x1b, 0x0a, 0x09, 0x69, 0x73, 0x5f, 0x63, 0x61, 0x6c, 0x6c, 0x65, 0x64, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x08, 0x69, 0x73, 0x43, 0x61, 0x6c, 0x6c, 0x65, 0x64, 0x22, 0xfe, 0x01,
+	0x0a, 0x13, 0x52, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x72, 0x65, 0x6c, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x49, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x63, 0x68,
+	0x65, 0x6d, 0x61, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x61, 0x62, 0x6c, 0x65,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x61, 0x62,
+	0x6c, 0x65, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e,
+	0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0b, 0x63, 0x6f,
+	0x6c, 0x75, 0x6d, 0x6e, 0x4e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x79, 0x70,
+	0x65, 0x5f, 0x6f, 0x69, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x08, 0x74, 0x79,
+	0x70, 0x65, 0x4f, 0x69, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x74, 0x79, 0x70, 0x65, 0x5f, 0x6d,
+	0x6f, 0x64, 0x69, 0x66, 0x69, 0x65, 0x72, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x05, 0x52, 0x0d,
+	0x74, 0x79, 0x70, 0x65, 0x4d, 0x6f, 0x64, 0x69, 0x66, 0x69, 0x65, 0x72, 0x73, 0x12, 0x1f, 0x0a,
+	0x0b, 0x6b, 0x65, 0x79, 0x5f, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x18, 0x07, 0x20, 0x03,
+	0x28, 0x0d, 0x52, 0x0a, 0x6b, 0x65, 0x79, 0x43, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x73, 0x22, 0x6f,
+	0x0a, 0x10, 0x54, 0x61, 0x62, 0x6c, 0x65, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x73, 0x63, 0x68, 0x65, 0x6d, 0x61, 0x4e,
+	0x61, 0x6d, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x4e, 0x61,
+	0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x5f, 0x6f, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x4f, 0x69, 0x64, 0x22,
+	0xe0, 0x01, 0x0a, 0x13, 0x46, 0x69, 0x65, 0x6c, 0x64, 0x53, 0x65, 0x74, 0x44, 0x65, 0x73, 0x63,
+	0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x61, 0x6d, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x61, 0x6d, 0x65, 0x73, 0x12, 0x16, 0x0a,
+	0x06, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x79, 0x70, 0x65, 0x5f, 0x6f, 0x69,
+	0x64, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x08, 0x74, 0x79, 0x70, 0x65, 0x4f, 0x69,
+	0x64, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x75, 0x6c, 0x6c, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x05, 0x6e, 0x75, 0x6c, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x66, 0x6f, 0x72, 0x6d,
+	0x61, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x66, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x63, 0x6f, 0x6c, 0x75, 0x6d, 0x6e, 0x5f, 0x6e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0d, 0x52, 0x0d, 0x63, 0x6f, 0x6c, 0x75,
+	0x6d, 0x6e, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x0f, 0x75, 0x6e, 0x63,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x5f, 0x74, 0x6f, 0x61, 0x73, 0x74, 0x18, 0x08, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x0e, 0x75, 0x6e, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x54, 0x6f, 0x61,
+	0x73, 0x74, 0x2a, 0x85, 0x03, 0x0a, 0x0f, 0x57, 0x69, 0x72, 0x65, 0x4d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0e, 0x0a, 0x0a, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x42,
+	0x45, 0x47, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x43,
+	0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x57, 0x4d, 0x53, 0x47, 0x5f,
+	0x49, 0x4e, 0x53, 0x45, 0x52, 0x54, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x57, 0x4d, 0x53, 0x47,
+	0x5f, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x10, 0x03, 0x12, 0x0f, 0x0a, 0x0b, 0x57, 0x4d, 0x53,
+	0x47, 0x5f, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x10, 0x04, 0x12, 0x11, 0x0a, 0x0d, 0x57, 0x4d,
+	0x53, 0x47, 0x5f, 0x54, 0x52, 0x55, 0x4e, 0x43, 0x41, 0x54, 0x45, 0x10, 0x05, 0x12, 0x10, 0x0a,
+	0x0c, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x4d, 0x45, 0x53, 0x53, 0x41, 0x47, 0x45, 0x10, 0x06, 0x12,
+	0x11, 0x0a, 0x0d, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x52, 0x45, 0x4c, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x10, 0x07, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41,
+	0x4d, 0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x10, 0x08, 0x12, 0x14, 0x0a, 0x10, 0x57, 0x4d, 0x53,
+	0x47, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x53, 0x54, 0x4f, 0x50, 0x10, 0x09, 0x12,
+	0x16, 0x0a, 0x12, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x43,
+	0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x10, 0x0a, 0x12, 0x15, 0x0a, 0x11, 0x57, 0x4d, 0x53, 0x47, 0x5f,
+	0x53, 0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x41, 0x42, 0x4f, 0x52, 0x54, 0x10, 0x0b, 0x12, 0x10,
+	0x0a, 0x0c, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x50, 0x52, 0x45, 0x50, 0x41, 0x52, 0x45, 0x10, 0x0c,
+	0x12, 0x18, 0x0a, 0x14, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x43, 0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x5f,
+	0x50, 0x52, 0x45, 0x50, 0x41, 0x52, 0x45, 0x44, 0x10, 0x0d, 0x12, 0x1a, 0x0a, 0x16, 0x57, 0x4d,
+	0x53, 0x47, 0x5f, 0x52, 0x4f, 0x4c, 0x4c, 0x42, 0x41, 0x43, 0x4b, 0x5f, 0x50, 0x52, 0x45, 0x50,
+	0x41, 0x52, 0x45, 0x44, 0x10, 0x0e, 0x12, 0x17, 0x0a, 0x13, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x53,
+	0x54, 0x52, 0x45, 0x41, 0x4d, 0x5f, 0x50, 0x52, 0x45, 0x50, 0x41, 0x52, 0x45, 0x10, 0x0f, 0x12,
+	0x11, 0x0a, 0x0d, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x45, 0x51, 0x55, 0x45, 0x4e, 0x43, 0x45,
+	0x10, 0x10, 0x12, 0x16, 0x0a, 0x12, 0x57, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x45, 0x53, 0x53, 0x49,
+	0x4f, 0x4e, 0x5f, 0x53, 0x54, 0x41, 0x52, 0x54, 0x10, 0x11, 0x2a, 0x6a, 0x0a, 0x11, 0x43, 0x6f,
+	0x6d, 0x70, 0x72, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12,
+	0x14, 0x0a, 0x10, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x4e,
+	0x4f, 0x4e, 0x45, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53,
+	0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x50, 0x47, 0x4c, 0x5a, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x43,
+	0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x4c, 0x5a, 0x34, 0x10, 0x02,
+	0x12, 0x14, 0x0a, 0x10, 0x43, 0x4f, 0x4d, 0x50, 0x52, 0x45, 0x53, 0x53, 0x49, 0x4f, 0x4e, 0x5f,
+	0x5a, 0x53, 0x54, 0x44, 0x10, 0x03, 0x42, 0x1c, 0x5a, 0x1a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6a, 0x6f, 0x68, 0x74, 0x6f, 0x2f, 0x70, 0x67, 0x5f, 0x70, 0x62,
+	0x33, 0x5f, 0x6c, 0x64, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pg_pb3_proto_rawDescOnce sync.Once
+	file_pg_pb3_proto_rawDescData = file_pg_pb3_proto_rawDesc
+)
+
+func file_pg_pb3_proto_rawDescGZIP() []byte {
+	file_pg_pb3_proto_rawDescOnce.Do(func() {
+		file_pg_pb3_proto_rawDescData = protoimpl.X.CompressGZIP(file_pg_pb3_proto_rawDescData)
+	})
+	return file_pg_pb3_proto_rawDescData
+}
+
+var file_pg_pb3_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pg_pb3_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_pg_pb3_proto_goTypes = []interface{}{
+	(WireMessageType)(0),        // 0: pg_pb3_ld.WireMessageType
+	(CompressionMethod)(0),      // 1: pg_pb3_ld.CompressionMethod
+	(*WireMessageHeader)(nil),   // 2: pg_pb3_ld.WireMessageHeader
+	(*SessionStart)(nil),        // 3: pg_pb3_ld.SessionStart
+	(*BeginTransaction)(nil),    // 4: pg_pb3_ld.BeginTransaction
+	(*CommitTransaction)(nil),   // 5: pg_pb3_ld.CommitTransaction
+	(*StreamStart)(nil),         // 6: pg_pb3_ld.StreamStart
+	(*StreamStop)(nil),          // 7: pg_pb3_ld.StreamStop
+	(*StreamCommit)(nil),        // 8: pg_pb3_ld.StreamCommit
+	(*StreamAbort)(nil),         // 9: pg_pb3_ld.StreamAbort
+	(*PrepareTransaction)(nil),  // 10: pg_pb3_ld.PrepareTransaction
+	(*CommitPrepared)(nil),      // 11: pg_pb3_ld.CommitPrepared
+	(*RollbackPrepared)(nil),    // 12: pg_pb3_ld.RollbackPrepared
+	(*StreamPrepare)(nil),       // 13: pg_pb3_ld.StreamPrepare
+	(*InsertDescription)(nil),   // 14: pg_pb3_ld.InsertDescription
+	(*UpdateDescription)(nil),   // 15: pg_pb3_ld.UpdateDescription
+	(*DeleteDescription)(nil),   // 16: pg_pb3_ld.DeleteDescription
+	(*TruncateDescription)(nil), // 17: pg_pb3_ld.TruncateDescription
+	(*LogicalMessage)(nil),      // 18: pg_pb3_ld.LogicalMessage
+	(*SequenceDescription)(nil), // 19: pg_pb3_ld.SequenceDescription
+	(*RelationDescription)(nil), // 20: pg_pb3_ld.RelationDescription
+	(*TableDescription)(nil),    // 21: pg_pb3_ld.TableDescription
+	(*FieldSetDescription)(nil), // 22: pg_pb3_ld.FieldSetDescription
+}
+var file_pg_pb3_proto_depIdxs = []int32{
+	0,  // 0: pg_pb3_ld.WireMessageHeader.types:type_name -> pg_pb3_ld.WireMessageType
+	1,  // 1: pg_pb3_ld.WireMessageHeader.compression:type_name -> pg_pb3_ld.CompressionMethod
+	21, // 2: pg_pb3_ld.InsertDescription.table:type_name -> pg_pb3_ld.TableDescription
+	22, // 3: pg_pb3_ld.InsertDescription.new_values:type_name -> pg_pb3_ld.FieldSetDescription
+	21, // 4: pg_pb3_ld.UpdateDescription.table:type_name -> pg_pb3_ld.TableDescription
+	22, // 5: pg_pb3_ld.UpdateDescription.key_fields:type_name -> pg_pb3_ld.FieldSetDescription
+	22, // 6: pg_pb3_ld.UpdateDescription.new_values:type_name -> pg_pb3_ld.FieldSetDescription
+	22, // 7: pg_pb3_ld.UpdateDescription.old_values:type_name -> pg_pb3_ld.FieldSetDescription
+	21, // 8: pg_pb3_ld.DeleteDescription.table:type_name -> pg_pb3_ld.TableDescription
+	22, // 9: pg_pb3_ld.DeleteDescription.key_fields:type_name -> pg_pb3_ld.FieldSetDescription
+	22, // 10: pg_pb3_ld.DeleteDescription.old_values:type_name -> pg_pb3_ld.FieldSetDescription
+	21, // 11: pg_pb3_ld.TruncateDescription.tables:type_name -> pg_pb3_ld.TableDescription
+	12, // [12:12] is the sub-list for method output_type
+	12, // [12:12] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_pg_pb3_proto_init() }
+func file_pg_pb3_proto_init() {
+	if File_pg_pb3_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pg_pb3_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WireMessageHeader); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionStart); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BeginTransaction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommitTransaction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamStart); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamStop); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamCommit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamAbort); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PrepareTransaction); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CommitPrepared); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RollbackPrepared); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamPrepare); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InsertDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TruncateDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogicalMessage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SequenceDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RelationDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TableDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pg_pb3_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FieldSetDescription); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_pg_pb3_proto_rawDesc,
+			NumEnums:      2,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pg_pb3_proto_goTypes,
+		DependencyIndexes: file_pg_pb3_proto_depIdxs,
+		EnumInfos:         file_pg_pb3_proto_enumTypes,
+		MessageInfos:      file_pg_pb3_proto_msgTypes,
+	}.Build()
+	File_pg_pb3_proto = out.File
+	file_pg_pb3_proto_rawDesc = nil
+	file_pg_pb3_proto_goTypes = nil
+	file_pg_pb3_proto_depIdxs = nil
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func TestColumnProjectionsInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", false, ""},
+		{"public.foo", true, "invalid input syntax for columns"},
+		{"public.foo(a", true, "invalid input syntax for columns"},
+		{"public.foo(a);", true, "invalid input syntax for columns"},
+		{"public.foo()", true, "column names in columns can't be empty"},
+		{"public.foo(a,)", true, "column names in columns can't be empty"},
+		{"public.foo(-)", true, "column names in columns can't be empty"},
+		{"public.foo(a,(b))", true, "invalid input syntax for columns"},
+		{"public.foo(a, -b)", true, "a columns entry can't both include and exclude columns"},
+		{"foo(a)", true, "table patterns must be schema-qualified"},
+		{"public.foo(a)", false, ""},
+		{"public.foo(-a, -b)", false, ""},
+		{" public.foo ( a , b ) ; audit.*(-payload) ", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"columns", test.input,
+		}
+
+		if test.expect_failure && test.expect_error == "" {
+			panic(test.input)
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+func TestColumnProjections(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+UPDATE tbl_identity_index SET f4 = 'baz';
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+DELETE FROM tbl_identity_full;
+COMMIT;
+`
+
+	// the key columns f2 and f3 of tbl_identity_index are always sent
+	options := []string{
+		"columns", "public.tbl_identity_index(f1); public.tbl_identity_*(-f2)",
+	}
+
+	projectedNames := []string{"f1", "f2", "f3"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: projectedNames,
+				Values: createStringValues(3, "1", "2", "foo"),
+				Nulls: createNulls(options,3),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: projectedNames,
+				Values: createStringValues(3, "1", "2", "foo"),
+				Nulls: createNulls(options,3),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"f3", "f2"},
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: []string{"f1"},
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityFullDescription,
+			KeyFields: &FieldSetDescription{
+				Names: []string{"f1"},
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestColumnProjectionsRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var tenk1Oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tenk1'::regclass::oid`).Scan(&tenk1Oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenk1Table := &TableDescription{TableOid: tenk1Oid}
+
+	sql := `
+ALTER TABLE tenk1 ALTER COLUMN string4 SET STORAGE EXTERNAL;
+INSERT INTO tenk1(unique1, unique2, string4)
+SELECT 1, 10, repeat('j', 9001);
+UPDATE tenk1 SET unique2 = 20;
+`
+
+	// without string4, the unchanged TOASTed value is not a problem
+	options := []string{
+		"enable_relation_messages","on",
+		"columns", "public.tenk1(-string4)",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: tenk1Oid,
+			SchemaName: "public",
+			TableName: "tenk1",
+			ColumnNames: tenk1FieldNames[:15],
+			TypeOids: tenk1FieldTypeOids[:15],
+			TypeModifiers: createTypeModifiers(15),
+			KeyColumns: []uint32{0},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(15, "1", "10"),
+				Nulls: createNulls(options,2,13),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(15, "1", "20"),
+				Nulls: createNulls(options,2,13),
+			},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
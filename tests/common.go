@@ -29,7 +29,19 @@ var tblIdentityFullDescription = &TableDescription{
 	TableName: "tbl_identity_full",
 }
 
-func testSetup(t *testing.T) *pgx.Conn {
+var tblIdentityIndexFieldNames = []string{"f1","f2","f3","f4"}
+var tblIdentityIndexDescription = &TableDescription{
+	SchemaName: "public",
+	TableName: "tbl_identity_index",
+}
+
+var tblIdentityDefaultNoPKFieldNames = []string{"f1","f2"}
+var tblIdentityDefaultNoPKDescription = &TableDescription{
+	SchemaName: "public",
+	TableName: "tbl_identity_default_nopk",
+}
+
+func testSetup(t testing.TB) *pgx.Conn {
 	conninfo := strings.Join([]string{
 		"sslmode=disable",
 		// required for predictability
@@ -80,11 +92,32 @@ CREATE TABLE tbl_identity_nothing (
 );
 ALTER TABLE tbl_identity_nothing REPLICA IDENTITY NOTHING;
 DROP TABLE IF EXISTS tbl_identity_full;
+DROP TABLE IF EXISTS tbl_identity_full_renamed;
 CREATE TABLE tbl_identity_full (
 	f1 int4,
 	f2 text
 );
 ALTER TABLE tbl_identity_full REPLICA IDENTITY FULL;
+DROP TABLE IF EXISTS tbl_identity_index;
+CREATE TABLE tbl_identity_index (
+	f1 int4 PRIMARY KEY,
+	f2 int4 NOT NULL,
+	f3 text NOT NULL,
+	f4 text
+);
+-- deliberately not in column order
+CREATE UNIQUE INDEX tbl_identity_index_f3_f2 ON tbl_identity_index (f3, f2);
+ALTER TABLE tbl_identity_index REPLICA IDENTITY USING INDEX tbl_identity_index_f3_f2;
+DROP TABLE IF EXISTS tbl_identity_default_nopk;
+CREATE TABLE tbl_identity_default_nopk (
+	f1 int4,
+	f2 text
+);
+DROP TABLE IF EXISTS tbl_serial;
+CREATE TABLE tbl_serial (
+	id serial PRIMARY KEY,
+	f2 text
+);
 `)
 	if err != nil {
 		_ = dbh.Close(context.Background())
@@ -108,6 +141,19 @@ ALTER TABLE tbl_identity_full REPLICA IDENTITY FULL;
 	return dbh
 }
 
+// requireServerVersion skips the test if the server is older than
+// minVersion, given in the format of server_version_num.
+func requireServerVersion(t *testing.T, dbh *pgx.Conn, minVersion int) {
+	var serverVersion int
+	err := dbh.QueryRow(context.Background(), "SELECT current_setting('server_version_num')::int").Scan(&serverVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serverVersion < minVersion {
+		t.Skipf("server version %d is older than %d", serverVersion, minVersion)
+	}
+}
+
 func createStringValues(numValues int, vals ...string) [][]byte {
 	ret := make([][]byte, numValues)
 	for i := 0; i < numValues; i++ {
@@ -153,22 +199,81 @@ func createFormats(options []string, vals ...int) []byte {
 }
 
 
-func testTeardown(t *testing.T, dbh *pgx.Conn) {
+func testTeardown(t testing.TB, dbh *pgx.Conn) {
 	_, _ = dbh.Exec(context.Background(), "SELECT pg_drop_replication_slot($1)", replicationSlotName)
 	_ = dbh.Close(context.Background())
 }
 
 func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expectedMessages []proto.Message) {
-	if options == nil {
-		options = []string{}
-	}
-
 	_, err := dbh.Exec(context.Background(), sql)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	rows, err := dbh.Query(context.Background(), `SELECT data FROM pg_logical_slot_get_binary_changes($1, NULL, NULL, VARIADIC $2)`,
+	compareMessages(t, getChanges(t, dbh, options), expectedMessages)
+}
+
+// compareMessages fails the test unless the received messages are equal to
+// the expected messages.
+func compareMessages(t *testing.T, messages []proto.Message, expectedMessages []proto.Message) {
+	numExpectedMessages := len(expectedMessages)
+	for i, msg := range messages {
+		if len(expectedMessages) == 0 {
+			t.Fatalf("found message %+#v after the last expected message", msg)
+		}
+
+		if !proto.Equal(msg, expectedMessages[0]) {
+			t.Logf("message number %d does not match:\n    %T:%+v\n\n  is not equal to\n\n    %T:%+v",
+					 i + 1, msg, msg, expectedMessages[0], expectedMessages[0])
+			t.Logf("received message was: %s", proto.MarshalTextString(msg))
+			t.FailNow()
+		}
+
+		expectedMessages = expectedMessages[1:]
+	}
+
+	if len(expectedMessages) > 0 {
+		t.Fatalf("only found %d out of %d expected messages",
+				 numExpectedMessages - len(expectedMessages),
+				 numExpectedMessages)
+	}
+}
+
+// getChanges consumes all pending changes from the test replication slot and
+// returns the decoded messages in the order they were received.
+func getChanges(t *testing.T, dbh *pgx.Conn, options []string) []proto.Message {
+	var messages []proto.Message
+	for _, wireMessage := range getWireMessages(t, dbh, options) {
+		messages = append(messages, wireMessage...)
+	}
+	return messages
+}
+
+// peekChanges is like getChanges, but doesn't consume the changes; the next
+// call will decode them again, just like it would after a restart.
+func peekChanges(t *testing.T, dbh *pgx.Conn, options []string) []proto.Message {
+	var messages []proto.Message
+	for _, wireMessage := range readWireMessages(t, dbh, "pg_logical_slot_peek_binary_changes", options) {
+		messages = append(messages, wireMessage...)
+	}
+	return messages
+}
+
+// getWireMessages is like getChanges, but keeps the messages of each wire
+// message separate.
+func getWireMessages(t *testing.T, dbh *pgx.Conn, options []string) [][]proto.Message {
+	return readWireMessages(t, dbh, "pg_logical_slot_get_binary_changes", options)
+}
+
+// readRawWireMessages returns the wire messages returned by function as is.
+func readRawWireMessages(t testing.TB, dbh *pgx.Conn, function string, options []string) [][]byte {
+	var wireMessages [][]byte
+
+	if options == nil {
+		options = []string{}
+	}
+
+	rows, err := dbh.Query(context.Background(), `SELECT data FROM ` + function + `($1, NULL, NULL, VARIADIC $2)`,
 		replicationSlotName,
 		options,
 	)
@@ -177,8 +282,6 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 	}
 	defer rows.Close()
 
-	numExpectedMessages := len(expectedMessages)
-	messageNum := 1
 	for rows.Next() {
 		var data []byte
 
@@ -186,28 +289,94 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 		if err != nil {
 			t.Fatal(err)
 		}
+		wireMessages = append(wireMessages, data)
+	}
+	if rows.Err() != nil {
+		t.Fatal(rows.Err())
+	}
+	return wireMessages
+}
 
-		if len(data) < 3 {
-			t.Fatalf("unexpected data %+#v length %d", data, len(data))
-		}
-		header_len := int32(0)
-		for i := 0; ; i++ {
-			if i > 6 || i >= len(data) {
-				t.Fatalf("could not parse wire message header %+#v", data)
+// decompressPglz decompresses a wire message body compressed with pglz.
+func decompressPglz(t *testing.T, data []byte, uncompressedLength int) []byte {
+	out := make([]byte, 0, uncompressedLength)
+	for sp := 0; sp < len(data) && len(out) < uncompressedLength; {
+		control := data[sp]
+		sp++
+		for bit := 0; bit < 8 && sp < len(data) && len(out) < uncompressedLength; bit++ {
+			if control & (1 << bit) == 0 {
+				out = append(out, data[sp])
+				sp++
+				continue
+			}
+			if sp + 1 >= len(data) {
+				t.Fatalf("truncated pglz back reference at offset %d", sp)
+			}
+			length := int(data[sp] & 0x0F) + 3
+			offset := int(data[sp] & 0xF0) << 4 | int(data[sp + 1])
+			sp += 2
+			if length == 18 {
+				length += int(data[sp])
+				sp++
 			}
-			header_len = int32(data[i] & 0x7F);
-			if (data[i] & 0x7F) == data[i] {
-				data = data[i + 1:]
-				break
+			if offset == 0 || offset > len(out) {
+				t.Fatalf("invalid pglz back reference offset %d", offset)
+			}
+			start := len(out) - offset
+			for i := 0; i < length; i++ {
+				out = append(out, out[start + i])
 			}
 		}
+	}
+	if len(out) != uncompressedLength {
+		t.Fatalf("decompressed %d bytes; expected %d", len(out), uncompressedLength)
+	}
+	return out
+}
 
-		wireMsg := &WireMessageHeader{}
-		err = proto.Unmarshal(data[:header_len], wireMsg)
-		if err != nil {
-			t.Fatal(err)
+// splitWireMessage parses the header of a wire message, and returns it and
+// the body, which is still compressed if the wire message was.
+func splitWireMessage(t testing.TB, data []byte) (*WireMessageHeader, []byte) {
+	if len(data) < 3 {
+		t.Fatalf("unexpected data %+#v length %d", data, len(data))
+	}
+	header_len := int32(0)
+	for i := 0; ; i++ {
+		if i > 4 || i >= len(data) {
+			t.Fatalf("could not parse wire message header %+#v", data)
+		}
+		header_len |= int32(data[i] & 0x7F) << (7 * i)
+		if (data[i] & 0x7F) == data[i] {
+			data = data[i + 1:]
+			break
+		}
+	}
+	if int(header_len) > len(data) {
+		t.Fatalf("wire message header length %d exceeds the length of the data %d", header_len, len(data))
+	}
+
+	wireMsg := &WireMessageHeader{}
+	err := proto.Unmarshal(data[:header_len], wireMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wireMsg, data[header_len:]
+}
+
+func readWireMessages(t *testing.T, dbh *pgx.Conn, function string, options []string) [][]proto.Message {
+	var wireMessages [][]proto.Message
+
+	for _, data := range readRawWireMessages(t, dbh, function, options) {
+		var messages []proto.Message
+
+		wireMsg, data := splitWireMessage(t, data)
+		switch wireMsg.Compression {
+			case CompressionMethod_COMPRESSION_NONE:
+			case CompressionMethod_COMPRESSION_PGLZ:
+				data = decompressPglz(t, data, int(wireMsg.UncompressedLength))
+			default:
+				t.Fatalf("wire message compressed with unsupported method %s", wireMsg.Compression)
 		}
-		data = data[header_len:]
 
 		if len(wireMsg.Types) != len(wireMsg.Offsets) {
 			t.Fatalf(
@@ -218,8 +387,8 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 		}
 
 		for i, typ := range wireMsg.Types {
-			var receivedTextFormat string
 			var msg proto.Message
+			var err error
 
 			offset := wireMsg.Offsets[i]
 			if offset > int32(len(data)) {
@@ -243,7 +412,6 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 					if err != nil {
 						t.Fatal(err)
 					}
-					receivedTextFormat = proto.MarshalTextString(begin)
 					msg = begin
 				case WireMessageType_WMSG_COMMIT:
 					commit := &CommitTransaction{}
@@ -251,7 +419,6 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 					if err != nil {
 						t.Fatal(err)
 					}
-					receivedTextFormat = proto.MarshalTextString(commit)
 					msg = commit
 				case WireMessageType_WMSG_INSERT:
 					ins := &InsertDescription{}
@@ -259,7 +426,6 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 					if err != nil {
 						t.Fatal(err)
 					}
-					receivedTextFormat = proto.MarshalTextString(ins)
 					msg = ins
 				case WireMessageType_WMSG_UPDATE:
 					upd := &UpdateDescription{}
@@ -267,7 +433,6 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 					if err != nil {
 						t.Fatal(err)
 					}
-					receivedTextFormat = proto.MarshalTextString(upd)
 					msg = upd
 				case WireMessageType_WMSG_DELETE:
 					del := &DeleteDescription{}
@@ -275,33 +440,104 @@ func runTest(t *testing.T, dbh *pgx.Conn, sql string, options []string, expected
 					if err != nil {
 						t.Fatal(err)
 					}
-					receivedTextFormat = proto.MarshalTextString(del)
 					msg = del
+				case WireMessageType_WMSG_TRUNCATE:
+					trunc := &TruncateDescription{}
+					err = proto.Unmarshal(msgData, trunc)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = trunc
+				case WireMessageType_WMSG_MESSAGE:
+					lmsg := &LogicalMessage{}
+					err = proto.Unmarshal(msgData, lmsg)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = lmsg
+				case WireMessageType_WMSG_RELATION:
+					rel := &RelationDescription{}
+					err = proto.Unmarshal(msgData, rel)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = rel
+				case WireMessageType_WMSG_STREAM_START:
+					start := &StreamStart{}
+					err = proto.Unmarshal(msgData, start)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = start
+				case WireMessageType_WMSG_STREAM_STOP:
+					stop := &StreamStop{}
+					err = proto.Unmarshal(msgData, stop)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = stop
+				case WireMessageType_WMSG_STREAM_COMMIT:
+					commit := &StreamCommit{}
+					err = proto.Unmarshal(msgData, commit)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = commit
+				case WireMessageType_WMSG_STREAM_ABORT:
+					abort := &StreamAbort{}
+					err = proto.Unmarshal(msgData, abort)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = abort
+				case WireMessageType_WMSG_PREPARE:
+					prepare := &PrepareTransaction{}
+					err = proto.Unmarshal(msgData, prepare)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = prepare
+				case WireMessageType_WMSG_COMMIT_PREPARED:
+					commit := &CommitPrepared{}
+					err = proto.Unmarshal(msgData, commit)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = commit
+				case WireMessageType_WMSG_ROLLBACK_PREPARED:
+					rollback := &RollbackPrepared{}
+					err = proto.Unmarshal(msgData, rollback)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = rollback
+				case WireMessageType_WMSG_STREAM_PREPARE:
+					prepare := &StreamPrepare{}
+					err = proto.Unmarshal(msgData, prepare)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = prepare
+				case WireMessageType_WMSG_SESSION_START:
+					start := &SessionStart{}
+					err = proto.Unmarshal(msgData, start)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = start
+				case WireMessageType_WMSG_SEQUENCE:
+					seq := &SequenceDescription{}
+					err = proto.Unmarshal(msgData, seq)
+					if err != nil {
+						t.Fatal(err)
+					}
+					msg = seq
 				default:
 					t.Fatalf("unknown wire message type %+#v", typ)
 			}
-			if len(expectedMessages) == 0 {
-				t.Fatalf("found message %+#v after the last expected message", msg)
-			}
-
-			if !proto.Equal(msg, expectedMessages[0]) {
-				t.Logf("message number %d does not match:\n    %T:%+v\n\n  is not equal to\n\n    %T:%+v",
-						 messageNum, msg, msg, expectedMessages[0], expectedMessages[0])
-				t.Logf("received message was: %s", receivedTextFormat)
-				t.FailNow()
-			}
-
-			expectedMessages = expectedMessages[1:]
-			messageNum++
+			messages = append(messages, msg)
 		}
+		wireMessages = append(wireMessages, messages)
 	}
-	if rows.Err() != nil {
-		t.Fatal(rows.Err())
-	}
-
-	if len(expectedMessages) > 0 {
-		t.Fatalf("only found %d out of %d expected messages",
-				 numExpectedMessages - len(expectedMessages),
-				 numExpectedMessages)
-	}
+	return wireMessages
 }
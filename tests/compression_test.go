@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"testing"
+)
+
+// fillTenk1 fills tenk1 with numRows rows resembling the tenk1 table of the
+// PostgreSQL regression tests.
+func fillTenk1(t testing.TB, dbh *pgx.Conn, numRows int) {
+	_, err := dbh.Exec(context.Background(), `
+INSERT INTO tenk1
+SELECT
+	i, (i * 7919) % $1, i % 2, i % 4, i % 10, i % 20, i % 100, i % 1000,
+	i % 2000, i % 5000, i % 10000, (i % 100) * 2, (i % 100) * 2 + 1,
+	chr(65 + i % 26) || chr(65 + i / 26 % 26) || chr(65 + i / 676 % 26) || 'AAA',
+	chr(65 + i / 7 % 26) || chr(65 + i / 182 % 26) || chr(65 + i / 4732 % 26) || 'AAA',
+	(ARRAY['AAAA', 'HHHH', 'OOOO', 'VVVV'])[i % 4 + 1] || repeat('x', 48)
+FROM generate_series(0, $1 - 1) i
+`, numRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// compressionSupported returns false if the server wasn't built with support
+// for the compression method.
+func compressionSupported(t testing.TB, dbh *pgx.Conn, method string) bool {
+	_, err := dbh.Exec(
+		context.Background(),
+		`SELECT pg_logical_slot_peek_binary_changes($1, NULL, 1, VARIADIC $2)`,
+		replicationSlotName,
+		[]string{"compression", method},
+	)
+	if err != nil {
+		if strings.Index(err.Error(), "is not supported by this build") != -1 {
+			return false
+		}
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestCompressionInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, `"" is not a valid value for parameter "compression"`},
+		{"gzip", true, `"gzip" is not a valid value for parameter "compression"`},
+		{"PGLZ", true, `"PGLZ" is not a valid value for parameter "compression"`},
+		{"none", false, ""},
+		{"pglz", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"compression", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+
+	// either accepted or rejected as not supported
+	for _, method := range []string{"lz4", "zstd"} {
+		_ = compressionSupported(t, dbh, method)
+	}
+}
+
+// Compressed wire messages decode to the same messages as uncompressed ones.
+func TestCompressionPglz(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	fillTenk1(t, dbh, 100)
+
+	options := []string{
+		"compression", "pglz",
+	}
+
+	compressed := 0
+	for _, data := range readRawWireMessages(t, dbh, "pg_logical_slot_peek_binary_changes", options) {
+		header, body := splitWireMessage(t, data)
+		if header.Compression == CompressionMethod_COMPRESSION_PGLZ {
+			compressed++
+			if int(header.UncompressedLength) <= len(body) {
+				t.Errorf("the uncompressed length %d is not larger than the compressed body", header.UncompressedLength)
+			}
+		} else if header.Compression != CompressionMethod_COMPRESSION_NONE {
+			t.Errorf("unexpected compression method %s", header.Compression)
+		}
+	}
+	if compressed == 0 {
+		t.Fatalf("no compressed wire messages")
+	}
+
+	expected := peekChanges(t, dbh, nil)
+	compareMessages(t, getChanges(t, dbh, options), expected)
+}
+
+// Reports the total size of the wire messages for the changes of inserting
+// the tenk1 fixture with each compression method, and the ratio to the size
+// without compression.
+func BenchmarkCompressionFrameSizes(b *testing.B) {
+	dbh := testSetup(b)
+	defer testTeardown(b, dbh)
+
+	fillTenk1(b, dbh, 10000)
+
+	frameSizes := func(method string) int {
+		total := 0
+		options := []string{
+			"compression", method,
+		}
+		for _, data := range readRawWireMessages(b, dbh, "pg_logical_slot_peek_binary_changes", options) {
+			total += len(data)
+		}
+		return total
+	}
+	uncompressed := frameSizes("none")
+
+	for _, method := range []string{"none", "pglz", "lz4", "zstd"} {
+		b.Run(method, func(b *testing.B) {
+			if !compressionSupported(b, dbh, method) {
+				b.Skipf("compression method %s is not supported by the server", method)
+			}
+			var total int
+			for i := 0; i < b.N; i++ {
+				total = frameSizes(method)
+			}
+			b.ReportMetric(float64(total), "bytes")
+			b.ReportMetric(float64(total) / float64(uncompressed), "ratio")
+		})
+	}
+}
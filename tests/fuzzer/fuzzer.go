@@ -237,6 +237,7 @@ func (f *Fuzzer) openReplicationConnection() {
 		"type_oids_mode 'omit_nulls'",
 		"formats_mode 'disabled'",
 		"binary_oid_ranges '1-200000'",
+		"enable_transaction_metadata 'on'",
 	}
 
 	replConnInfo := append(f.conninfo, "replication=database")
@@ -358,7 +359,16 @@ func (f *Fuzzer) runTests(schema *TestSchema, generator TransactionGenerator) er
 			}
 			expectedMessages = append(expectedMessages, op.ExpectedMessages(schema)...)
 		}
-		expectedMessages = append(expectedMessages, &CommitTransaction{})
+
+		var xid int64
+		err = dbtxn.QueryRow(context.Background(), "SELECT txid_current() % 4294967296").Scan(&xid)
+		if err != nil {
+			_ = dbtxn.Rollback(context.Background())
+			return err
+		}
+		expectedMessages = append(expectedMessages, &CommitTransaction{
+			Xid: uint32(xid),
+		})
 
 		err = dbtxn.Commit(context.Background())
 		if err != nil {
@@ -391,6 +401,15 @@ func (f *Fuzzer) runTests(schema *TestSchema, generator TransactionGenerator) er
 			}
 			msg := decodedMessage.Message
 			receivedMessages = append(receivedMessages, msg)
+			msg, err = stripTransactionMetadata(msg)
+			if err != nil {
+				return &FuzzerError{
+					Transaction: txn,
+					ExpectedMessages: expectedMessages,
+					ReceivedMessages: receivedMessages,
+					Err: err,
+				}
+			}
 			if !proto.Equal(msg, expectedMessage) {
 				return &FuzzerError{
 					Transaction: txn,
@@ -411,6 +430,28 @@ func (f *Fuzzer) runTests(schema *TestSchema, generator TransactionGenerator) er
 	return nil
 }
 
+// stripTransactionMetadata checks the parts of the transaction metadata in a
+// CommitTransaction message which can't be predicted, and returns a copy of
+// the message with those fields cleared.  Other messages are returned as-is.
+func stripTransactionMetadata(msg proto.Message) (proto.Message, error) {
+	commit, ok := msg.(*CommitTransaction)
+	if !ok {
+		return msg, nil
+	}
+	if commit.CommitLsn == 0 {
+		return nil, fmt.Errorf("commit_lsn is not set in %+v", commit)
+	}
+	if commit.EndLsn <= commit.CommitLsn {
+		return nil, fmt.Errorf("end_lsn is not past commit_lsn in %+v", commit)
+	}
+	if commit.CommitTime == 0 {
+		return nil, fmt.Errorf("commit_time is not set in %+v", commit)
+	}
+	return &CommitTransaction{
+		Xid: commit.Xid,
+	}, nil
+}
+
 func (f *Fuzzer) shutdownLogicalReceiver() {
 	f.replCancel()
 	for {
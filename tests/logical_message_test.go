@@ -0,0 +1,210 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+// stripLogicalMessageLsns checks that each LogicalMessage has its lsn set and
+// clears it so that the messages can be compared against the expected ones.
+func stripLogicalMessageLsns(t *testing.T, messages []proto.Message) {
+	for _, msg := range messages {
+		if lmsg, ok := msg.(*LogicalMessage); ok {
+			if lmsg.Lsn == 0 {
+				t.Fatalf("lsn is not set in %+v", lmsg)
+			}
+			lmsg.Lsn = 0
+		}
+	}
+}
+
+func TestLogicalMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tenk1(unique1) VALUES (1);
+SELECT pg_logical_emit_message(true, 'test', 'transactional');
+SELECT pg_logical_emit_message(false, 'test', 'non-transactional');
+COMMIT;
+SELECT pg_logical_emit_message(false, 'test', '\x00ff'::bytea);
+`
+
+	options := []string{
+		"enable_logical_messages",	"on",
+	}
+
+	_, err := dbh.Exec(context.Background(), sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	stripLogicalMessageLsns(t, messages)
+
+	var expected []proto.Message
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "test",
+			Content: []byte("non-transactional"),
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1"),
+				Nulls: createNulls(options,1,15),
+			},
+		},
+	)
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "test",
+			Content: []byte("transactional"),
+			Transactional: true,
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "test",
+			Content: []byte{0x00, 0xff},
+		},
+	)
+	compareMessages(t, messages, expected)
+}
+
+func TestLogicalMessagesRollback(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tenk1(unique1) VALUES (1);
+SELECT pg_logical_emit_message(true, 'test', 'transactional');
+SELECT pg_logical_emit_message(false, 'test', 'non-transactional');
+ROLLBACK;
+`
+
+	options := []string{
+		"enable_logical_messages",	"on",
+	}
+
+	_, err := dbh.Exec(context.Background(), sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	stripLogicalMessageLsns(t, messages)
+
+	var expected []proto.Message
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "test",
+			Content: []byte("non-transactional"),
+		},
+	)
+	compareMessages(t, messages, expected)
+}
+
+func TestLogicalMessagePrefixes(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+SELECT pg_logical_emit_message(false, 'deploy', 'a');
+SELECT pg_logical_emit_message(false, 'deployment', 'b');
+SELECT pg_logical_emit_message(false, 'outbox', 'c');
+SELECT pg_logical_emit_message(false, 'other', 'd');
+`
+
+	options := []string{
+		"enable_logical_messages",	"on",
+		"logical_message_prefixes",	" deploy , outbox",
+	}
+
+	_, err := dbh.Exec(context.Background(), sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	stripLogicalMessageLsns(t, messages)
+
+	var expected []proto.Message
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "deploy",
+			Content: []byte("a"),
+		},
+	)
+	expected = append(expected,
+		&LogicalMessage{
+			Prefix: "outbox",
+			Content: []byte("c"),
+		},
+	)
+	compareMessages(t, messages, expected)
+}
+
+func TestLogicalMessagesDisabled(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+SELECT pg_logical_emit_message(true, 'test', 'transactional');
+SELECT pg_logical_emit_message(false, 'test', 'non-transactional');
+`
+
+	runTest(t, dbh, sql, nil, nil)
+}
+
+func TestLogicalMessagePrefixesInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+	}{
+		{"", false},
+		{"a", false},
+		{" a , b ", false},
+		{",", true},
+		{"a,", true},
+		{",a", true},
+		{"a, ,b", true},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"enable_logical_messages", "on",
+			"logical_message_prefixes", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), "invalid input syntax for logical_message_prefixes") == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s", test.input, err)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
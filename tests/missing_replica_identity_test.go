@@ -0,0 +1,168 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+const missingReplicaIdentitySQL = `
+BEGIN;
+INSERT INTO tbl_identity_default_nopk VALUES (1, 'foo');
+UPDATE tbl_identity_default_nopk SET f2 = 'bar';
+DELETE FROM tbl_identity_default_nopk;
+COMMIT;
+`
+
+func TestMissingReplicaIdentityError(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), missingReplicaIdentitySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, options := range [][]string{
+		{},
+		{"missing_replica_identity", "error"},
+	} {
+		_, err = dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_peek_binary_changes($1, NULL, NULL, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err == nil {
+			t.Errorf("options %v: decoding succeeded unexpectedly", options)
+			continue
+		}
+		expectedError := `cannot decode UPDATE on table "public.tbl_identity_default_nopk" because it does not have a replica identity`
+		if strings.Index(err.Error(), expectedError) == -1 {
+			t.Errorf("options %v: unexpected error %s (expected to contain %q)", options, err, expectedError)
+		}
+	}
+}
+
+func TestMissingReplicaIdentitySkip(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	options := []string{"missing_replica_identity", "skip"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityDefaultNoPKDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityDefaultNoPKFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, missingReplicaIdentitySQL, options, expected)
+}
+
+func TestMissingReplicaIdentitySendWithoutKeys(t *testing.T) {
+	for _, mode := range []string{"send_without_keys", "send_full_old_tuple_if_available"} {
+		t.Run(mode, func(t *testing.T) {
+			dbh := testSetup(t)
+			defer testTeardown(t, dbh)
+
+			options := []string{"missing_replica_identity", mode}
+
+			var expected []proto.Message
+			expected = append(expected,
+				&InsertDescription{
+					Table: tblIdentityDefaultNoPKDescription,
+					NewValues: &FieldSetDescription{
+						Names: tblIdentityDefaultNoPKFieldNames,
+						Values: createStringValues(2, "1", "foo"),
+						Nulls: createNulls(options,2),
+					},
+				},
+			)
+			expected = append(expected,
+				&UpdateDescription{
+					Table: tblIdentityDefaultNoPKDescription,
+					NewValues: &FieldSetDescription{
+						Names: tblIdentityDefaultNoPKFieldNames,
+						Values: createStringValues(2, "1", "bar"),
+						Nulls: createNulls(options,2),
+					},
+					KeyFields: &FieldSetDescription{},
+				},
+			)
+			expected = append(expected,
+				&DeleteDescription{
+					Table: tblIdentityDefaultNoPKDescription,
+				},
+			)
+			expected = append(expected, &CommitTransaction{})
+			runTest(t, dbh, missingReplicaIdentitySQL, options, expected)
+		})
+	}
+}
+
+// PostgreSQL never writes the old tuple of such tables to WAL, so no key
+// columns are described in send_full_old_tuple_if_available mode either.
+func TestMissingReplicaIdentityRelationMessages(t *testing.T) {
+	for _, mode := range []string{"send_without_keys", "send_full_old_tuple_if_available"} {
+		t.Run(mode, func(t *testing.T) {
+			dbh := testSetup(t)
+			defer testTeardown(t, dbh)
+
+			var relid uint32
+			err := dbh.QueryRow(context.Background(), `SELECT 'tbl_identity_default_nopk'::regclass::oid`).Scan(&relid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = dbh.Exec(context.Background(), missingReplicaIdentitySQL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			options := []string{
+				"missing_replica_identity", mode,
+				"enable_relation_messages", "on",
+			}
+
+			var relations []proto.Message
+			for _, msg := range getChanges(t, dbh, options) {
+				if _, ok := msg.(*RelationDescription); ok {
+					relations = append(relations, msg)
+				}
+			}
+			expected := []proto.Message{
+				&RelationDescription{
+					RelationId: relid,
+					SchemaName: "public",
+					TableName: "tbl_identity_default_nopk",
+					ColumnNames: tblIdentityDefaultNoPKFieldNames,
+					TypeOids: []uint32{23, 25},
+					TypeModifiers: createTypeModifiers(2),
+				},
+			}
+			compareMessages(t, relations, expected)
+		})
+	}
+}
+
+func TestMissingReplicaIdentityInput(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	_, err := dbh.Exec(
+		context.Background(),
+		`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+		replicationSlotName,
+		[]string{"missing_replica_identity", "ignore"},
+	)
+	expectedError := `"ignore" is not a valid value for parameter "missing_replica_identity"`
+	if err == nil || strings.Index(err.Error(), expectedError) == -1 {
+		t.Errorf("unexpected error %v (expected to contain %q)", err, expectedError)
+	}
+}
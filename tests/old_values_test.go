@@ -0,0 +1,176 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"testing"
+)
+
+func TestOldValues(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+UPDATE tbl_identity_full SET f2 = 'bar';
+DELETE FROM tbl_identity_full;
+COMMIT;
+`
+
+	options := []string{"enable_old_values","on"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+			OldValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityFullDescription,
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+			OldValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+// The old tuple of a table with a replica identity index only contains the
+// key, so it's not sent as the old values.
+func TestOldValuesIdentityIndex(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+UPDATE tbl_identity_index SET f2 = 3;
+COMMIT;
+`
+
+	options := []string{"enable_old_values","on"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "bar"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "3", "foo", "bar"),
+				Nulls: createNulls(options,4),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"f3", "f2"},
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestOldValuesRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tbl_identity_full'::regclass::oid`).Scan(&oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := &TableDescription{TableOid: oid}
+
+	sql := `
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+DELETE FROM tbl_identity_full;
+`
+
+	options := []string{
+		"enable_relation_messages","on",
+		"enable_old_values","on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: oid,
+			SchemaName: "public",
+			TableName: "tbl_identity_full",
+			ColumnNames: tblIdentityFullFieldNames,
+			TypeOids: []uint32{23, 25},
+			TypeModifiers: createTypeModifiers(2),
+			KeyColumns: []uint32{0, 1},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&DeleteDescription{
+			Table: table,
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+			OldValues: &FieldSetDescription{
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
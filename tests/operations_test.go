@@ -0,0 +1,177 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func TestOperationsInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, "invalid input syntax for operations"},
+		{"insert,", true, "invalid input syntax for operations"},
+		{"insert,,delete", true, "invalid input syntax for operations"},
+		{"upsert", true, `unrecognized operation "upsert" in operations`},
+		{"INSERT", true, `unrecognized operation "INSERT" in operations`},
+		{"insert", false, ""},
+		{" insert , delete ", false, ""},
+		{"insert,update,delete,truncate", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"operations", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+const operationsSQL = `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+UPDATE tbl_identity_full SET f2 = 'bar';
+DELETE FROM tbl_identity_full;
+COMMIT;
+UPDATE tbl_identity_full SET f2 = 'baz';
+TRUNCATE tbl_identity_full;
+`
+
+func TestOperationsInsertDelete(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	options := []string{
+		"operations", "insert,delete",
+		"enable_truncate_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityFullDescription,
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	// the UPDATE and the TRUNCATE transactions are empty
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, operationsSQL, options, expected)
+}
+
+func TestOperationsUpdateTruncate(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	options := []string{
+		"operations", "update,truncate",
+		"enable_truncate_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	// the second UPDATE doesn't match any rows
+	expected = append(expected,
+		&TruncateDescription{
+			Tables: []*TableDescription{tblIdentityFullDescription},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, operationsSQL, options, expected)
+}
+
+// Asserts that no message of a filtered type appears in a larger stream.
+func TestOperationsNeverSent(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), `
+INSERT INTO tenk1(unique1, unique2) SELECT i, i FROM generate_series(1, 100) i;
+UPDATE tenk1 SET two = 2 WHERE unique1 % 2 = 0;
+DELETE FROM tenk1 WHERE unique1 % 3 = 0;
+BEGIN;
+INSERT INTO tenk1(unique1, unique2) VALUES (1000, 1000);
+UPDATE tenk1 SET two = 3;
+COMMIT;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := getChanges(t, dbh, []string{"operations", "update"})
+	numUpdates := 0
+	numCommits := 0
+	for _, msg := range messages {
+		switch msg.(type) {
+			case *UpdateDescription:
+				numUpdates++
+			case *CommitTransaction:
+				numCommits++
+			default:
+				t.Errorf("unexpected message %T", msg)
+		}
+	}
+	if numUpdates != 50 + 68 {
+		t.Errorf("got %d updates; expected %d", numUpdates, 50 + 68)
+	}
+	// the INSERT and DELETE transactions are empty
+	if numCommits != 2 {
+		t.Errorf("got %d commits; expected 2", numCommits)
+	}
+}
@@ -0,0 +1,201 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"testing"
+)
+
+var testOriginNames = []string{"pb3ld_test_origin_a", "pb3ld_test_origin_b"}
+
+// createTestOrigins creates the replication origins in testOriginNames, and
+// returns a function which drops them.
+func createTestOrigins(t *testing.T, dbh *pgx.Conn) func() {
+	for _, name := range testOriginNames {
+		_, err := dbh.Exec(context.Background(), `
+SELECT pg_replication_origin_create($1)
+WHERE pg_replication_origin_oid($1) IS NULL
+`, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return func() {
+		for _, name := range testOriginNames {
+			_, _ = dbh.Exec(context.Background(), `SELECT pg_replication_origin_drop($1)`, name)
+		}
+	}
+}
+
+// One transaction without an origin, and one from each of the test origins.
+const originSQL = `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'local');
+COMMIT;
+SELECT pg_replication_origin_session_setup('pb3ld_test_origin_a');
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (2, 'a');
+COMMIT;
+SELECT pg_replication_origin_session_reset();
+SELECT pg_replication_origin_session_setup('pb3ld_test_origin_b');
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (3, 'b');
+SELECT pg_logical_emit_message(true, 'test', 'b');
+COMMIT;
+SELECT pg_replication_origin_session_reset();
+`
+
+func TestOriginInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, "invalid input syntax for origin"},
+		{"pb3ld_test_origin_a,", true, "invalid input syntax for origin"},
+		{"pb3ld_test_origin_a,,pb3ld_test_origin_b", true, "invalid input syntax for origin"},
+		{"no_such_origin", true, `replication origin "no_such_origin" does not exist`},
+		{"ANY", true, `replication origin "ANY" does not exist`},
+		{"any", false, ""},
+		{"none", false, ""},
+		{"pb3ld_test_origin_a", false, ""},
+		{" pb3ld_test_origin_a , pb3ld_test_origin_b ", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	defer createTestOrigins(t, dbh)()
+
+	for _, test := range tests {
+		options := []string{
+			"origin", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+func TestOriginFilter(t *testing.T) {
+	tests := []struct{
+		origin string
+		// the values of f1 of the rows which are sent
+		expected_rows []string
+	}{
+		{"any", []string{"1", "2", "3"}},
+		{"none", []string{"1"}},
+		{"pb3ld_test_origin_a", []string{"1", "2"}},
+		{"pb3ld_test_origin_b,pb3ld_test_origin_a", []string{"1", "2", "3"}},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	defer createTestOrigins(t, dbh)()
+
+	values := map[string]string{"1": "local", "2": "a", "3": "b"}
+
+	for _, test := range tests {
+		options := []string{
+			"origin", test.origin,
+			"enable_logical_messages", "on",
+		}
+
+		var expected []proto.Message
+		for _, row := range test.expected_rows {
+			expected = append(expected,
+				&InsertDescription{
+					Table: tblIdentityFullDescription,
+					NewValues: &FieldSetDescription{
+						Names: tblIdentityFullFieldNames,
+						Values: createStringValues(2, row, values[row]),
+						Nulls: createNulls(options, 2),
+					},
+				},
+			)
+			// the logical message has the same origin as the row
+			if row == "3" {
+				expected = append(expected,
+					&LogicalMessage{
+						Transactional: true,
+						Prefix: "test",
+						Content: []byte("b"),
+					},
+				)
+			}
+			expected = append(expected, &CommitTransaction{})
+		}
+		runTest(t, dbh, originSQL, options, expected)
+	}
+}
+
+func TestOriginName(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	defer createTestOrigins(t, dbh)()
+
+	options := []string{
+		"enable_begin_messages", "on",
+		"enable_transaction_metadata", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), originSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var originNames []string
+	for _, msg := range getChanges(t, dbh, options) {
+		begin, ok := msg.(*BeginTransaction)
+		if ok {
+			originNames = append(originNames, begin.OriginName)
+		}
+	}
+	expected := []string{"", "pb3ld_test_origin_a", "pb3ld_test_origin_b"}
+	if strings.Join(originNames, ",") != strings.Join(expected, ",") {
+		t.Fatalf("got origin names %q; expected %q", originNames, expected)
+	}
+
+	// sent without transaction metadata as well
+	options = []string{
+		"enable_begin_messages", "on",
+	}
+
+	_, err = dbh.Exec(context.Background(), originSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originNames = nil
+	for _, msg := range getChanges(t, dbh, options) {
+		begin, ok := msg.(*BeginTransaction)
+		if ok {
+			if begin.Xid != 0 {
+				t.Fatalf("unexpected xid %d without transaction metadata", begin.Xid)
+			}
+			originNames = append(originNames, begin.OriginName)
+		}
+	}
+	if strings.Join(originNames, ",") != strings.Join(expected, ",") {
+		t.Fatalf("got origin names %q without transaction metadata; expected %q", originNames, expected)
+	}
+}
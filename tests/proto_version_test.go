@@ -0,0 +1,137 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func TestProtoVersionInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, "invalid input syntax for proto_version"},
+		{"foo", true, "invalid input syntax for proto_version"},
+		{"0", true, "invalid input syntax for proto_version"},
+		{"-1", true, "invalid input syntax for proto_version"},
+		{"1-", true, "invalid input syntax for proto_version"},
+		{"1-2-3", true, "invalid input syntax for proto_version"},
+		{"2-1", true, "the upper bound of a range can't be lower than its lower bound in proto_version"},
+		{"3", true, "protocol version 3 is not supported"},
+		{"3-5", true, "none of the protocol versions 3 to 5 are supported"},
+		{"1", false, ""},
+		{"2", false, ""},
+		{"1-2", false, ""},
+		{"2-5", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"proto_version", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+// The highest version in the range is picked, and reported before the first
+// message of the session.
+func TestProtoVersionSessionStart(t *testing.T) {
+	tests := []struct{
+		input string
+		expected_version uint32
+	}{
+		{"2", 2},
+		{"1-2", 2},
+		{"1-99", 2},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		_, err := dbh.Exec(context.Background(), `
+INSERT INTO tenk1(unique1) VALUES (1);
+DELETE FROM tenk1;
+`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		options := []string{
+			"proto_version", test.input,
+		}
+		wireMessages := getWireMessages(t, dbh, options)
+		if len(wireMessages) == 0 || len(wireMessages[0]) == 0 {
+			t.Fatalf("%q: no messages received", test.input)
+		}
+		start, ok := wireMessages[0][0].(*SessionStart)
+		if !ok {
+			t.Fatalf("%q: the first message is %T; expected SessionStart", test.input, wireMessages[0][0])
+		}
+		if start.ProtocolVersion != test.expected_version {
+			t.Errorf("%q: got protocol version %d; expected %d", test.input, start.ProtocolVersion, test.expected_version)
+		}
+		for i, messages := range wireMessages {
+			for j, msg := range messages {
+				if _, ok := msg.(*SessionStart); ok && (i != 0 || j != 0) {
+					t.Errorf("%q: unexpected SessionStart in message %d of wire message %d", test.input, j, i)
+				}
+			}
+		}
+	}
+}
+
+// Version 1 is the protocol spoken without the option.
+func TestProtoVersion1(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+INSERT INTO tenk1(unique1) VALUES (1);
+`
+
+	options := []string{
+		"proto_version", "1",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1"),
+				Nulls: createNulls(options,1,15),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
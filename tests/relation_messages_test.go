@@ -0,0 +1,240 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func createTypeModifiers(numColumns int) []int32 {
+	typmods := make([]int32, numColumns)
+	for i := range typmods {
+		typmods[i] = -1
+	}
+	return typmods
+}
+
+func TestRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var tenk1Oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tenk1'::regclass::oid`).Scan(&tenk1Oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenk1Table := &TableDescription{TableOid: tenk1Oid}
+
+	sql := `
+BEGIN;
+INSERT INTO tenk1(unique1) VALUES (1);
+UPDATE tenk1 SET unique2 = -20;
+COMMIT;
+DELETE FROM tenk1;
+ALTER TABLE tenk1 ADD COLUMN extra varchar(10);
+INSERT INTO tenk1(unique1, extra) VALUES (2, 'x');
+`
+
+	options := []string{"enable_relation_messages","on"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: tenk1Oid,
+			SchemaName: "public",
+			TableName: "tenk1",
+			ColumnNames: tenk1FieldNames,
+			TypeOids: tenk1FieldTypeOids,
+			TypeModifiers: createTypeModifiers(16),
+			KeyColumns: []uint32{0},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(16, "1"),
+				Nulls: createNulls(options,1,15),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(16, "1", "-20"),
+				Nulls: createNulls(options,2,14),
+			},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	// already described in this session
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tenk1Table,
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	// described again after ALTER TABLE
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: tenk1Oid,
+			SchemaName: "public",
+			TableName: "tenk1",
+			ColumnNames: append(append([]string{}, tenk1FieldNames...), "extra"),
+			TypeOids: append(append([]uint32{}, tenk1FieldTypeOids...), 1043),
+			TypeModifiers: append(createTypeModifiers(16), 14),
+			KeyColumns: []uint32{0},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(17, "2",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "",
+					"x",
+				),
+				Nulls: createNulls(options,1,15,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestRelationMessagesOmittedColumns(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var tenk1Oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tenk1'::regclass::oid`).Scan(&tenk1Oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenk1Table := &TableDescription{TableOid: tenk1Oid}
+
+	sql := `
+ALTER TABLE tenk1 ALTER COLUMN string4 SET STORAGE EXTERNAL;
+INSERT INTO tenk1(unique1, unique2, string4)
+SELECT 1, 10, repeat('j', 9001);
+UPDATE tenk1 SET unique2 = 20;
+`
+
+	options := []string{"enable_relation_messages","on"}
+
+	columnNumbers := make([]uint32, 15)
+	for i := range columnNumbers {
+		columnNumbers[i] = uint32(i)
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: tenk1Oid,
+			SchemaName: "public",
+			TableName: "tenk1",
+			ColumnNames: tenk1FieldNames,
+			TypeOids: tenk1FieldTypeOids,
+			TypeModifiers: createTypeModifiers(16),
+			KeyColumns: []uint32{0},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(16, "1", "10",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "", "",
+					"",
+					strings.Repeat("j", 9001),
+				),
+				Nulls: createNulls(options,2,13,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(15, "1", "20"),
+				Nulls: createNulls(options,2,13),
+				ColumnNumbers: columnNumbers,
+			},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestRelationMessagesIdentityFull(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tbl_identity_full'::regclass::oid`).Scan(&oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+DELETE FROM tbl_identity_full;
+COMMIT;
+`
+
+	options := []string{"enable_relation_messages","on"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: oid,
+			SchemaName: "public",
+			TableName: "tbl_identity_full",
+			ColumnNames: tblIdentityFullFieldNames,
+			TypeOids: []uint32{23, 25},
+			TypeModifiers: createTypeModifiers(2),
+			KeyColumns: []uint32{0, 1},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: &TableDescription{TableOid: oid},
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: &TableDescription{TableOid: oid},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
@@ -0,0 +1,188 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"testing"
+)
+
+func TestReplicaIdentityIndex(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+-- key not changed; the key is taken from the new tuple
+UPDATE tbl_identity_index SET f1 = 10, f4 = 'baz';
+-- key changed; the old key is logged
+UPDATE tbl_identity_index SET f2 = 3;
+DELETE FROM tbl_identity_index;
+COMMIT;
+`
+
+	options := []string{}
+
+	keyNames := []string{"f3", "f2"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "bar"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "10", "2", "foo", "baz"),
+				Nulls: createNulls(options,4),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "10", "3", "foo", "baz"),
+				Nulls: createNulls(options,4),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityIndexDescription,
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "3"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestReplicaIdentityIndexRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tbl_identity_index'::regclass::oid`).Scan(&oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', NULL);
+DELETE FROM tbl_identity_index;
+COMMIT;
+`
+
+	options := []string{"enable_relation_messages","on"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: oid,
+			SchemaName: "public",
+			TableName: "tbl_identity_index",
+			ColumnNames: tblIdentityIndexFieldNames,
+			TypeOids: []uint32{23, 23, 25, 25},
+			TypeModifiers: []int32{-1, -1, -1, -1},
+			KeyColumns: []uint32{2, 1},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: &TableDescription{TableOid: oid},
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(4, "1", "2", "foo"),
+				Nulls: createNulls(options,3,1),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: &TableDescription{TableOid: oid},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestReplicaIdentityFull(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+UPDATE tbl_identity_full SET f2 = 'bar';
+DELETE FROM tbl_identity_full;
+COMMIT;
+`
+
+	options := []string{}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityFullDescription,
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
@@ -0,0 +1,473 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func TestRowFilterInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, "invalid input syntax for row_filter"},
+		{"public.tbl_identity_full", true, "invalid input syntax for row_filter"},
+		{"public.tbl_identity_full:  ", true, "invalid input syntax for row_filter"},
+		{"tbl_identity_full: true", true, "table patterns must be schema-qualified"},
+		{"public.: true", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.tbl_identity_full: f1 >", true, "syntax error at end of input"},
+		{"public.tbl_identity_full: true ORDER BY 1", true, "invalid input syntax for row_filter"},
+		{"public.tbl_identity_full: true; SELECT 1", true, "invalid input syntax for row_filter"},
+		{"public.tbl_identity_full: true UNION SELECT 1", true, "invalid input syntax for row_filter"},
+		{"public.tbl_identity_full: f1 > 0", false, ""},
+		{" public.* : f2::int4 > 0 ", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	for _, test := range tests {
+		options := []string{
+			"row_filter", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+// The expression is only resolved against a table when the first change to
+// it is decoded, so these errors need a change to happen.
+func TestRowFilterResolveErrors(t *testing.T) {
+	tests := []struct{
+		expression string
+		expect_failure bool
+		expect_error string
+	}{
+		{"f3 > 0", true, `column "f3" does not exist`},
+		{"f1", true, "argument of row_filter must be type boolean"},
+		{"f2 = now()::text", true, "only immutable functions and operators are allowed in row filters"},
+		{"f1 IN (SELECT 1)", true, "subqueries are not allowed in row filters"},
+		{"xmin = '1'", true, "system columns are not allowed in row filters"},
+		{"row_filter_test_func(f1)", true, "user-defined functions and operators are not allowed in row filters"},
+		{"f1 > 0 AND f2 IS NOT NULL", false, ""},
+		{"tbl_identity_full.f1 > 0 AND public.tbl_identity_full.f2 IS NOT NULL", false, ""},
+		{"tbl_identity_index.f1 > 0", true, `missing FROM-clause entry for table "tbl_identity_index"`},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	_, err := dbh.Exec(context.Background(), `
+CREATE OR REPLACE FUNCTION row_filter_test_func(int4) RETURNS bool
+	IMMUTABLE LANGUAGE sql AS 'SELECT $1 > 0';
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_, _ = dbh.Exec(context.Background(), `DROP FUNCTION row_filter_test_func(int4)`)
+	}()
+
+	for _, test := range tests {
+		options := []string{
+			"row_filter", "public.tbl_identity_full: " + test.expression,
+			// filters for tables without changes are never resolved
+			"row_filter", "public.tbl_identity_index: no_such_column",
+		}
+
+		// peek, so that every expression sees the same changes
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_peek_binary_changes($1, NULL, NULL, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.expression, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.expression, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.expression)
+				continue
+			}
+		}
+	}
+}
+
+func TestRowFilterNulls(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+INSERT INTO tbl_identity_full VALUES (2, 'draft');
+INSERT INTO tbl_identity_full VALUES (3, NULL);
+COMMIT;
+`
+
+	// NULL counts as false
+	options := []string{
+		"row_filter", "public.tbl_identity_full: f2 <> 'draft'",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+
+	options = []string{
+		"row_filter", "public.tbl_identity_full: f2 IS DISTINCT FROM 'draft'",
+	}
+
+	expected = nil
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "3"),
+				Nulls: createNulls(options,1,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, `TRUNCATE tbl_identity_full;` + sql, options, expected)
+}
+
+// With REPLICA IDENTITY FULL both versions of the row are known, so UPDATEs
+// moving the row into or out of the filter become INSERTs and DELETEs.
+func TestRowFilterUpdateIdentityFull(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'draft');
+UPDATE tbl_identity_full SET f2 = 'foo';
+UPDATE tbl_identity_full SET f2 = 'bar';
+UPDATE tbl_identity_full SET f2 = 'draft';
+UPDATE tbl_identity_full SET f2 = NULL;
+DELETE FROM tbl_identity_full;
+COMMIT;
+`
+
+	options := []string{
+		"row_filter", "public.tbl_identity_full: f2 <> 'draft'",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityFullDescription,
+			KeyFields: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "bar"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	// the rest of the changes are on a row outside of the filter
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+// The old version of the key is known even without REPLICA IDENTITY FULL, so
+// filters only referring to key columns work the same way.
+func TestRowFilterUpdateKeyOnly(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+-- key not changed
+UPDATE tbl_identity_index SET f4 = 'baz';
+-- moves out of the filter
+UPDATE tbl_identity_index SET f2 = 0;
+-- moves into the filter
+UPDATE tbl_identity_index SET f2 = 5;
+DELETE FROM tbl_identity_index;
+COMMIT;
+`
+
+	options := []string{
+		"row_filter", "public.tbl_identity_index: f2 > 1",
+	}
+
+	keyNames := []string{"f3", "f2"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "bar"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "baz"),
+				Nulls: createNulls(options,4),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityIndexDescription,
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "5", "foo", "baz"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityIndexDescription,
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "5"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+// An UPDATE moving a row into the filter which leaves a TOASTed column
+// unchanged is sent as is, since the INSERT couldn't include the column.
+func TestRowFilterUpdateKeyOnlyUnchangedToast(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+ALTER TABLE tbl_identity_index ALTER COLUMN f4 SET STORAGE EXTERNAL;
+INSERT INTO tbl_identity_index VALUES (1, 0, 'foo', repeat('x', 9001));
+-- moves into the filter
+UPDATE tbl_identity_index SET f2 = 5;
+COMMIT;
+`
+
+	options := []string{
+		"row_filter", "public.tbl_identity_index: f2 > 1",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames[:3],
+				Values: createStringValues(3, "1", "5", "foo"),
+				Nulls: createNulls(options,3),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"f3", "f2"},
+				Values: createStringValues(2, "foo", "0"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+// Without the old version of the row, UPDATEs are only sent if the new version
+// matches, and DELETEs are always sent.
+func TestRowFilterUpdateOldRowUnknown(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'x');
+UPDATE tbl_identity_index SET f4 = 'y';
+UPDATE tbl_identity_index SET f4 = 'x';
+UPDATE tbl_identity_index SET f4 = 'y';
+DELETE FROM tbl_identity_index;
+COMMIT;
+`
+
+	options := []string{
+		"row_filter", "public.tbl_identity_index: f4 = 'x'",
+	}
+
+	keyNames := []string{"f3", "f2"}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "x"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "x"),
+				Nulls: createNulls(options,4),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected,
+		&DeleteDescription{
+			Table: tblIdentityIndexDescription,
+			KeyFields: &FieldSetDescription{
+				Names: keyNames,
+				Values: createStringValues(2, "foo", "2"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestRowFilterFirstMatchApplies(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 120000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+INSERT INTO tbl_identity_full VALUES (2, 'bar');
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+COMMIT;
+`
+
+	options := []string{
+		"row_filter", "public.tbl_identity_full: f1 = 1",
+		"row_filter", "public.tbl_identity_*: false",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "foo"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
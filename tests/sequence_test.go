@@ -0,0 +1,269 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"testing"
+)
+
+var tblSerialDescription = &TableDescription{
+	SchemaName: "public",
+	TableName: "tbl_serial",
+}
+var tblSerialFieldNames = []string{"id","f2"}
+
+func serialInsert(options []string, id string, f2 string) proto.Message {
+	return &InsertDescription{
+		Table: tblSerialDescription,
+		NewValues: &FieldSetDescription{
+			Names: tblSerialFieldNames,
+			Values: createStringValues(2, id, f2),
+			Nulls: createNulls(options, 2),
+		},
+	}
+}
+
+func serialSequence(lastValue int64, isCalled bool) proto.Message {
+	return &SequenceDescription{
+		SchemaName: "public",
+		SequenceName: "tbl_serial_id_seq",
+		LastValue: lastValue,
+		IsCalled: isCalled,
+	}
+}
+
+// clearSequenceOids checks that all SequenceDescriptions among messages
+// carry an oid, and clears it so that the messages can be compared with
+// compareMessages.
+func clearSequenceOids(t *testing.T, messages []proto.Message) {
+	for _, msg := range messages {
+		if seq, ok := msg.(*SequenceDescription); ok {
+			if seq.SequenceOid == 0 {
+				t.Fatalf("SequenceDescription %+v has no oid", seq)
+			}
+			seq.SequenceOid = 0
+		}
+	}
+}
+
+func runSequenceTest(t *testing.T, sql string, options []string, expected []proto.Message) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := getChanges(t, dbh, options)
+	clearSequenceOids(t, messages)
+	compareMessages(t, messages, expected)
+}
+
+func TestSequenceInsert(t *testing.T) {
+	sql := `
+INSERT INTO tbl_serial(f2) VALUES ('a'), ('b');
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "1", "a"),
+		serialInsert(options, "2", "b"),
+		serialSequence(2, true),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+func TestSequenceSetval(t *testing.T) {
+	sql := `
+SELECT setval('tbl_serial_id_seq', 100);
+INSERT INTO tbl_serial(f2) VALUES ('a');
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "101", "a"),
+		serialSequence(101, true),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+// Calls to nextval() aren't sent by themselves, but the value they advanced
+// the sequence to is sent with the next transaction which changes the table.
+func TestSequenceNextval(t *testing.T) {
+	sql := `
+SELECT nextval('tbl_serial_id_seq') FROM generate_series(1, 3);
+INSERT INTO tbl_serial(f2) VALUES ('a');
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "4", "a"),
+		serialSequence(4, true),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+// The sequence the transaction saw has been replaced by a new one by the time
+// it's decoded, so there's nothing to send for it.
+func TestSequenceRestart(t *testing.T) {
+	sql := `
+INSERT INTO tbl_serial(f2) VALUES ('a');
+ALTER SEQUENCE tbl_serial_id_seq RESTART WITH 100;
+INSERT INTO tbl_serial(f2) VALUES ('b');
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "1", "a"),
+		&CommitTransaction{},
+		serialInsert(options, "100", "b"),
+		serialSequence(100, true),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+// A change of ownership is noticed in the same session.  The sequence is
+// owned by the table again at the end, so that it's dropped with the table.
+func TestSequenceOwnedBy(t *testing.T) {
+	sql := `
+INSERT INTO tbl_serial(f2) VALUES ('a');
+ALTER SEQUENCE tbl_serial_id_seq OWNED BY NONE;
+INSERT INTO tbl_serial(f2) VALUES ('b');
+ALTER SEQUENCE tbl_serial_id_seq OWNED BY tbl_serial.id;
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "1", "a"),
+		serialSequence(1, true),
+		&CommitTransaction{},
+		serialInsert(options, "2", "b"),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+// The value isn't sent again unless it has changed.
+func TestSequenceUnchanged(t *testing.T) {
+	sql := `
+INSERT INTO tbl_serial(f2) VALUES ('a');
+UPDATE tbl_serial SET f2 = 'b';
+`
+
+	options := []string{
+		"enable_sequence_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(options, "1", "a"),
+		serialSequence(1, true),
+		&CommitTransaction{},
+		&UpdateDescription{
+			Table: tblSerialDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblSerialFieldNames,
+				Values: createStringValues(2, "1", "b"),
+				Nulls: createNulls(options, 2),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"id"},
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options, 1),
+			},
+		},
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, options, expected)
+}
+
+// The values for a streamed transaction are sent in a stream block of their
+// own right before its StreamCommit.
+func TestSequenceStreaming(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 140000)
+	setLowDecodingWorkMem(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_serial(f2) SELECT 'a' FROM generate_series(1, 2000) i;
+COMMIT;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := []string{
+		"enable_sequence_messages", "on",
+		"streaming", "on",
+	}
+
+	messages := getChanges(t, dbh, options)
+	clearSequenceOids(t, messages)
+	txns, other := assembleStreamedTransactions(t, messages)
+	if len(txns) != 1 || !txns[0].committed {
+		t.Fatalf("unexpected streamed transactions %+v", txns)
+	}
+	if len(other) != 0 {
+		t.Fatalf("unexpected messages outside of stream blocks %+v", other)
+	}
+	changes := txns[0].changes
+	if len(insertedValues(changes)) != 2000 {
+		t.Fatalf("got %d streamed inserts; expected 2000", len(insertedValues(changes)))
+	}
+	compareMessages(t, changes[len(changes) - 1:], []proto.Message{serialSequence(2000, true)})
+
+	// the block is the last one before the StreamCommit
+	for i, msg := range messages {
+		if _, ok := msg.(*SequenceDescription); ok {
+			if len(messages) < i + 3 {
+				t.Fatalf("SequenceDescription is one of the last two messages")
+			}
+			if _, ok := messages[i + 1].(*StreamStop); !ok {
+				t.Fatalf("unexpected message %T after the SequenceDescription", messages[i + 1])
+			}
+			if _, ok := messages[i + 2].(*StreamCommit); !ok {
+				t.Fatalf("unexpected message %T after the StreamStop", messages[i + 2])
+			}
+		}
+	}
+}
+
+func TestSequenceDisabled(t *testing.T) {
+	sql := `
+INSERT INTO tbl_serial(f2) VALUES ('a');
+`
+
+	var expected []proto.Message
+	expected = append(expected,
+		serialInsert(nil, "1", "a"),
+		&CommitTransaction{},
+	)
+	runSequenceTest(t, sql, nil, expected)
+}
@@ -0,0 +1,393 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/jackc/pgx/v4"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The smallest allowed value; a couple of thousand rows are enough to make
+// the server stream a transaction.
+func setLowDecodingWorkMem(t *testing.T, dbh *pgx.Conn) {
+	_, err := dbh.Exec(context.Background(), `SET logical_decoding_work_mem TO '64kB'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// streamedTransaction is the outcome of a streamed transaction as a client
+// which follows the rules in the README would see it.
+type streamedTransaction struct {
+	xid uint32
+	numBlocks int
+	committed bool
+	// set instead of committed if the transaction was prepared
+	prepared bool
+	changes []proto.Message
+}
+
+// assembleStreamedTransactions checks that the messages of stream blocks are
+// well-formed, and collects the changes of each streamed transaction in the
+// order the transactions ended.  Messages outside of stream blocks are
+// returned separately.
+func assembleStreamedTransactions(t *testing.T, messages []proto.Message) ([]*streamedTransaction, []proto.Message) {
+	var finished []*streamedTransaction
+	var other []proto.Message
+	inProgress := make(map[uint32]*streamedTransaction)
+	var current *streamedTransaction
+
+	for i, msg := range messages {
+		switch m := msg.(type) {
+			case *StreamStart:
+				if current != nil {
+					t.Fatalf("message %d: nested StreamStart", i)
+				}
+				current = inProgress[m.Xid]
+				if current == nil {
+					if !m.FirstSegment {
+						t.Fatalf("message %d: first StreamStart of xid %d is not marked as the first segment", i, m.Xid)
+					}
+					current = &streamedTransaction{xid: m.Xid}
+					inProgress[m.Xid] = current
+				} else if m.FirstSegment {
+					t.Fatalf("message %d: StreamStart of xid %d unexpectedly marked as the first segment", i, m.Xid)
+				}
+				current.numBlocks++
+			case *StreamStop:
+				if current == nil || current.xid != m.Xid {
+					t.Fatalf("message %d: unexpected StreamStop %+v", i, m)
+				}
+				current = nil
+			case *StreamCommit:
+				txn := inProgress[m.Xid]
+				if current != nil || txn == nil {
+					t.Fatalf("message %d: unexpected StreamCommit %+v", i, m)
+				}
+				txn.committed = true
+				delete(inProgress, m.Xid)
+				finished = append(finished, txn)
+			case *StreamPrepare:
+				txn := inProgress[m.Xid]
+				if current != nil || txn == nil {
+					t.Fatalf("message %d: unexpected StreamPrepare %+v", i, m)
+				}
+				txn.prepared = true
+				delete(inProgress, m.Xid)
+				finished = append(finished, txn)
+			case *StreamAbort:
+				txn := inProgress[m.Xid]
+				if current != nil || txn == nil {
+					t.Fatalf("message %d: unexpected StreamAbort %+v", i, m)
+				}
+				if m.Subxid != m.Xid {
+					var changes []proto.Message
+					for _, change := range txn.changes {
+						if streamXid(change) != m.Subxid {
+							changes = append(changes, change)
+						}
+					}
+					txn.changes = changes
+					continue
+				}
+				delete(inProgress, m.Xid)
+				finished = append(finished, txn)
+			default:
+				if current == nil {
+					other = append(other, msg)
+					continue
+				}
+				switch msg.(type) {
+					case *RelationDescription, *SequenceDescription:
+					default:
+						if streamXid(msg) == 0 {
+							t.Fatalf("message %d: change %T in a stream block has no stream_xid", i, msg)
+						}
+				}
+				current.changes = append(current.changes, msg)
+		}
+	}
+	if current != nil {
+		t.Fatalf("stream block of xid %d was not stopped", current.xid)
+	}
+	if len(inProgress) > 0 {
+		t.Fatalf("%d streamed transactions did not end", len(inProgress))
+	}
+	return finished, other
+}
+
+func streamXid(msg proto.Message) uint32 {
+	switch m := msg.(type) {
+		case *InsertDescription:
+			return m.StreamXid
+		case *UpdateDescription:
+			return m.StreamXid
+		case *DeleteDescription:
+			return m.StreamXid
+		case *TruncateDescription:
+			return m.StreamXid
+		case *LogicalMessage:
+			return m.StreamXid
+		default:
+			return 0
+	}
+}
+
+// insertedValues returns the values of f2 of the InsertDescriptions among
+// messages.
+func insertedValues(messages []proto.Message) []string {
+	var values []string
+	for _, msg := range messages {
+		if ins, ok := msg.(*InsertDescription); ok {
+			values = append(values, string(ins.NewValues.Values[1]))
+		}
+	}
+	return values
+}
+
+func TestStreamingInput(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var serverVersion int
+	err := dbh.QueryRow(context.Background(), "SELECT current_setting('server_version_num')::int").Scan(&serverVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"foo", true, `could not parse value "foo" for parameter "streaming"`},
+		{"off", false, ""},
+		{"on", serverVersion < 140000, "streaming requires PostgreSQL 14 or later"},
+	}
+
+	for _, test := range tests {
+		options := []string{
+			"streaming", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 140000)
+	setLowDecodingWorkMem(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'a' || i FROM generate_series(1, 2000) i;
+COMMIT;
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'small');
+COMMIT;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not streamed without the option
+	messages := getChanges(t, dbh, []string{"streaming", "off"})
+	for _, msg := range messages {
+		if _, ok := msg.(*StreamStart); ok {
+			t.Fatal("unexpected StreamStart with streaming disabled")
+		}
+	}
+	if len(insertedValues(messages)) != 2001 {
+		t.Fatalf("got %d inserts; expected 2001", len(insertedValues(messages)))
+	}
+
+	_, err = dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'a' || i FROM generate_series(1, 2000) i;
+COMMIT;
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'small');
+COMMIT;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages = getChanges(t, dbh, []string{"streaming", "on"})
+	txns, other := assembleStreamedTransactions(t, messages)
+	if len(txns) != 1 || !txns[0].committed {
+		t.Fatalf("unexpected streamed transactions %+v", txns)
+	}
+	if txns[0].numBlocks < 2 {
+		t.Fatalf("transaction was streamed in %d blocks; expected at least 2", txns[0].numBlocks)
+	}
+	values := insertedValues(txns[0].changes)
+	if len(values) != 2000 {
+		t.Fatalf("got %d streamed inserts; expected 2000", len(values))
+	}
+	for i, value := range values {
+		if value != "a" + strconv.Itoa(i + 1) {
+			t.Fatalf("streamed insert %d has value %q", i, value)
+		}
+	}
+	for _, change := range txns[0].changes {
+		if streamXid(change) != txns[0].xid {
+			t.Fatalf("unexpected stream_xid %d; expected %d", streamXid(change), txns[0].xid)
+		}
+	}
+
+	// the small transaction is sent the usual way
+	expected := []proto.Message{
+		&InsertDescription{
+			Table: tblIdentityFullDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "1", "small"),
+				Nulls: createNulls(nil, 2),
+			},
+		},
+		&CommitTransaction{},
+	}
+	compareMessages(t, other, expected)
+}
+
+func TestStreamingAbort(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 140000)
+	setLowDecodingWorkMem(t, dbh)
+
+	// the changes of the rolled back subtransaction and of the rolled back
+	// transaction are streamed before the rollbacks are decoded
+	_, err := dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'a' FROM generate_series(1, 2000) i;
+SAVEPOINT s1;
+INSERT INTO tbl_identity_full SELECT i, 'b' FROM generate_series(1, 2000) i;
+ROLLBACK TO SAVEPOINT s1;
+INSERT INTO tbl_identity_full VALUES (1, 'c');
+COMMIT;
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'd' FROM generate_series(1, 2000) i;
+ROLLBACK;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := getChanges(t, dbh, []string{"streaming", "on"})
+
+	numSubxactAborts := 0
+	for _, msg := range messages {
+		if abort, ok := msg.(*StreamAbort); ok && abort.Subxid != abort.Xid {
+			numSubxactAborts++
+			if abort.AbortLsn == 0 {
+				t.Errorf("abort_lsn is not set")
+			}
+		}
+	}
+	if numSubxactAborts != 1 {
+		t.Fatalf("got %d subtransaction aborts; expected 1", numSubxactAborts)
+	}
+
+	txns, other := assembleStreamedTransactions(t, messages)
+	if len(other) != 0 {
+		t.Fatalf("unexpected messages outside of stream blocks: %+v", other)
+	}
+	if len(txns) != 2 || !txns[0].committed || txns[1].committed {
+		t.Fatalf("unexpected streamed transactions %+v", txns)
+	}
+
+	counts := make(map[string]int)
+	for _, value := range insertedValues(txns[0].changes) {
+		counts[value]++
+	}
+	if len(counts) != 2 || counts["a"] != 2000 || counts["c"] != 1 {
+		t.Fatalf("unexpected values in the committed transaction: %v", counts)
+	}
+}
+
+func TestStreamingRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 140000)
+	setLowDecodingWorkMem(t, dbh)
+
+	_, err := dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (0, 'before');
+COMMIT;
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'a' FROM generate_series(1, 2000) i;
+COMMIT;
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (0, 'after');
+COMMIT;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := []string{
+		"streaming", "on",
+		"enable_relation_messages", "on",
+	}
+	messages := getChanges(t, dbh, options)
+	txns, other := assembleStreamedTransactions(t, messages)
+	if len(txns) != 1 {
+		t.Fatalf("unexpected streamed transactions %+v", txns)
+	}
+
+	// described once in every stream block
+	numDescriptions := 0
+	for _, change := range txns[0].changes {
+		if _, ok := change.(*RelationDescription); ok {
+			numDescriptions++
+		}
+	}
+	if numDescriptions != txns[0].numBlocks {
+		t.Fatalf("relation was described %d times in %d stream blocks", numDescriptions, txns[0].numBlocks)
+	}
+
+	// and again in the transaction following the streamed one
+	var types []string
+	for _, msg := range other {
+		types = append(types, fmt.Sprintf("%T", msg))
+	}
+	expected := []string{
+		"*test.RelationDescription",
+		"*test.InsertDescription",
+		"*test.CommitTransaction",
+		"*test.RelationDescription",
+		"*test.InsertDescription",
+		"*test.CommitTransaction",
+	}
+	if strings.Join(types, ",") != strings.Join(expected, ",") {
+		t.Fatalf("got messages %q; expected %q", types, expected)
+	}
+}
@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+func TestTablePatternsInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", false, ""},
+		{"foo", true, "table patterns must be schema-qualified"},
+		{"public.foo.bar", true, "table patterns must consist of exactly two parts"},
+		{".foo", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.", true, "the schema and table parts of a table pattern can't be empty"},
+		{"public.foo,", true, "invalid input syntax for include_tables"},
+		{",public.foo", true, "invalid input syntax for include_tables"},
+		{"public.foo, ,public.bar", true, "invalid input syntax for include_tables"},
+		{"public.foo", false, ""},
+		{"*.*", false, ""},
+		{" public.* , audit.events_* ", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	for _, test := range tests {
+		options := []string{
+			"include_tables", test.input,
+		}
+
+		if test.expect_failure && test.expect_error == "" {
+			panic(test.input)
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+func TestTableFilters(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tenk1(unique1) VALUES (1);
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+INSERT INTO tbl_identity_index VALUES (1, 2, 'foo', 'bar');
+INSERT INTO tbl_identity_default_nopk VALUES (1, 'foo');
+-- would fail without a replica identity if it wasn't filtered out
+UPDATE tbl_identity_default_nopk SET f2 = 'bar';
+COMMIT;
+TRUNCATE tenk1, tbl_identity_full, tbl_identity_index;
+`
+
+	options := []string{
+		"include_tables", "public.tbl_identity_*",
+		"exclude_tables", "public.tbl_identity_full,*.*_nopk",
+		"enable_truncate_messages", "on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tblIdentityIndexDescription,
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityIndexFieldNames,
+				Values: createStringValues(4, "1", "2", "foo", "bar"),
+				Nulls: createNulls(options,4),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&TruncateDescription{
+			Tables: []*TableDescription{tblIdentityIndexDescription},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+// The filters are applied to the current name of the table.
+func TestTableFiltersRename(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+INSERT INTO tbl_identity_full VALUES (1, 'foo');
+ALTER TABLE tbl_identity_full RENAME TO tbl_identity_full_renamed;
+INSERT INTO tbl_identity_full_renamed VALUES (2, 'bar');
+`
+
+	options := []string{
+		"exclude_tables", "public.tbl_identity_full",
+	}
+
+	// the first two transactions are empty
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: &TableDescription{
+				SchemaName: "public",
+				TableName: "tbl_identity_full_renamed",
+			},
+			NewValues: &FieldSetDescription{
+				Names: tblIdentityFullFieldNames,
+				Values: createStringValues(2, "2", "bar"),
+				Nulls: createNulls(options,2),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"testing"
+	"time"
+)
+
+func TestTransactionMetadata(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	options := []string{
+		"enable_begin_messages",		"on",
+		"enable_commit_messages",		"on",
+		"enable_transaction_metadata",	"on",
+	}
+
+	txn, err := dbh.Begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = txn.Exec(context.Background(), `INSERT INTO tenk1(unique1) VALUES (1)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var xid int64
+	err = txn.QueryRow(context.Background(), `SELECT txid_current() % 4294967296`).Scan(&xid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txn.Commit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	committedAt := time.Now()
+
+	messages := getChanges(t, dbh, options)
+	if len(messages) != 3 {
+		t.Fatalf("unexpected number of messages %d; expected 3", len(messages))
+	}
+
+	begin, ok := messages[0].(*BeginTransaction)
+	if !ok {
+		t.Fatalf("unexpected first message %T", messages[0])
+	}
+	commit, ok := messages[2].(*CommitTransaction)
+	if !ok {
+		t.Fatalf("unexpected last message %T", messages[2])
+	}
+
+	if int64(begin.Xid) != xid {
+		t.Errorf("unexpected xid %d in BeginTransaction; expected %d", begin.Xid, xid)
+	}
+	if int64(commit.Xid) != xid {
+		t.Errorf("unexpected xid %d in CommitTransaction; expected %d", commit.Xid, xid)
+	}
+	if commit.CommitLsn == 0 {
+		t.Errorf("commit_lsn is not set")
+	}
+	if begin.FinalLsn != commit.CommitLsn {
+		t.Errorf("final_lsn %d does not match commit_lsn %d", begin.FinalLsn, commit.CommitLsn)
+	}
+	if commit.EndLsn <= commit.CommitLsn {
+		t.Errorf("end_lsn %d is not past commit_lsn %d", commit.EndLsn, commit.CommitLsn)
+	}
+	if begin.CommitTime != commit.CommitTime {
+		t.Errorf("commit_time %d in BeginTransaction does not match commit_time %d in CommitTransaction",
+				 begin.CommitTime, commit.CommitTime)
+	}
+	commitTime := time.Unix(0, commit.CommitTime * 1000)
+	if d := committedAt.Sub(commitTime); d < 0 || d > time.Minute {
+		t.Errorf("unexpected commit_time %s; transaction committed at %s", commitTime, committedAt)
+	}
+}
+
+func TestTransactionMetadataDisabled(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+-- not decoded
+INSERT INTO tbl_identity_nothing DEFAULT VALUES;
+COMMIT;
+`
+
+	options := []string{
+		"enable_begin_messages",		"on",
+		"enable_commit_messages",		"on",
+		"enable_transaction_metadata",	"off",
+	}
+
+	var expected []proto.Message
+	expected = append(expected, &BeginTransaction{})
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
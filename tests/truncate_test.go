@@ -0,0 +1,97 @@
+package test
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 110000)
+
+	sql := `
+INSERT INTO tenk1(unique1) VALUES (1);
+TRUNCATE tenk1, tbl_identity_nothing, tbl_identity_full RESTART IDENTITY CASCADE;
+`
+
+	options := []string{
+		"enable_truncate_messages",	"on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1"),
+				Nulls: createNulls(options,1,15),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	expected = append(expected,
+		&TruncateDescription{
+			Tables: []*TableDescription{
+				tenk1TableDescriptionNoOid,
+				tblIdentityFullDescription,
+			},
+			Cascade: true,
+			RestartIdentity: true,
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestTruncateNoOptions(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 110000)
+
+	sql := `
+TRUNCATE tenk1;
+`
+
+	options := []string{
+		"enable_truncate_messages",	"on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&TruncateDescription{
+			Tables: []*TableDescription{
+				tenk1TableDescriptionNoOid,
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestTruncateDisabled(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+TRUNCATE tenk1;
+`
+
+	runTest(t, dbh, sql, nil, nil)
+}
+
+func TestTruncateIdentityNothing(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+TRUNCATE tbl_identity_nothing;
+`
+
+	options := []string{
+		"enable_truncate_messages",	"on",
+	}
+
+	runTest(t, dbh, sql, options, nil)
+}
@@ -0,0 +1,417 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"testing"
+)
+
+const testGid = "pb3ld_test_gid"
+
+// requirePreparedTransactions skips the test if the server doesn't allow
+// PREPARE TRANSACTION.
+func requirePreparedTransactions(t *testing.T, dbh *pgx.Conn) {
+	var maxPreparedTransactions int
+	err := dbh.QueryRow(context.Background(), "SELECT current_setting('max_prepared_transactions')::int").Scan(&maxPreparedTransactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxPreparedTransactions == 0 {
+		t.Skip("max_prepared_transactions is 0")
+	}
+}
+
+// rollbackTestTransaction rolls back the test transaction if a failed test
+// left it prepared, since it would block testSetup from dropping the tables.
+func rollbackTestTransaction(dbh *pgx.Conn) {
+	var n int
+	err := dbh.QueryRow(context.Background(), "SELECT count(*) FROM pg_prepared_xacts WHERE gid = $1", testGid).Scan(&n)
+	if err == nil && n > 0 {
+		_, _ = dbh.Exec(context.Background(), "ROLLBACK PREPARED '" + testGid + "'")
+	}
+}
+
+// clearPreparedXids checks that all the two-phase messages among messages
+// carry the same xid, and clears it so that the messages can be compared
+// with compareMessages.  Returns the xid.
+func clearPreparedXids(t *testing.T, messages []proto.Message) uint32 {
+	var xid uint32
+	for _, msg := range messages {
+		var msgXid *uint32
+		switch m := msg.(type) {
+			case *PrepareTransaction:
+				msgXid = &m.Xid
+			case *CommitPrepared:
+				msgXid = &m.Xid
+			case *RollbackPrepared:
+				msgXid = &m.Xid
+			case *StreamPrepare:
+				msgXid = &m.Xid
+			default:
+				continue
+		}
+		if *msgXid == 0 {
+			t.Fatalf("%T has no xid", msg)
+		}
+		if xid != 0 && *msgXid != xid {
+			t.Fatalf("%T has xid %d; expected %d", msg, *msgXid, xid)
+		}
+		xid = *msgXid
+		*msgXid = 0
+	}
+	return xid
+}
+
+func preparedInsert(options []string) proto.Message {
+	return &InsertDescription{
+		Table: tblIdentityFullDescription,
+		NewValues: &FieldSetDescription{
+			Names: tblIdentityFullFieldNames,
+			Values: createStringValues(2, "1", "prepared"),
+			Nulls: createNulls(options, 2),
+		},
+	}
+}
+
+const prepareSQL = `
+BEGIN;
+INSERT INTO tbl_identity_full VALUES (1, 'prepared');
+PREPARE TRANSACTION '` + testGid + `';
+`
+
+func TestTwoPhaseInput(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var serverVersion int
+	err := dbh.QueryRow(context.Background(), "SELECT current_setting('server_version_num')::int").Scan(&serverVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"foo", true, `could not parse value "foo" for parameter "two_phase"`},
+		{"off", false, ""},
+		{"on", serverVersion < 150000, "two_phase requires PostgreSQL 15 or later"},
+	}
+
+	for _, test := range tests {
+		options := []string{
+			"two_phase", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+func TestTwoPhaseCommit(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	options := []string{
+		"two_phase", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	xid := clearPreparedXids(t, messages)
+	compareMessages(t, messages, []proto.Message{
+		preparedInsert(options),
+		&PrepareTransaction{Gid: testGid},
+	})
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = getChanges(t, dbh, options)
+	if clearPreparedXids(t, messages) != xid {
+		t.Fatalf("the xid of CommitPrepared does not match the xid %d of PrepareTransaction", xid)
+	}
+	compareMessages(t, messages, []proto.Message{
+		&CommitPrepared{Gid: testGid},
+	})
+}
+
+func TestTwoPhaseRollback(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	options := []string{
+		"two_phase", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	xid := clearPreparedXids(t, messages)
+	compareMessages(t, messages, []proto.Message{
+		preparedInsert(options),
+		&PrepareTransaction{Gid: testGid},
+	})
+
+	_, err = dbh.Exec(context.Background(), "ROLLBACK PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = getChanges(t, dbh, options)
+	if clearPreparedXids(t, messages) != xid {
+		t.Fatalf("the xid of RollbackPrepared does not match the xid %d of PrepareTransaction", xid)
+	}
+	compareMessages(t, messages, []proto.Message{
+		&RollbackPrepared{Gid: testGid},
+	})
+}
+
+func TestTwoPhaseMetadata(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	options := []string{
+		"two_phase", "on",
+		"enable_begin_messages", "on",
+		"enable_transaction_metadata", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	if len(messages) != 3 {
+		t.Fatalf("got %d messages; expected 3", len(messages))
+	}
+	begin, ok1 := messages[0].(*BeginTransaction)
+	prepare, ok2 := messages[2].(*PrepareTransaction)
+	if !ok1 || !ok2 {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+	if prepare.Xid == 0 || prepare.Xid != begin.Xid {
+		t.Fatalf("PrepareTransaction xid %d does not match BeginTransaction xid %d", prepare.Xid, begin.Xid)
+	}
+	if prepare.PrepareLsn == 0 || prepare.EndLsn <= prepare.PrepareLsn || prepare.PrepareTime == 0 {
+		t.Fatalf("unexpected PrepareTransaction %+v", prepare)
+	}
+	if prepare.PrepareLsn != begin.FinalLsn {
+		t.Fatalf("PrepareTransaction prepare_lsn %d does not match BeginTransaction final_lsn %d", prepare.PrepareLsn, begin.FinalLsn)
+	}
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = getChanges(t, dbh, options)
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages; expected 1", len(messages))
+	}
+	commit, ok := messages[0].(*CommitPrepared)
+	if !ok {
+		t.Fatalf("unexpected message %+v", messages[0])
+	}
+	if commit.Xid != prepare.Xid || commit.Gid != testGid {
+		t.Fatalf("unexpected CommitPrepared %+v", commit)
+	}
+	if commit.CommitLsn <= prepare.EndLsn || commit.EndLsn <= commit.CommitLsn || commit.CommitTime < prepare.PrepareTime {
+		t.Fatalf("unexpected CommitPrepared %+v after PrepareTransaction %+v", commit, prepare)
+	}
+}
+
+// Until the client has confirmed a position past the PrepareTransaction, the
+// prepared transaction is sent again by every new session, and the same goes
+// for its CommitPrepared.
+func TestTwoPhaseRestart(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	options := []string{
+		"two_phase", "on",
+	}
+	expectedPrepare := []proto.Message{
+		preparedInsert(options),
+		&PrepareTransaction{Gid: testGid},
+	}
+	expectedCommit := []proto.Message{
+		&CommitPrepared{Gid: testGid},
+	}
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		messages := peekChanges(t, dbh, options)
+		clearPreparedXids(t, messages)
+		compareMessages(t, messages, expectedPrepare)
+	}
+	messages := getChanges(t, dbh, options)
+	clearPreparedXids(t, messages)
+	compareMessages(t, messages, expectedPrepare)
+	compareMessages(t, getChanges(t, dbh, options), nil)
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = peekChanges(t, dbh, options)
+	clearPreparedXids(t, messages)
+	compareMessages(t, messages, expectedCommit)
+	messages = getChanges(t, dbh, options)
+	clearPreparedXids(t, messages)
+	compareMessages(t, messages, expectedCommit)
+	compareMessages(t, getChanges(t, dbh, options), nil)
+}
+
+func TestTwoPhaseDisabled(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareMessages(t, getChanges(t, dbh, nil), nil)
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareMessages(t, getChanges(t, dbh, nil), []proto.Message{
+		preparedInsert(nil),
+		&CommitTransaction{},
+	})
+}
+
+// A transaction which was prepared before the option was first enabled is sent
+// in its entirety when it commits.
+func TestTwoPhaseEnabledAfterPrepare(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+
+	options := []string{
+		"two_phase", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), prepareSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// move the slot past the PREPARE TRANSACTION without two_phase
+	compareMessages(t, getChanges(t, dbh, nil), nil)
+	compareMessages(t, getChanges(t, dbh, options), nil)
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	clearPreparedXids(t, messages)
+	compareMessages(t, messages, []proto.Message{
+		preparedInsert(options),
+		&PrepareTransaction{Gid: testGid},
+		&CommitPrepared{Gid: testGid},
+	})
+}
+
+func TestTwoPhaseStreaming(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 150000)
+	requirePreparedTransactions(t, dbh)
+	defer rollbackTestTransaction(dbh)
+	setLowDecodingWorkMem(t, dbh)
+
+	options := []string{
+		"two_phase", "on",
+		"streaming", "on",
+	}
+
+	_, err := dbh.Exec(context.Background(), `
+BEGIN;
+INSERT INTO tbl_identity_full SELECT i, 'a' FROM generate_series(1, 2000) i;
+PREPARE TRANSACTION '` + testGid + `';
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := getChanges(t, dbh, options)
+	var prepare *StreamPrepare
+	for _, msg := range messages {
+		if m, ok := msg.(*StreamPrepare); ok {
+			prepare = m
+		}
+	}
+	if prepare == nil || prepare.Gid != testGid {
+		t.Fatalf("no StreamPrepare with gid %q among %d messages", testGid, len(messages))
+	}
+
+	txns, other := assembleStreamedTransactions(t, messages)
+	if len(other) != 0 {
+		t.Fatalf("unexpected messages outside of stream blocks: %+v", other)
+	}
+	if len(txns) != 1 || !txns[0].prepared || txns[0].xid != prepare.Xid {
+		t.Fatalf("unexpected streamed transactions %+v", txns)
+	}
+	if len(insertedValues(txns[0].changes)) != 2000 {
+		t.Fatalf("got %d streamed inserts; expected 2000", len(insertedValues(txns[0].changes)))
+	}
+
+	_, err = dbh.Exec(context.Background(), "COMMIT PREPARED '" + testGid + "'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages = getChanges(t, dbh, options)
+	if clearPreparedXids(t, messages) != prepare.Xid {
+		t.Fatalf("the xid of CommitPrepared does not match the xid %d of StreamPrepare", prepare.Xid)
+	}
+	compareMessages(t, messages, []proto.Message{
+		&CommitPrepared{Gid: testGid},
+	})
+}
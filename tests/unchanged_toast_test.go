@@ -0,0 +1,170 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strings"
+	"testing"
+)
+
+// Like TestTableVarattExternalOndisk, but with the unchanged TOASTed column
+// marked instead of omitted.
+func TestUnchangedToastBitmap(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+ALTER TABLE tenk1 ALTER COLUMN string4 SET STORAGE EXTERNAL;
+INSERT INTO tenk1(unique1, unique2, string4)
+SELECT 1, 10, repeat('j', 9001);
+UPDATE tenk1 SET unique2 = 20;
+UPDATE tenk1 SET string4 = 'k';
+COMMIT;
+`
+
+	options := []string{
+		"type_oids_mode","full",
+		"formats_mode","full",
+		"enable_unchanged_toast_bitmap","on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1", "10",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "", "",
+					"",
+					strings.Repeat("j", 9001),
+				),
+				TypeOids: tenk1FieldTypeOids,
+				Nulls: createNulls(options,2,13,1),
+				Formats: createFormats(options, 16),
+			},
+		},
+	)
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1", "20"),
+				TypeOids: tenk1FieldTypeOids,
+				Nulls: createNulls(options,2,13,1),
+				Formats: createFormats(options, 16),
+				// same format as the nulls
+				UnchangedToast: createNulls(options,15,1),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"unique1"},
+				Values: createStringValues(1, "1"),
+				TypeOids: tenk1FieldTypeOids[:1],
+				Nulls: createNulls(options,1),
+				Formats: createFormats(options, 1),
+			},
+		},
+	)
+	// no bitmap when nothing is marked
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1TableDescriptionNoOid,
+			NewValues: &FieldSetDescription{
+				Names: tenk1FieldNames,
+				Values: createStringValues(16, "1", "20",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "", "",
+					"",
+					"k",
+				),
+				TypeOids: tenk1FieldTypeOids,
+				Nulls: createNulls(options,2,13,1),
+				Formats: createFormats(options, 16),
+			},
+			KeyFields: &FieldSetDescription{
+				Names: []string{"unique1"},
+				Values: createStringValues(1, "1"),
+				TypeOids: tenk1FieldTypeOids[:1],
+				Nulls: createNulls(options,1),
+				Formats: createFormats(options, 1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
+
+func TestUnchangedToastBitmapRelationMessages(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	var tenk1Oid uint32
+	err := dbh.QueryRow(context.Background(), `SELECT 'tenk1'::regclass::oid`).Scan(&tenk1Oid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenk1Table := &TableDescription{TableOid: tenk1Oid}
+
+	sql := `
+ALTER TABLE tenk1 ALTER COLUMN string4 SET STORAGE EXTERNAL;
+INSERT INTO tenk1(unique1, unique2, string4)
+SELECT 1, 10, repeat('j', 9001);
+UPDATE tenk1 SET unique2 = 20;
+`
+
+	options := []string{
+		"enable_relation_messages","on",
+		"enable_unchanged_toast_bitmap","on",
+	}
+
+	var expected []proto.Message
+	expected = append(expected,
+		&RelationDescription{
+			RelationId: tenk1Oid,
+			SchemaName: "public",
+			TableName: "tenk1",
+			ColumnNames: tenk1FieldNames,
+			TypeOids: tenk1FieldTypeOids,
+			TypeModifiers: createTypeModifiers(16),
+			KeyColumns: []uint32{0},
+		},
+	)
+	expected = append(expected,
+		&InsertDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(16, "1", "10",
+					"", "", "", "",
+					"", "", "", "",
+					"", "", "", "",
+					"",
+					strings.Repeat("j", 9001),
+				),
+				Nulls: createNulls(options,2,13,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	// all columns are present, so no column_numbers
+	expected = append(expected,
+		&UpdateDescription{
+			Table: tenk1Table,
+			NewValues: &FieldSetDescription{
+				Values: createStringValues(16, "1", "20"),
+				Nulls: createNulls(options,2,13,1),
+				UnchangedToast: createNulls(options,15,1),
+			},
+			KeyFields: &FieldSetDescription{
+				Values: createStringValues(1, "1"),
+				Nulls: createNulls(options,1),
+			},
+		},
+	)
+	expected = append(expected, &CommitTransaction{})
+	runTest(t, dbh, sql, options, expected)
+}
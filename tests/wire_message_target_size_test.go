@@ -0,0 +1,219 @@
+package test
+
+import (
+	"context"
+	proto "github.com/golang/protobuf/proto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWireMessageTargetSizeInput(t *testing.T) {
+	tests := []struct{
+		input string
+		expect_failure bool
+		expect_error string
+	}{
+		{"", true, `could not parse value "" for parameter "wire_message_target_size"`},
+		{"foo", true, `could not parse value "foo" for parameter "wire_message_target_size"`},
+		{"1.5", true, `could not parse value "1.5" for parameter "wire_message_target_size"`},
+		{"10 parsecs", true, `could not parse value "10 parsecs" for parameter "wire_message_target_size"`},
+		{"-1", true, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{"1", true, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{"1023", true, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{"268435457", true, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{"1GB", true, "wire_message_target_size must be 0 or between 1024 and 268435456 bytes"},
+		{"0", false, ""},
+		{"1024", false, ""},
+		{"268435456", false, ""},
+		{"64kB", false, ""},
+		{"256MB", false, ""},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	// memory units
+	requireServerVersion(t, dbh, 110000)
+
+	for _, test := range tests {
+		options := []string{
+			"wire_message_target_size", test.input,
+		}
+
+		_, err := dbh.Exec(
+			context.Background(),
+			`SELECT pg_logical_slot_get_binary_changes($1, NULL, 1, VARIADIC $2)`,
+			replicationSlotName,
+			options,
+		)
+		if err != nil {
+			if !test.expect_failure {
+				t.Errorf("test %q failed unexpectedly: %s", test.input, err)
+				continue
+			}
+			if strings.Index(err.Error(), test.expect_error) == -1 {
+				t.Errorf("test %q failed with an unexpected error: %s (expected to contain %q)", test.input, err, test.expect_error)
+				continue
+			}
+		} else {
+			if test.expect_failure {
+				t.Errorf("test %q succeeded unexpectedly", test.input)
+				continue
+			}
+		}
+	}
+}
+
+// Checks which messages end up in which wire message for different target
+// sizes.  Every INSERT is a bit over 600 bytes, and the buffer is flushed as
+// soon as it's larger than the target.
+func TestWireMessageTargetSizeFrames(t *testing.T) {
+	tests := []struct{
+		size string
+		// the number of messages in each wire message
+		expected_frames []int
+	}{
+		// the default is 4MB
+		{"", []int{6}},
+		// every change separately; the commit is sent at the end of the
+		// transaction, so it's also separate
+		{"0", []int{1, 1, 1, 1, 1, 1}},
+		{"1024", []int{2, 2, 2}},
+		{"2048", []int{4, 2}},
+		{"2kB", []int{4, 2}},
+		{"4096", []int{6}},
+	}
+
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+	requireServerVersion(t, dbh, 110000)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full (f1, f2) SELECT i, repeat('j', 600) FROM generate_series(1, 5) i;
+COMMIT;
+`
+
+	for _, test := range tests {
+		options := []string{}
+		if test.size != "" {
+			options = append(options, "wire_message_target_size", test.size)
+		}
+
+		_, err := dbh.Exec(context.Background(), sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected []proto.Message
+		for i := 1; i <= 5; i++ {
+			expected = append(expected,
+				&InsertDescription{
+					Table: tblIdentityFullDescription,
+					NewValues: &FieldSetDescription{
+						Names: tblIdentityFullFieldNames,
+						Values: createStringValues(2, strconv.Itoa(i), strings.Repeat("j", 600)),
+						Nulls: createNulls(options,2),
+					},
+				},
+			)
+		}
+		expected = append(expected, &CommitTransaction{})
+
+		wireMessages := getWireMessages(t, dbh, options)
+		var messages []proto.Message
+		var frames []int
+		for _, wireMessage := range wireMessages {
+			messages = append(messages, wireMessage...)
+			frames = append(frames, len(wireMessage))
+		}
+		compareMessages(t, messages, expected)
+		if !intSlicesEqual(frames, test.expected_frames) {
+			t.Errorf("wire_message_target_size %q: got wire messages of %v messages; expected %v",
+					 test.size, frames, test.expected_frames)
+		}
+	}
+}
+
+// A single change larger than the target is sent in one wire message, and the
+// begin message is sent together with the first change even if every change
+// is sent separately.
+func TestWireMessageTargetSizeLargeChange(t *testing.T) {
+	dbh := testSetup(t)
+	defer testTeardown(t, dbh)
+
+	sql := `
+BEGIN;
+INSERT INTO tbl_identity_full (f1, f2) VALUES (1, repeat('j', 3000));
+INSERT INTO tbl_identity_full (f1, f2) VALUES (2, 'foo');
+COMMIT;
+`
+
+	tests := []struct{
+		size string
+		expected_frames []int
+	}{
+		{"0", []int{2, 1, 1}},
+		{"1024", []int{2, 2}},
+	}
+
+	for _, test := range tests {
+		options := []string{
+			"enable_begin_messages", "on",
+			"wire_message_target_size", test.size,
+		}
+
+		_, err := dbh.Exec(context.Background(), sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var expected []proto.Message
+		expected = append(expected, &BeginTransaction{})
+		expected = append(expected,
+			&InsertDescription{
+				Table: tblIdentityFullDescription,
+				NewValues: &FieldSetDescription{
+					Names: tblIdentityFullFieldNames,
+					Values: createStringValues(2, "1", strings.Repeat("j", 3000)),
+					Nulls: createNulls(options,2),
+				},
+			},
+		)
+		expected = append(expected,
+			&InsertDescription{
+				Table: tblIdentityFullDescription,
+				NewValues: &FieldSetDescription{
+					Names: tblIdentityFullFieldNames,
+					Values: createStringValues(2, "2", "foo"),
+					Nulls: createNulls(options,2),
+				},
+			},
+		)
+		expected = append(expected, &CommitTransaction{})
+
+		var messages []proto.Message
+		var frames []int
+		for _, wireMessage := range getWireMessages(t, dbh, options) {
+			messages = append(messages, wireMessage...)
+			frames = append(frames, len(wireMessage))
+		}
+		compareMessages(t, messages, expected)
+		if !intSlicesEqual(frames, test.expected_frames) {
+			t.Errorf("wire_message_target_size %q: got wire messages of %v messages; expected %v",
+					 test.size, frames, test.expected_frames)
+		}
+	}
+}
+
+func intSlicesEqual(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}